@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jukemori/timeline-generator/internal/config"
 	"github.com/jukemori/timeline-generator/internal/database"
 	"github.com/jukemori/timeline-generator/internal/models"
 	"github.com/sirupsen/logrus"
@@ -28,8 +29,13 @@ func main() {
 func run() error {
 	ctx := context.Background()
 	
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	// Initialize database connection
-	database.InitDB()
+	database.InitDB(ctx, cfg.Database.Connection())
 	db := database.DB
 	
 	// Begin transaction
@@ -80,27 +86,49 @@ func deleteAll(ctx context.Context, tx *sql.Tx) error {
 		return err
 	}
 
+	_, err = tx.ExecContext(ctx, "DELETE FROM organizations")
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func createAll(ctx context.Context, tx *sql.Tx) error {
 	// Sample IDs
+	organizationID := "default"
 	userID := "1"
 	logrus.Infof("Generated userID: %s", userID)
+
+	// Create the organization the users belong to
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO organizations (id, name, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		organizationID, "Example Organization", time.Now(), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to insert organization: %v", err)
+	}
 	
 	// Create a user first
-	_, err := tx.ExecContext(ctx,
-		"INSERT INTO users (id, email, created_at, updated_at) VALUES (?, ?, ?, ?)",
-		userID, "test@example.com", time.Now(), time.Now())
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO users (id, organization_id, email, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		userID, organizationID, "test@example.com", time.Now(), time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to insert user: %v", err)
 	}
+
+	// Create an admin who administers the organization
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO users (id, organization_id, email, role, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		"admin", organizationID, "admin@example.com", models.RoleAdmin, time.Now(), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to insert admin user: %v", err)
+	}
 	
 	// Create a goal with the user ID
 	goalID := uuid.New().String()
 	_, err = tx.ExecContext(ctx,
-		"INSERT INTO goals (id, user_id, title, description, current_level, target_level, start_date, target_date, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
-		goalID, userID, "Master Go Programming", "Become proficient in Go", "Beginner", "Advanced", 
+		"INSERT INTO goals (id, organization_id, user_id, title, description, current_level, target_level, start_date, target_date, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		goalID, organizationID, userID, "Master Go Programming", "Become proficient in Go", "Beginner", "Advanced", 
 		time.Now(), time.Now().AddDate(0, 3, 0), time.Now(), time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to insert goal: %v", err)
@@ -136,7 +164,7 @@ func createAll(ctx context.Context, tx *sql.Tx) error {
 			Description: "Install Go and set up the development environment",
 			StartDate:   timeline1.StartDate,
 			EndDate:     timeline1.StartDate.AddDate(0, 0, 7),
-			Duration:    "7 days",
+			Duration:    models.Duration{Label: "1 week", Value: 7, Unit: models.DurationDays},
 			Priority:    1,
 			Completed:   false,
 			CreatedAt:   time.Now(),
@@ -149,21 +177,35 @@ func createAll(ctx context.Context, tx *sql.Tx) error {
 			Description: "Learn basic syntax, variables, and control structures",
 			StartDate:   timeline1.StartDate.AddDate(0, 0, 8),
 			EndDate:     timeline1.StartDate.AddDate(0, 0, 21),
-			Duration:    "14 days",
+			Duration:    models.Duration{Label: "2 weeks", Value: 14, Unit: models.DurationDays},
 			Priority:    1,
 			Completed:   false,
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		},
+		{
+			ID:          uuid.New().String(),
+			TimelineID:  timeline1.ID,
+			Title:       "Practice Go Exercises",
+			Description: "Solve a Go exercise every weekday",
+			StartDate:   timeline1.StartDate.AddDate(0, 0, 8),
+			EndDate:     timeline1.EndDate,
+			Duration:    models.Duration{Label: "30 minutes", Value: 0.5, Unit: models.DurationHours},
+			Recurrence:  "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+			Priority:    2,
+			Completed:   false,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		},
 		// Add more tasks as needed
 	}
 
 	// Insert tasks
 	for _, task := range tasks {
 		_, err := tx.ExecContext(ctx, 
-			"INSERT INTO timeline_tasks (id, timeline_id, title, description, start_date, end_date, duration, priority, completed, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			"INSERT INTO timeline_tasks (id, timeline_id, title, description, start_date, end_date, duration, duration_value, duration_unit, recurrence, priority, completed, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
 			task.ID, task.TimelineID, task.Title, task.Description, 
-			task.StartDate, task.EndDate, task.Duration, task.Priority, task.Completed, task.CreatedAt, task.UpdatedAt)
+			task.StartDate, task.EndDate, task.Duration.Label, task.Duration.Value, task.Duration.Unit, sql.NullString{String: task.Recurrence, Valid: task.Recurrence != ""}, task.Priority, task.Completed, task.CreatedAt, task.UpdatedAt)
 		if err != nil {
 			return fmt.Errorf("failed to insert task: %v", err)
 		}
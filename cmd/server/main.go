@@ -1,45 +1,217 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/jukemori/timeline-generator/graph/generated"
 	"github.com/jukemori/timeline-generator/graph/resolver"
+	"github.com/jukemori/timeline-generator/internal/activity"
+	"github.com/jukemori/timeline-generator/internal/audit"
+	"github.com/jukemori/timeline-generator/internal/auditexport"
+	"github.com/jukemori/timeline-generator/internal/auth"
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/config"
 	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/health"
+	"github.com/jukemori/timeline-generator/internal/ical"
+	"github.com/jukemori/timeline-generator/internal/jobs"
+	"github.com/jukemori/timeline-generator/internal/metrics"
+	"github.com/jukemori/timeline-generator/internal/notify"
 	"github.com/jukemori/timeline-generator/internal/openai"
+	"github.com/jukemori/timeline-generator/internal/reminder"
+	"github.com/jukemori/timeline-generator/internal/rest"
+	"github.com/jukemori/timeline-generator/internal/service"
+	"github.com/jukemori/timeline-generator/internal/tracing"
+	"github.com/jukemori/timeline-generator/internal/webhook"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
-const defaultPort = "8080"
+// abortTimeout is how long interrupted generation jobs are given to be put back in the queue once the
+// shutdown timeout has passed
+const abortTimeout = 2 * time.Second
 
 func main() {
-	// Initialize the database connection
-	database.InitDB()
-	
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = defaultPort
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	log.Printf("Effective configuration:\n%s", cfg.Redacted())
+
+	// Shut down on SIGINT or SIGTERM, finishing in-flight requests and background work first
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Export traces of requests, resolvers, queries and OpenAI calls
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:     cfg.Tracing.Exporter,
+		ServiceName:  cfg.Tracing.ServiceName,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
 	}
 
-	openaiClient := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
+	// Initialize the database connection, waiting for the database to come up
+	database.InitDB(ctx, cfg.Database.Connection())
+	metrics.RegisterDB(database.DB, "timeline")
+
+	// Background workers keep running until the HTTP server has drained
+	background, stopBackground := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	runWorker := func(run func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(background)
+		}()
+	}
+
+	openaiClient := openai.NewClient(openai.Config{
+		APIKey:      cfg.LLM.APIKey,
+		Model:       cfg.LLM.Model,
+		Temperature: float32(cfg.LLM.Temperature),
+		Timeout:     cfg.LLM.Timeout,
+	})
+
+	// Dates are evaluated in each user's time zone against this clock
+	clk := clock.System
+
+	generationLimiter := service.NewGenerationLimiter(service.GenerationLimits{
+		UserPerMinute: cfg.Generation.RatePerMinute,
+		UserBurst:     cfg.Generation.RateBurst,
+		IPPerMinute:   cfg.Generation.IPRatePerMinute,
+		IPBurst:       cfg.Generation.IPRateBurst,
+		MonthlyQuota:  cfg.Generation.MonthlyQuota,
+	}, clk)
+
+	// Deliver events to webhook subscribers
+	eventBus := events.NewBus()
+	webhookConfig := webhook.DefaultConfig()
+	webhookConfig.Workers = cfg.Webhooks.Workers
+	webhookConfig.MaxAttempts = cfg.Webhooks.MaxAttempts
+	webhookConfig.Timeout = cfg.Webhooks.Timeout
+	webhookConfig.AllowPrivateNetworks = cfg.Webhooks.AllowPrivateNetworks
+	webhookDispatcher := webhook.NewDispatcher(webhookConfig)
+	if cfg.Features.Webhooks {
+		eventBus.Subscribe(webhookDispatcher.Handle)
+		webhookDispatcher.Start(background)
+	}
+
+	deadlineMonitor := service.NewDeadlineMonitor(eventBus, clk, cfg.Deadlines.CheckInterval)
+	runWorker(deadlineMonitor.Run)
+
+	// Remind users about upcoming and overdue tasks
+	reminderScheduler := reminder.NewScheduler(eventBus, clk, cfg.Reminders.Interval)
+	if cfg.Features.Reminders {
+		runWorker(reminderScheduler.Run)
+	}
+
+	// Email reminders and weekly digests
+	notifier := notify.NewNotifier(notify.NewSMTPSender(notify.SMTPConfig{
+		Host:     cfg.Email.SMTPHost,
+		Port:     cfg.Email.SMTPPort,
+		Username: cfg.Email.SMTPUsername,
+		Password: cfg.Email.SMTPPassword,
+		From:     cfg.Email.From,
+	}), clk, notify.Config{
+		PublicURL:      cfg.Server.PublicURL,
+		DigestInterval: cfg.Email.DigestInterval,
+	})
+	if cfg.Features.Emails {
+		eventBus.Subscribe(notifier.Handle)
+		notifier.Start(background)
+	}
+
+	// Permanently delete what has been in the trash for the retention period
+	trashService := service.NewTrashService(clk, cfg.Trash.Retention, cfg.Trash.PurgeInterval)
+	runWorker(trashService.Run)
+
+	// Record timeline activity and send it to subscribers
+	activityHub := activity.NewHub()
+	eventBus.Subscribe(activity.NewRecorder(activityHub).Handle)
+
+	timelineScheduler := service.NewTimelineScheduler(clk, eventBus)
+	timelineGenerator := service.NewTimelineGenerator(openaiClient, timelineScheduler, eventBus)
+
+	// Start the background generation workers
+	queueConfig := jobs.DefaultConfig()
+	queueConfig.Workers = cfg.Generation.Workers
+	queueConfig.MaxAttempts = cfg.Generation.MaxAttempts
+	generationQueue := jobs.NewQueue(queueConfig, timelineGenerator, generationLimiter)
+	generationQueue.Start(background)
+
+	// Report the depth of the background workers' queues
+	metrics.RegisterQueue("generation_jobs", generationQueue.Depth)
+	metrics.RegisterQueue("webhook_deliveries", webhookDispatcher.Depth)
+	metrics.RegisterQueue("emails", func() (int, error) { return notifier.Depth(), nil })
+
+	taskService := service.NewTaskService(eventBus)
 	
 	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
 		Resolvers: &resolver.Resolver{
-			OpenAIClient: openaiClient,
+			OpenAIClient:        openaiClient,
+			TimelineGenerator:   timelineGenerator,
+			GenerationLimiter:   generationLimiter,
+			GenerationQueue:     generationQueue,
+			TaskService:         taskService,
+			WebhookDispatcher:   webhookDispatcher,
+			ReminderScheduler:   reminderScheduler,
+			Notifier:            notifier,
+			TimelineScheduler:   timelineScheduler,
+			TemplateService:     service.NewTemplateService(timelineScheduler),
+			SharingService:      service.NewSharingService(eventBus),
+			OrganizationService: service.NewOrganizationService(),
+			CommentService:      service.NewCommentService(activityHub, eventBus),
+			TrashService:        trashService,
+			ActivityHub:         activityHub,
+			Clock:               clk,
 		},
 	}))
 
-	// Setup CORS
-	allowOrigins := strings.Split(os.Getenv("ALLOW_ORIGINS"), ",")
+	// Attribute the changes each mutation makes to it in the audit log
+	srv.AroundRootFields(audit.RootFieldMiddleware)
+	// Report timed out and cancelled requests with their own error codes
+	srv.SetErrorPresenter(resolver.PresentError)
+	// Record operation counts and latencies and resolver errors for /metrics
+	srv.Use(metrics.Tracer{})
+	// Trace each operation and resolver call
+	srv.Use(tracing.Tracer{})
+
+	// Identify users by their bearer tokens and clients through the trusted proxies
+	authenticator, err := auth.NewAuthenticator(cfg.Auth.TokenSecret, cfg.Auth.TokenIssuer, cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid authentication settings: %v", err)
+	}
+
+	// Setup CORS. cors allows every origin when given none, so cross-origin requests are refused
+	// unless origins are configured.
+	allowOrigins := cfg.Server.AllowOrigins
+	if len(allowOrigins) == 0 {
+		allowOrigins = []string{""}
+	}
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   allowOrigins,
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete},
 		AllowCredentials: true,
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", audit.RequestIDHeader},
 		MaxAge:           60 * 60, // 1 hour in seconds
 	})
 
@@ -47,9 +219,102 @@ func main() {
 	mux := http.NewServeMux()
 	
 	// Add the handlers with CORS middleware
-	mux.Handle("/", playground.Handler("GraphQL playground", "/graphql"))
-	mux.Handle("/graphql", corsHandler.Handler(srv))
+	if cfg.Features.Playground {
+		mux.Handle("/", playground.Handler("GraphQL playground", "/graphql"))
+	}
+	mux.Handle("/graphql", otelhttp.NewHandler(corsHandler.Handler(authenticator.Middleware(audit.Middleware(srv))), "/graphql"))
+	mux.Handle("/notifications/unsubscribe", authenticator.Middleware(audit.Middleware(notifier.UnsubscribeHandler())))
+	mux.Handle("GET /timelines/{id}/calendar.ics", corsHandler.Handler(authenticator.Middleware(ical.Handler(clk))))
+	mux.Handle("GET /audit/export", corsHandler.Handler(authenticator.Middleware(auditexport.Handler())))
+
+	// Serve the REST API backed by the same services as the resolvers
+	restServer := &rest.Server{
+		TimelineGenerator: timelineGenerator,
+		GenerationLimiter: generationLimiter,
+		GenerationQueue:   generationQueue,
+		TaskService:       taskService,
+		TimelineScheduler: timelineScheduler,
+		TrashService:      trashService,
+		Clock:             clk,
+	}
+	mux.Handle(rest.Prefix+"/", otelhttp.NewHandler(corsHandler.Handler(authenticator.Middleware(audit.Middleware(restServer.Handler()))), rest.Prefix))
+
+	// Report liveness and readiness to the orchestrator
+	checker := health.NewChecker(5 * time.Second)
+	checker.Add("database", database.DB.PingContext)
+	checker.Add("schema", database.CheckSchema)
+	checker.Add("llm", func(context.Context) error {
+		if !openaiClient.Configured() {
+			return errors.New("OPENAI_API_KEY is not set")
+		}
+		return nil
+	})
+	mux.Handle("GET /healthz", checker.Liveness())
+	mux.Handle("GET /readyz", checker.Readiness())
+
+	server := &http.Server{Addr: fmt.Sprintf(":%d", cfg.Server.Port), Handler: mux}
+	go func() {
+		log.Printf("connect to http://localhost:%d/ for GraphQL playground", cfg.Server.Port)
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to serve: %v", err)
+		}
+	}()
+
+	// Serve metrics on their own port, which is not exposed publicly
+	var metricsServer *http.Server
+	if cfg.Features.Metrics {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", promhttp.Handler())
+		metricsServer = &http.Server{Addr: fmt.Sprintf(":%d", cfg.Server.MetricsPort), Handler: metricsMux}
+		go func() {
+			if err := metricsServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("Failed to serve metrics: %v", err)
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	// A second signal stops the server at once
+	stop()
+	log.Println("Shutting down")
+	checker.Drain()
+	// Keep serving until load balancers have seen /readyz fail and stopped sending requests
+	time.Sleep(cfg.Server.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to drain HTTP requests: %v", err)
+	}
+
+	// Stop the background workers, letting them finish what they are working on, including the
+	// generation jobs that are running
+	stopBackground()
+	stopped := make(chan struct{})
+	go func() {
+		generationQueue.Wait()
+		webhookDispatcher.Wait()
+		notifier.Wait()
+		workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		log.Println("Background workers did not stop in time")
+		// Put the generation jobs still running back in the queue so that they run after restart
+		generationQueue.Abort(abortTimeout)
+	}
+
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to stop serving metrics: %v", err)
+		}
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+	database.DB.Close()
+	log.Println("Shut down")
+}
 
-	log.Printf("connect to http://localhost:%s/ for GraphQL playground", port)
-	log.Fatal(http.ListenAndServe(":"+port, mux))
-}
\ No newline at end of file
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/rest"
+)
+
+// backend reads and changes a user's goals and timelines, either through a server or directly
+type backend interface {
+	ListGoals(ctx context.Context) ([]*models.Goal, error)
+	ListTimelines(ctx context.Context, goalID string) ([]*models.Timeline, error)
+	// GetTimeline returns a timeline along with its tasks
+	GetTimeline(ctx context.Context, id string) (*models.Timeline, error)
+	GenerateTimeline(ctx context.Context, input models.TimelineInput) (*models.Timeline, error)
+	SetTaskCompleted(ctx context.Context, taskID string, completed bool) (*models.TimelineTask, error)
+	Close() error
+}
+
+// apiBackend uses the REST API of a running server as the user a token identifies. The server checks
+// the user's access as for any client.
+type apiBackend struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func newAPIBackend(server, token string) *apiBackend {
+	return &apiBackend{
+		baseURL: strings.TrimSuffix(server, "/") + rest.Prefix,
+		token:   token,
+		client:  &http.Client{},
+	}
+}
+
+func (b *apiBackend) ListGoals(ctx context.Context) ([]*models.Goal, error) {
+	var goals []*models.Goal
+	err := b.do(ctx, http.MethodGet, "/goals", nil, &goals)
+	return goals, err
+}
+
+func (b *apiBackend) ListTimelines(ctx context.Context, goalID string) ([]*models.Timeline, error) {
+	var timelines []*models.Timeline
+	err := b.do(ctx, http.MethodGet, "/goals/"+url.PathEscape(goalID)+"/timelines", nil, &timelines)
+	return timelines, err
+}
+
+func (b *apiBackend) GetTimeline(ctx context.Context, id string) (*models.Timeline, error) {
+	var timeline models.Timeline
+	if err := b.do(ctx, http.MethodGet, "/timelines/"+url.PathEscape(id), nil, &timeline); err != nil {
+		return nil, err
+	}
+	return &timeline, nil
+}
+
+func (b *apiBackend) GenerateTimeline(ctx context.Context, input models.TimelineInput) (*models.Timeline, error) {
+	var timeline models.Timeline
+	if err := b.do(ctx, http.MethodPost, "/timelines", input, &timeline); err != nil {
+		return nil, err
+	}
+	return &timeline, nil
+}
+
+func (b *apiBackend) SetTaskCompleted(ctx context.Context, taskID string, completed bool) (*models.TimelineTask, error) {
+	var task models.TimelineTask
+	body := map[string]bool{"completed": completed}
+	if err := b.do(ctx, http.MethodPatch, "/tasks/"+url.PathEscape(taskID), body, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (b *apiBackend) Close() error {
+	b.client.CloseIdleConnections()
+	return nil
+}
+
+// do sends a request to the API as the user and decodes the response into result. Error responses
+// are returned as errors carrying the API's message and code.
+func (b *apiBackend) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error.Code == "" {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return fmt.Errorf("%s (%s)", apiErr.Error.Message, apiErr.Error.Code)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("invalid response to %s %s: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/ical"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// app is what commands run with
+type app struct {
+	backend backend
+	out     io.Writer
+	// json prints results as JSON instead of tables, for scripts
+	json bool
+}
+
+func listGoals(ctx context.Context, app *app, args []string) error {
+	if _, err := parseArgs("goals", args, 0, nil); err != nil {
+		return err
+	}
+
+	goals, err := app.backend.ListGoals(ctx)
+	if err != nil {
+		return err
+	}
+	if app.json {
+		return app.printJSON(goals)
+	}
+
+	return app.printTable([]string{"ID", "TITLE", "START", "TARGET"}, len(goals), func(i int) []string {
+		return []string{goals[i].ID, goals[i].Title, formatDate(goals[i].StartDate), formatDate(goals[i].TargetDate)}
+	})
+}
+
+func listTimelines(ctx context.Context, app *app, args []string) error {
+	flags, err := parseArgs("timelines GOAL_ID", args, 1, nil)
+	if err != nil {
+		return err
+	}
+
+	timelines, err := app.backend.ListTimelines(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if app.json {
+		return app.printJSON(timelines)
+	}
+
+	return app.printTable([]string{"ID", "TITLE", "START", "END"}, len(timelines), func(i int) []string {
+		return []string{timelines[i].ID, timelines[i].Title, formatDate(timelines[i].StartDate), formatDate(timelines[i].EndDate)}
+	})
+}
+
+func listTasks(ctx context.Context, app *app, args []string) error {
+	flags, err := parseArgs("tasks TIMELINE_ID", args, 1, nil)
+	if err != nil {
+		return err
+	}
+
+	timeline, err := app.backend.GetTimeline(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if app.json {
+		return app.printJSON(timeline.Tasks)
+	}
+
+	return app.printTasks(timeline.Tasks...)
+}
+
+// timelineSpec is a YAML file describing a timeline to generate
+type timelineSpec struct {
+	Goal         string `yaml:"goal"`
+	CurrentLevel string `yaml:"current_level"`
+	Objectives   string `yaml:"objectives"`
+	CurrentDate  string `yaml:"current_date"`
+	TargetDate   string `yaml:"target_date"`
+}
+
+func generateTimeline(ctx context.Context, app *app, args []string) error {
+	var file, goal, level, objectives, from, to string
+	_, err := parseArgs("generate [flags]", args, 0, func(flags *flag.FlagSet) {
+		flags.StringVar(&file, "f", "", "YAML file with goal, current_level, objectives, current_date and target_date, which flags override")
+		flags.StringVar(&goal, "goal", "", "the goal to reach")
+		flags.StringVar(&level, "level", "", "your current level")
+		flags.StringVar(&objectives, "objectives", "", "specific objectives along the way")
+		flags.StringVar(&from, "from", "", "date to start from, as YYYY-MM-DD (default today)")
+		flags.StringVar(&to, "to", "", "date to reach the goal by, as YYYY-MM-DD")
+	})
+	if err != nil {
+		return err
+	}
+
+	var spec timelineSpec
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(data, &spec); err != nil {
+			return fmt.Errorf("invalid timeline file %s: %w", file, err)
+		}
+	}
+
+	input := models.TimelineInput{
+		Goal:         firstNonEmpty(goal, spec.Goal),
+		CurrentLevel: firstNonEmpty(level, spec.CurrentLevel),
+		Objectives:   firstNonEmpty(objectives, spec.Objectives),
+		CurrentDate:  firstNonEmpty(from, spec.CurrentDate, clock.Today(clock.System, time.Local).Format(clock.DateLayout)),
+		TargetDate:   firstNonEmpty(to, spec.TargetDate),
+	}
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	timeline, err := app.backend.GenerateTimeline(ctx, input)
+	if err != nil {
+		return err
+	}
+	if app.json {
+		return app.printJSON(timeline)
+	}
+
+	fmt.Fprintf(app.out, "Generated timeline %s: %s (%s to %s)\n\n", timeline.ID, timeline.Title,
+		formatDate(timeline.StartDate), formatDate(timeline.EndDate))
+	return app.printTasks(timeline.Tasks...)
+}
+
+func completeTask(ctx context.Context, app *app, args []string) error {
+	var undo bool
+	flags, err := parseArgs("complete [-undo] TASK_ID", args, 1, func(flags *flag.FlagSet) {
+		flags.BoolVar(&undo, "undo", false, "mark the task not complete instead")
+	})
+	if err != nil {
+		return err
+	}
+
+	task, err := app.backend.SetTaskCompleted(ctx, flags.Arg(0), !undo)
+	if err != nil {
+		return err
+	}
+	if app.json {
+		return app.printJSON(task)
+	}
+
+	return app.printTasks(*task)
+}
+
+func exportTimeline(ctx context.Context, app *app, args []string) error {
+	var format, output string
+	flags, err := parseArgs("export [flags] TIMELINE_ID", args, 1, func(flags *flag.FlagSet) {
+		flags.StringVar(&format, "format", "markdown", "markdown or ics")
+		flags.StringVar(&output, "o", "", "file to write to (default standard output)")
+	})
+	if err != nil {
+		return err
+	}
+	if format != "markdown" && format != "ics" {
+		return fmt.Errorf("unknown format %q: use markdown or ics", format)
+	}
+
+	timeline, err := app.backend.GetTimeline(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	out := app.out
+	var file *os.File
+	if output != "" {
+		if file, err = os.Create(output); err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if format == "ics" {
+		err = ical.Encode(out, timeline, clock.System.Now())
+	} else {
+		err = writeMarkdown(out, timeline)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to export timeline %s: %w", timeline.ID, err)
+	}
+	if file != nil {
+		return file.Close()
+	}
+	return nil
+}
+
+func issueToken(ctx context.Context, app *app, args []string) error {
+	var ttl time.Duration
+	_, err := parseArgs("token [-ttl DURATION]", args, 0, func(flags *flag.FlagSet) {
+		flags.DurationVar(&ttl, "ttl", 0, "how long the token stays valid, such as 720h (default auth.token_ttl)")
+	})
+	if err != nil {
+		return err
+	}
+
+	issuer, ok := app.backend.(interface {
+		IssueToken(ttl time.Duration) (string, error)
+	})
+	if !ok {
+		return errors.New("tokens can only be issued with direct access: run without -server")
+	}
+
+	token, err := issuer.IssueToken(ttl)
+	if err != nil {
+		return err
+	}
+	if app.json {
+		return app.printJSON(map[string]string{"token": token})
+	}
+
+	_, err = fmt.Fprintln(app.out, token)
+	return err
+}
+
+// parseArgs parses the flags a command adds with define, followed by exactly n arguments
+func parseArgs(usage string, args []string, n int, define func(*flag.FlagSet)) (*flag.FlagSet, error) {
+	flags := flag.NewFlagSet(usage, flag.ContinueOnError)
+	if define != nil {
+		define(flags)
+	}
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: timelinectl %s\n", usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, errUsage
+	}
+	if flags.NArg() != n {
+		flags.Usage()
+		return nil, errUsage
+	}
+	return flags, nil
+}
+
+func (a *app) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(a.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printTable prints n rows under a header, aligning the columns
+func (a *app) printTable(header []string, n int, row func(i int) []string) error {
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	writeRow := func(cells []string) {
+		for i, cell := range cells {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, cell)
+		}
+		fmt.Fprintln(w)
+	}
+
+	writeRow(header)
+	for i := 0; i < n; i++ {
+		writeRow(row(i))
+	}
+	return w.Flush()
+}
+
+func (a *app) printTasks(tasks ...models.TimelineTask) error {
+	return a.printTable([]string{"ID", "DONE", "START", "END", "DURATION", "PRIORITY", "TITLE"}, len(tasks), func(i int) []string {
+		task := tasks[i]
+		done := ""
+		if task.Completed {
+			done = "x"
+		}
+		return []string{task.ID, done, formatDate(task.StartDate), formatDate(task.EndDate), task.Duration.Label,
+			strconv.Itoa(task.Priority), task.Title}
+	})
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(clock.DateLayout)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeBackend serves testTimeline and records the generations asked of it
+type fakeBackend struct {
+	generated []models.TimelineInput
+}
+
+func (b *fakeBackend) ListGoals(ctx context.Context) ([]*models.Goal, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) ListTimelines(ctx context.Context, goalID string) ([]*models.Timeline, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) GetTimeline(ctx context.Context, id string) (*models.Timeline, error) {
+	return testTimeline(), nil
+}
+
+func (b *fakeBackend) GenerateTimeline(ctx context.Context, input models.TimelineInput) (*models.Timeline, error) {
+	b.generated = append(b.generated, input)
+	return testTimeline(), nil
+}
+
+func (b *fakeBackend) SetTaskCompleted(ctx context.Context, taskID string, completed bool) (*models.TimelineTask, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) Close() error {
+	return nil
+}
+
+func TestGenerateFromSpec(t *testing.T) {
+	spec := timelineSpec{
+		Goal:         "Learn Spanish",
+		CurrentLevel: "beginner",
+		Objectives:   "Order food; read the news",
+		CurrentDate:  "2024-03-01",
+		TargetDate:   "2024-09-01",
+	}
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "timeline.yaml")
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want models.TimelineInput
+	}{
+		{
+			name: "file",
+			args: []string{"-f", file},
+			want: models.TimelineInput{
+				Goal:         "Learn Spanish",
+				CurrentLevel: "beginner",
+				Objectives:   "Order food; read the news",
+				CurrentDate:  "2024-03-01",
+				TargetDate:   "2024-09-01",
+			},
+		},
+		{
+			name: "flags override the file",
+			args: []string{"-f", file, "-level", "intermediate", "-to", "2024-06-01"},
+			want: models.TimelineInput{
+				Goal:         "Learn Spanish",
+				CurrentLevel: "intermediate",
+				Objectives:   "Order food; read the news",
+				CurrentDate:  "2024-03-01",
+				TargetDate:   "2024-06-01",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &fakeBackend{}
+			var out strings.Builder
+			if err := generateTimeline(context.Background(), &app{backend: b, out: &out}, tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(b.generated) != 1 {
+				t.Fatalf("generated %d timelines, want 1", len(b.generated))
+			}
+			if b.generated[0] != tt.want {
+				t.Errorf("generated from %+v, want %+v", b.generated[0], tt.want)
+			}
+			if !strings.Contains(out.String(), "Generated timeline timeline-1: Learn Spanish") {
+				t.Errorf("output = %s, want the generated timeline", out.String())
+			}
+		})
+	}
+}
+
+func TestGenerateInvalidSpec(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "timeline.yaml")
+	if err := os.WriteFile(file, []byte("goal: [unterminated"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &fakeBackend{}
+	err := generateTimeline(context.Background(), &app{backend: b, out: &strings.Builder{}}, []string{"-f", file})
+	if err == nil || !strings.Contains(err.Error(), "invalid timeline file") {
+		t.Errorf("error = %v, want an invalid timeline file", err)
+	}
+	if len(b.generated) != 0 {
+		t.Error("generated a timeline from an invalid file")
+	}
+}
+
+func TestExportICS(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "timeline.ics")
+	var out strings.Builder
+	err := exportTimeline(context.Background(), &app{backend: &fakeBackend{}, out: &out}, []string{"-format", "ics", "-o", output, "timeline-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("wrote %q to standard output, want only the file", out.String())
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Compare all but the time of export
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n") {
+		if !strings.HasPrefix(line, "DTSTAMP:") {
+			lines = append(lines, line)
+		}
+	}
+	want := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Timeline Generator//Timeline Export//EN",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:Learn Spanish",
+		"BEGIN:VEVENT",
+		"UID:task-1@timeline-generator",
+		"DTSTART;VALUE=DATE:20240301",
+		"DTEND;VALUE=DATE:20240308",
+		"SUMMARY:Learn the basics",
+		`DESCRIPTION:Greetings and numbers\nThe present tense`,
+		"PRIORITY:1",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:task-2@timeline-generator",
+		"DTSTART;VALUE=DATE:20240308",
+		"DTEND;VALUE=DATE:20240309",
+		"RRULE:FREQ=DAILY;UNTIL=20240331",
+		`SUMMARY:Practice\, daily`,
+		"PRIORITY:5",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:task-3@timeline-generator",
+		"DTSTART;VALUE=DATE:20240331",
+		"DTEND;VALUE=DATE:20240401",
+		"SUMMARY:Take the exam",
+		"PRIORITY:9",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}
+	if got, want := strings.Join(lines, "\n"), strings.Join(want, "\n"); got != want {
+		t.Errorf("exported\n%s\nwant\n%s", got, want)
+	}
+	if n := strings.Count(string(data), "DTSTAMP:"); n != 3 {
+		t.Errorf("exported %d DTSTAMP lines, want one per event", n)
+	}
+}
+
+func TestExportMarkdown(t *testing.T) {
+	var out strings.Builder
+	if err := exportTimeline(context.Background(), &app{backend: &fakeBackend{}, out: &out}, []string{"timeline-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "# Learn Spanish\n") {
+		t.Errorf("exported %q, want the Markdown document", out.String())
+	}
+
+	err := exportTimeline(context.Background(), &app{backend: &fakeBackend{}, out: &out}, []string{"-format", "pdf", "timeline-1"})
+	if err == nil || !strings.Contains(err.Error(), "unknown format") {
+		t.Errorf("error = %v, want an unknown format", err)
+	}
+}
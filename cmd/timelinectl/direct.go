@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/activity"
+	"github.com/jukemori/timeline-generator/internal/auth"
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/config"
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/openai"
+	"github.com/jukemori/timeline-generator/internal/repository"
+	"github.com/jukemori/timeline-generator/internal/service"
+	"github.com/jukemori/timeline-generator/internal/webhook"
+)
+
+// directBackend uses the database and the LLM provider through the same services as the server. It
+// trusts its caller to act as the user: only the goals the user can see are read, and generation counts
+// against the user's rate limit and monthly quota as it does through the server.
+type directBackend struct {
+	user              *models.User
+	authenticator     *auth.Authenticator
+	tokenTTL          time.Duration
+	openaiClient      *openai.Client
+	generationLimiter *service.GenerationLimiter
+	timelineGenerator *service.TimelineGenerator
+	taskService       *service.TaskService
+	goalRepo          *repository.GoalRepository
+	timelineRepo      *repository.TimelineRepository
+}
+
+// newDirectBackend connects to the database named by the configuration, loaded as the server loads it
+// from configArgs and the environment, and checks the user exists
+func newDirectBackend(ctx context.Context, configArgs []string, userID string) (*directBackend, error) {
+	cfg, err := config.Load("timelinectl", configArgs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	database.InitDB(ctx, cfg.Database.Connection())
+
+	user, err := repository.NewUserRepository().GetByID(ctx, userID)
+	if err != nil {
+		database.DB.Close()
+		return nil, notFound(err, "user", userID)
+	}
+
+	authenticator, err := auth.NewAuthenticator(cfg.Auth.TokenSecret, cfg.Auth.TokenIssuer, nil)
+	if err != nil {
+		database.DB.Close()
+		return nil, err
+	}
+
+	openaiClient := openai.NewClient(openai.Config{
+		APIKey:      cfg.LLM.APIKey,
+		Model:       cfg.LLM.Model,
+		Temperature: float32(cfg.LLM.Temperature),
+		Timeout:     cfg.LLM.Timeout,
+	})
+
+	generationLimiter := service.NewGenerationLimiter(service.GenerationLimits{
+		UserPerMinute: cfg.Generation.RatePerMinute,
+		UserBurst:     cfg.Generation.RateBurst,
+		IPPerMinute:   cfg.Generation.IPRatePerMinute,
+		IPBurst:       cfg.Generation.IPRateBurst,
+		MonthlyQuota:  cfg.Generation.MonthlyQuota,
+	}, clock.System)
+
+	// Record activity as the server does, so the changes show in the goal's feed, and queue the webhook
+	// deliveries of the changes for the server's workers to send
+	eventBus := events.NewBus()
+	eventBus.Subscribe(activity.NewRecorder(activity.NewHub()).Handle)
+	if cfg.Features.Webhooks {
+		eventBus.Subscribe(webhook.NewDispatcher(webhook.DefaultConfig()).Handle)
+	}
+
+	timelineScheduler := service.NewTimelineScheduler(clock.System, eventBus)
+	return &directBackend{
+		user:              user,
+		authenticator:     authenticator,
+		tokenTTL:          cfg.Auth.TokenTTL,
+		openaiClient:      openaiClient,
+		generationLimiter: generationLimiter,
+		timelineGenerator: service.NewTimelineGenerator(openaiClient, timelineScheduler, eventBus),
+		taskService:       service.NewTaskService(eventBus),
+		goalRepo:          repository.NewGoalRepository(),
+		timelineRepo:      repository.NewTimelineRepository(),
+	}, nil
+}
+
+func (b *directBackend) ListGoals(ctx context.Context) ([]*models.Goal, error) {
+	owned, err := b.goalRepo.GetByUserID(ctx, b.user.OrganizationID, b.user.ID)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := b.goalRepo.GetSharedWithUserID(ctx, b.user.OrganizationID, b.user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return append(owned, shared...), nil
+}
+
+func (b *directBackend) ListTimelines(ctx context.Context, goalID string) ([]*models.Timeline, error) {
+	if _, err := b.goalRepo.GetVisible(ctx, b.user.OrganizationID, goalID, b.user.ID); err != nil {
+		return nil, notFound(err, "goal", goalID)
+	}
+	return b.timelineRepo.GetVisibleByGoalID(ctx, b.user.OrganizationID, goalID, b.user.ID)
+}
+
+func (b *directBackend) GetTimeline(ctx context.Context, id string) (*models.Timeline, error) {
+	timeline, err := b.timelineRepo.GetVisible(ctx, b.user.OrganizationID, id, b.user.ID)
+	if err != nil {
+		return nil, notFound(err, "timeline", id)
+	}
+	return timeline, nil
+}
+
+func (b *directBackend) GenerateTimeline(ctx context.Context, input models.TimelineInput) (*models.Timeline, error) {
+	if !b.openaiClient.Configured() {
+		return nil, errors.New("llm.api_key is not set")
+	}
+
+	// The rate limit is per user and process, but the monthly quota is shared with the server
+	if err := b.generationLimiter.Acquire(ctx, b.user.ID, ""); err != nil {
+		return nil, err
+	}
+
+	timeline, err := b.timelineGenerator.GenerateTimeline(ctx, b.user.ID, input)
+	if err != nil {
+		if releaseErr := b.generationLimiter.Release(ctx, b.user.ID); releaseErr != nil {
+			log.Printf("failed to release generation usage for user %s: %v", b.user.ID, releaseErr)
+		}
+		return nil, err
+	}
+	return timeline, nil
+}
+
+func (b *directBackend) SetTaskCompleted(ctx context.Context, taskID string, completed bool) (*models.TimelineTask, error) {
+	if err := service.CheckTaskAccess(ctx, b.user, taskID, models.GoalRoleEditor); err != nil {
+		return nil, err
+	}
+	task, err := b.taskService.SetCompleted(ctx, b.user, taskID, completed)
+	if err != nil {
+		return nil, notFound(err, "task", taskID)
+	}
+	return task, nil
+}
+
+// IssueToken issues a token for the user that the server accepts, valid for ttl or by default the
+// configured auth.token_ttl
+func (b *directBackend) IssueToken(ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		ttl = b.tokenTTL
+	}
+	return b.authenticator.IssueToken(b.user.ID, ttl)
+}
+
+func (b *directBackend) Close() error {
+	return database.DB.Close()
+}
+
+// notFound names the missing resource for sql.ErrNoRows and returns other errors as they are
+func notFound(err error, resource, id string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s %s not found", resource, id)
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/jukemori/timeline-generator/internal/audit"
+)
+
+const usageHeader = `Usage: timelinectl [flags] <command> [arguments]
+
+Generates and manages timelines through a running server's REST API when -server is given, as the
+user -token identifies, or directly against the database and the LLM provider as -user otherwise.
+Direct access reads the server's configuration (see config.example.yaml) and bypasses access checks.
+Generation counts against the user's quota, and the server sends the webhooks for the changes, but
+not the emails.
+It can also issue the tokens the API accepts.
+
+Commands:
+`
+
+// command is a subcommand of timelinectl
+type command struct {
+	args        string
+	description string
+	run         func(ctx context.Context, app *app, args []string) error
+}
+
+var commands = map[string]command{
+	"goals":     {"", "list your goals and those shared with you", listGoals},
+	"timelines": {"GOAL_ID", "list the timelines of a goal", listTimelines},
+	"tasks":     {"TIMELINE_ID", "list the tasks of a timeline", listTasks},
+	"generate":  {"[flags]", "generate a timeline from flags or a YAML file", generateTimeline},
+	"complete":  {"[-undo] TASK_ID", "mark a task complete, or not complete with -undo", completeTask},
+	"export":    {"[flags] TIMELINE_ID", "export a timeline as Markdown or iCalendar", exportTimeline},
+	"token":     {"[-ttl DURATION]", "issue an API token for the user (direct access only)", issueToken},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err := run(ctx, os.Args[1:])
+	stop()
+
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(0)
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "timelinectl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// errUsage is returned once the usage of a command has been printed for arguments it does not accept
+var errUsage = errors.New("invalid usage")
+
+func run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("timelinectl", flag.ContinueOnError)
+	server := flags.String("server", os.Getenv("TIMELINECTL_SERVER"), "base URL of the server to use, such as http://localhost:8080 (default $TIMELINECTL_SERVER)")
+	token := flags.String("token", os.Getenv("TIMELINECTL_TOKEN"), "API token identifying the user to act as with -server (default $TIMELINECTL_TOKEN)")
+	user := flags.String("user", os.Getenv("TIMELINECTL_USER"), "ID of the user to act as with direct access (default $TIMELINECTL_USER)")
+	configFile := flags.String("config", "", "YAML configuration file for direct access, overriding $CONFIG_FILE")
+	jsonOutput := flags.Bool("json", false, "print results as JSON")
+	flags.Usage = func() { printUsage(flags) }
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "timelinectl: unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return errUsage
+	}
+
+	var b backend
+	if *server != "" {
+		if *token == "" {
+			return errors.New("no token given: set -token or $TIMELINECTL_TOKEN")
+		}
+		b = newAPIBackend(*server, *token)
+	} else {
+		if *user == "" {
+			return errors.New("no user given: set -user or $TIMELINECTL_USER")
+		}
+		var configArgs []string
+		if *configFile != "" {
+			configArgs = []string{"-config", *configFile}
+		}
+		var err error
+		if b, err = newDirectBackend(ctx, configArgs, *user); err != nil {
+			return err
+		}
+		// Attribute the changes to the user in the audit log, as made through timelinectl
+		ctx = audit.WithActor(ctx, *user, "timelinectl")
+	}
+	defer b.Close()
+
+	app := &app{backend: b, out: os.Stdout, json: *jsonOutput}
+	return cmd.run(ctx, app, flags.Args()[1:])
+}
+
+func printUsage(flags *flag.FlagSet) {
+	out := flags.Output()
+	fmt.Fprint(out, usageHeader)
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cmd := commands[name]
+		fmt.Fprintf(out, "  %-32s %s\n", name+" "+cmd.args, cmd.description)
+	}
+
+	fmt.Fprint(out, "\nFlags:\n")
+	flags.PrintDefaults()
+}
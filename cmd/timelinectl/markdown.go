@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// writeMarkdown writes a timeline as a Markdown document with a checklist of its tasks
+func writeMarkdown(w io.Writer, timeline *models.Timeline) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "# %s\n\n", timeline.Title)
+	if timeline.Description != "" {
+		fmt.Fprintf(out, "%s\n\n", timeline.Description)
+	}
+	fmt.Fprintf(out, "%s to %s\n\n## Tasks\n\n", formatDate(timeline.StartDate), formatDate(timeline.EndDate))
+
+	for _, task := range timeline.Tasks {
+		check := " "
+		if task.Completed {
+			check = "x"
+		}
+		fmt.Fprintf(out, "- [%s] **%s** (%s to %s", check, task.Title, formatDate(task.StartDate), formatDate(task.EndDate))
+		if task.Duration.Label != "" {
+			fmt.Fprintf(out, ", %s", task.Duration.Label)
+		}
+		if task.IsRecurring() {
+			fmt.Fprintf(out, ", repeats %s", task.Recurrence)
+		}
+		fmt.Fprint(out, ")\n")
+
+		// Indent the description so it stays part of the list item
+		if task.Description != "" {
+			for _, line := range strings.Split(strings.TrimSpace(task.Description), "\n") {
+				fmt.Fprintf(out, "  %s\n", line)
+			}
+		}
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// testTimeline is a timeline with a plain, a completed and a recurring task
+func testTimeline() *models.Timeline {
+	return &models.Timeline{
+		ID:          "timeline-1",
+		Title:       "Learn Spanish",
+		Description: "Reach B1 by summer",
+		StartDate:   date(2024, time.March, 1),
+		EndDate:     date(2024, time.March, 31),
+		Tasks: []models.TimelineTask{
+			{
+				ID:          "task-1",
+				Title:       "Learn the basics",
+				Description: "Greetings and numbers\nThe present tense",
+				StartDate:   date(2024, time.March, 1),
+				EndDate:     date(2024, time.March, 7),
+				Duration:    models.Duration{Label: "1 week"},
+				Priority:    5,
+				Completed:   true,
+			},
+			{
+				ID:         "task-2",
+				Title:      "Practice, daily",
+				StartDate:  date(2024, time.March, 8),
+				EndDate:    date(2024, time.March, 31),
+				Recurrence: "FREQ=DAILY",
+				Priority:   3,
+			},
+			{
+				ID:        "task-3",
+				Title:     "Take the exam",
+				StartDate: date(2024, time.March, 31),
+				EndDate:   date(2024, time.March, 31),
+				Priority:  1,
+			},
+		},
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeline *models.Timeline
+		want     string
+	}{
+		{
+			name:     "tasks",
+			timeline: testTimeline(),
+			want: `# Learn Spanish
+
+Reach B1 by summer
+
+2024-03-01 to 2024-03-31
+
+## Tasks
+
+- [x] **Learn the basics** (2024-03-01 to 2024-03-07, 1 week)
+  Greetings and numbers
+  The present tense
+- [ ] **Practice, daily** (2024-03-08 to 2024-03-31, repeats FREQ=DAILY)
+- [ ] **Take the exam** (2024-03-31 to 2024-03-31)
+`,
+		},
+		{
+			name:     "no description or tasks",
+			timeline: &models.Timeline{Title: "Empty", StartDate: date(2024, time.March, 1)},
+			want: `# Empty
+
+2024-03-01 to -
+
+## Tasks
+
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := writeMarkdown(&out, tt.timeline); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("writeMarkdown wrote\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,31 +1,423 @@
 package model
 
+import "time"
+
 // TimelineInput represents the input for generating a timeline
 type TimelineInput struct {
 	CurrentLevel string `json:"currentLevel"`
 	Goal         string `json:"goal"`
 	Objectives   string `json:"objectives"`
-	CurrentDate  string `json:"currentDate"`
-	TargetDate   *string `json:"targetDate,omitempty"`
+	CurrentDate  time.Time  `json:"currentDate"`
+	TargetDate   *time.Time `json:"targetDate,omitempty"`
 }
 
 // Timeline represents a generated timeline for achieving a goal
 type Timeline struct {
 	ID          string          `json:"id"`
+	GoalID      string          `json:"goalId"`
 	Title       string          `json:"title"`
 	Description string          `json:"description"`
-	StartDate   string          `json:"startDate"`
-	EndDate     string          `json:"endDate"`
+	StartDate   time.Time       `json:"startDate"`
+	EndDate     time.Time       `json:"endDate"`
 	Tasks       []*TimelineTask `json:"tasks"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+// GoalMember represents a user with access to a goal
+type GoalMember struct {
+	UserID    string    `json:"userId"`
+	Email     string    `json:"email"`
+	Role      GoalRole  `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GoalInvitation represents a pending invitation to a goal
+type GoalInvitation struct {
+	ID        string    `json:"id"`
+	GoalID    string    `json:"goalId"`
+	GoalTitle string    `json:"goalTitle"`
+	Email     string    `json:"email"`
+	Role      GoalRole  `json:"role"`
+	InvitedBy string    `json:"invitedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Comment represents a comment on a goal or task, with its replies
+type Comment struct {
+	ID          string     `json:"id"`
+	GoalID      string     `json:"goalId"`
+	TaskID      *string    `json:"taskId,omitempty"`
+	ParentID    *string    `json:"parentId,omitempty"`
+	AuthorID    string     `json:"authorId"`
+	AuthorEmail string     `json:"authorEmail"`
+	Body        string     `json:"body"`
+	Mentions    []string   `json:"mentions"`
+	Replies     []*Comment `json:"replies"`
+	EditedAt    *time.Time `json:"editedAt,omitempty"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// ActivityItem represents something that happened on a timeline
+type ActivityItem struct {
+	ID         string       `json:"id"`
+	TimelineID string       `json:"timelineId"`
+	TaskID     *string      `json:"taskId,omitempty"`
+	ActorID    *string      `json:"actorId,omitempty"`
+	Kind       ActivityKind `json:"kind"`
+	Comment    *Comment     `json:"comment,omitempty"`
+	OccurredAt time.Time    `json:"occurredAt"`
+}
+
+// TrashItem represents a deleted goal, timeline or task that can still be restored
+type TrashItem struct {
+	Kind       TrashKind `json:"kind"`
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	GoalID     string    `json:"goalId"`
+	TimelineID *string   `json:"timelineId,omitempty"`
+	DeletedAt  time.Time `json:"deletedAt"`
+	PurgeAt    time.Time `json:"purgeAt"`
+}
+
+// AuditEntry represents a recorded change to a row, with its values as JSON text
+type AuditEntry struct {
+	ID         string      `json:"id"`
+	ActorID    *string     `json:"actorId,omitempty"`
+	Operation  string      `json:"operation"`
+	Action     AuditAction `json:"action"`
+	EntityType string      `json:"entityType"`
+	EntityID   string      `json:"entityId"`
+	Before     *string     `json:"before,omitempty"`
+	After      *string     `json:"after,omitempty"`
+	RequestID  *string     `json:"requestId,omitempty"`
+	IPAddress  *string     `json:"ipAddress,omitempty"`
+	UserAgent  *string     `json:"userAgent,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
+
+// AuditFilter restricts audit log queries by who changed what, and when
+type AuditFilter struct {
+	ActorID    *string      `json:"actorId,omitempty"`
+	EntityType *string      `json:"entityType,omitempty"`
+	EntityID   *string      `json:"entityId,omitempty"`
+	Action     *AuditAction `json:"action,omitempty"`
+	Operation  *string      `json:"operation,omitempty"`
+	From       *time.Time   `json:"from,omitempty"`
+	To         *time.Time   `json:"to,omitempty"`
+}
+
+// GoalTemplate represents a reusable timeline skeleton
+type GoalTemplate struct {
+	ID           string          `json:"id"`
+	Title        string          `json:"title"`
+	Description  string          `json:"description"`
+	Goal         string          `json:"goal"`
+	CurrentLevel string          `json:"currentLevel"`
+	TargetLevel  string          `json:"targetLevel"`
+	Curated      bool            `json:"curated"`
+	SpanDays     int             `json:"spanDays"`
+	Tasks        []*TemplateTask `json:"tasks"`
+	CreatedAt    *time.Time      `json:"createdAt,omitempty"`
+	UpdatedAt    *time.Time      `json:"updatedAt,omitempty"`
+}
+
+// TemplateTask represents a task in a goal template, placed by its offset from the start of the plan
+type TemplateTask struct {
+	Title            string    `json:"title"`
+	Description      string    `json:"description"`
+	StartOffset      int       `json:"startOffset"`
+	SpanDays         int       `json:"spanDays"`
+	Duration         string    `json:"duration"`
+	DurationEstimate *Duration `json:"durationEstimate,omitempty"`
+	EffortHours      float64   `json:"effortHours"`
+	Recurrence       *string   `json:"recurrence,omitempty"`
+	Priority         int       `json:"priority"`
+}
+
+// User represents the authenticated user
+type User struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organizationId"`
+	Email          string    `json:"email"`
+	Role           UserRole  `json:"role"`
+	TimeZone       string    `json:"timeZone"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Organization represents the tenant users and their goals belong to
+type Organization struct {
+	ID        string                `json:"id"`
+	Name      string                `json:"name"`
+	Settings  *OrganizationSettings `json:"settings"`
+	CreatedAt time.Time             `json:"createdAt"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+}
+
+// OrganizationSettings represents an organization's overrides of the server defaults
+type OrganizationSettings struct {
+	DefaultModel   *string `json:"defaultModel,omitempty"`
+	PromptTemplate *string `json:"promptTemplate,omitempty"`
+	MonthlyQuota   *int    `json:"monthlyQuota,omitempty"`
+}
+
+// OrganizationSettingsInput represents an organization's new settings; omitted settings use the server defaults
+type OrganizationSettingsInput struct {
+	DefaultModel   *string `json:"defaultModel,omitempty"`
+	PromptTemplate *string `json:"promptTemplate,omitempty"`
+	MonthlyQuota   *int    `json:"monthlyQuota,omitempty"`
 }
 
 // TimelineTask represents a single task in a timeline
 type TimelineTask struct {
-	ID          string `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	StartDate   string `json:"startDate"`
-	EndDate     string `json:"endDate"`
-	Duration    string `json:"duration"`
-	Priority    int    `json:"priority"`
-} 
\ No newline at end of file
+	ID               string    `json:"id"`
+	Title            string    `json:"title"`
+	Description      string    `json:"description"`
+	StartDate        time.Time `json:"startDate"`
+	EndDate          time.Time `json:"endDate"`
+	Duration         string    `json:"duration"`
+	DurationEstimate *Duration `json:"durationEstimate,omitempty"`
+	EffortHours      *float64  `json:"effortHours,omitempty"`
+	Recurrence       *string   `json:"recurrence,omitempty"`
+	Priority         int       `json:"priority"`
+	Completed        bool      `json:"completed"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// TaskOccurrence represents one occurrence of a recurring task
+type TaskOccurrence struct {
+	Date        time.Time  `json:"date"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// Streak represents how consistently a recurring task has been done
+type Streak struct {
+	Current              int `json:"current"`
+	Longest              int `json:"longest"`
+	CompletedOccurrences int `json:"completedOccurrences"`
+	DueOccurrences       int `json:"dueOccurrences"`
+	TotalOccurrences     int `json:"totalOccurrences"`
+}
+
+// Duration represents a task's effort estimate along with its human-readable label
+type Duration struct {
+	Label string       `json:"label"`
+	Value float64      `json:"value"`
+	Unit  DurationUnit `json:"unit"`
+	Days  float64      `json:"days"`
+	Hours float64      `json:"hours"`
+}
+
+// GenerationUsage represents a user's timeline generation usage for a month
+type GenerationUsage struct {
+	UserID       string `json:"userId"`
+	Period       string `json:"period"`
+	Generations  int    `json:"generations"`
+	MonthlyQuota int    `json:"monthlyQuota"`
+	Remaining    int    `json:"remaining"`
+}
+
+// LLMUsageSummary aggregates language model usage for a user, day or model
+type LLMUsageSummary struct {
+	Key              string  `json:"key"`
+	Calls            int     `json:"calls"`
+	Failures         int     `json:"failures"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	EstimatedCost    float64 `json:"estimatedCost"`
+	AverageLatencyMs float64 `json:"averageLatencyMs"`
+}
+
+// LLMCall represents a single recorded language model call
+type LLMCall struct {
+	ID               string  `json:"id"`
+	UserID           *string `json:"userId,omitempty"`
+	GoalID           *string `json:"goalId,omitempty"`
+	TimelineID       *string `json:"timelineId,omitempty"`
+	Operation        string  `json:"operation"`
+	Model            string  `json:"model"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	LatencyMs        int     `json:"latencyMs"`
+	EstimatedCost    float64 `json:"estimatedCost"`
+	Success          bool      `json:"success"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+// UsageFilter restricts usage queries to a user and date range
+type UsageFilter struct {
+	UserID *string    `json:"userId,omitempty"`
+	From   *time.Time `json:"from,omitempty"`
+	To     *time.Time `json:"to,omitempty"`
+}
+
+// GenerationJob represents a timeline generation running in the background
+type GenerationJob struct {
+	ID          string              `json:"id"`
+	Status      GenerationJobStatus `json:"status"`
+	Attempts    int                 `json:"attempts"`
+	MaxAttempts int                 `json:"maxAttempts"`
+	LastError   *string             `json:"lastError,omitempty"`
+	TimelineID  string              `json:"-"`
+	CreatedAt   time.Time           `json:"createdAt"`
+	UpdatedAt   time.Time           `json:"updatedAt"`
+	StartedAt   *time.Time          `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time          `json:"finishedAt,omitempty"`
+}
+
+// WebhookSubscription represents an endpoint receiving a user's events
+type WebhookSubscription struct {
+	ID               string             `json:"id"`
+	OrganizationWide bool               `json:"organizationWide"`
+	URL              string             `json:"url"`
+	EventTypes       []WebhookEventType `json:"eventTypes"`
+	Active           bool               `json:"active"`
+	CreatedAt        time.Time          `json:"createdAt"`
+	UpdatedAt        time.Time          `json:"updatedAt"`
+}
+
+// CreatedWebhookSubscription returns the signing secret, which is only shown once
+type CreatedWebhookSubscription struct {
+	Subscription *WebhookSubscription `json:"subscription"`
+	Secret       string               `json:"secret"`
+}
+
+// WebhookDelivery represents an attempt to send an event to a webhook
+type WebhookDelivery struct {
+	ID             string                `json:"id"`
+	SubscriptionID string                `json:"subscriptionId"`
+	EventID        string                `json:"eventId"`
+	EventType      WebhookEventType      `json:"eventType"`
+	Payload        string                `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	ResponseStatus *int                  `json:"responseStatus,omitempty"`
+	LastError      *string               `json:"lastError,omitempty"`
+	NextAttemptAt  time.Time             `json:"nextAttemptAt"`
+	DeliveredAt    *time.Time            `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time             `json:"createdAt"`
+	UpdatedAt      time.Time             `json:"updatedAt"`
+}
+
+// WebhookSubscriptionInput represents the input for subscribing to events
+type WebhookSubscriptionInput struct {
+	URL              string             `json:"url"`
+	EventTypes       []WebhookEventType `json:"eventTypes"`
+	OrganizationWide *bool              `json:"organizationWide"`
+}
+
+// ReminderSettings represents when a user is reminded about their tasks
+type ReminderSettings struct {
+	Enabled       bool       `json:"enabled"`
+	StartLeadDays []int      `json:"startLeadDays"`
+	DueLeadDays   []int      `json:"dueLeadDays"`
+	Overdue       bool       `json:"overdue"`
+	CreatedAt     *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt     *time.Time `json:"updatedAt,omitempty"`
+}
+
+// TaskReminder represents a reminder sent about a task
+type TaskReminder struct {
+	TaskID   string       `json:"taskId"`
+	Kind     ReminderKind `json:"kind"`
+	LeadDays int          `json:"leadDays"`
+	DueOn    time.Time    `json:"dueOn"`
+	Message  string       `json:"message"`
+	SentAt   time.Time    `json:"sentAt"`
+}
+
+// NotificationPreferences represents which emails a user receives
+type NotificationPreferences struct {
+	Email          string     `json:"email"`
+	EmailReminders bool       `json:"emailReminders"`
+	WeeklyDigest   bool       `json:"weeklyDigest"`
+	DigestDay      int        `json:"digestDay"`
+	LastDigestAt   *time.Time `json:"lastDigestAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// NotificationPreferencesInput represents changes to a user's notification preferences
+type NotificationPreferencesInput struct {
+	EmailReminders *bool `json:"emailReminders,omitempty"`
+	WeeklyDigest   *bool `json:"weeklyDigest,omitempty"`
+	DigestDay      *int  `json:"digestDay,omitempty"`
+}
+
+// ReminderSettingsInput represents changes to a user's reminder settings
+type ReminderSettingsInput struct {
+	Enabled       *bool `json:"enabled,omitempty"`
+	StartLeadDays []int `json:"startLeadDays,omitempty"`
+	DueLeadDays   []int `json:"dueLeadDays,omitempty"`
+	Overdue       *bool `json:"overdue,omitempty"`
+}
+
+// Availability represents when a user can work on their tasks
+type Availability struct {
+	WorkingDays    []Weekday   `json:"workingDays"`
+	HoursPerDay    float64     `json:"hoursPerDay"`
+	WeeklyCapacity float64     `json:"weeklyCapacity"`
+	HolidaySet     *HolidaySet `json:"holidaySet,omitempty"`
+	BlackoutDates  []time.Time `json:"blackoutDates"`
+	CreatedAt      *time.Time  `json:"createdAt,omitempty"`
+	UpdatedAt      *time.Time  `json:"updatedAt,omitempty"`
+}
+
+// HolidaySet represents a bundled calendar of public holidays
+type HolidaySet struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// Holiday represents a public holiday
+type Holiday struct {
+	Date time.Time `json:"date"`
+	Name string    `json:"name"`
+}
+
+// AvailabilityInput represents changes to a user's availability
+type AvailabilityInput struct {
+	WorkingDays    []Weekday   `json:"workingDays,omitempty"`
+	HoursPerDay    *float64    `json:"hoursPerDay,omitempty"`
+	WeeklyCapacity *float64    `json:"weeklyCapacity,omitempty"`
+	HolidaySet     *string     `json:"holidaySet,omitempty"`
+	BlackoutDates  []time.Time `json:"blackoutDates,omitempty"`
+}
+
+// Workload represents the hours a user has planned per week against their capacity
+type Workload struct {
+	WeeklyCapacity float64             `json:"weeklyCapacity"`
+	Weeks          []*WorkloadWeek     `json:"weeks"`
+	Conflicts      []*ScheduleConflict `json:"conflicts"`
+}
+
+// WorkloadWeek represents the hours planned in a week starting on a Monday
+type WorkloadWeek struct {
+	WeekStart    time.Time       `json:"weekStart"`
+	PlannedHours float64         `json:"plannedHours"`
+	Capacity     float64         `json:"capacity"`
+	OverCapacity bool            `json:"overCapacity"`
+	Timelines    []*TimelineLoad `json:"timelines"`
+}
+
+// TimelineLoad represents the hours a timeline contributes to a week
+type TimelineLoad struct {
+	TimelineID string  `json:"timelineId"`
+	Title      string  `json:"title"`
+	Hours      float64 `json:"hours"`
+}
+
+// ScheduleConflict represents a goal that cannot be finished by its target date within the user's capacity
+type ScheduleConflict struct {
+	GoalID           string    `json:"goalId"`
+	Title            string    `json:"title"`
+	TargetDate       time.Time `json:"targetDate"`
+	ProjectedEndDate time.Time `json:"projectedEndDate"`
+}
@@ -2,8 +2,566 @@
 
 package model
 
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type AddCommentInput struct {
+	GoalID   *string `json:"goalId,omitempty"`
+	TaskID   *string `json:"taskId,omitempty"`
+	ParentID *string `json:"parentId,omitempty"`
+	Body     string  `json:"body"`
+}
+
 type Mutation struct {
 }
 
 type Query struct {
 }
+
+type Subscription struct {
+}
+
+type ActivityKind string
+
+const (
+	ActivityKindCreated        ActivityKind = "CREATED"
+	ActivityKindRescheduled    ActivityKind = "RESCHEDULED"
+	ActivityKindCompleted      ActivityKind = "COMPLETED"
+	ActivityKindCommented      ActivityKind = "COMMENTED"
+	ActivityKindCommentEdited  ActivityKind = "COMMENT_EDITED"
+	ActivityKindCommentDeleted ActivityKind = "COMMENT_DELETED"
+)
+
+var AllActivityKind = []ActivityKind{
+	ActivityKindCreated,
+	ActivityKindRescheduled,
+	ActivityKindCompleted,
+	ActivityKindCommented,
+	ActivityKindCommentEdited,
+	ActivityKindCommentDeleted,
+}
+
+func (e ActivityKind) IsValid() bool {
+	switch e {
+	case ActivityKindCreated, ActivityKindRescheduled, ActivityKindCompleted, ActivityKindCommented, ActivityKindCommentEdited, ActivityKindCommentDeleted:
+		return true
+	}
+	return false
+}
+
+func (e ActivityKind) String() string {
+	return string(e)
+}
+
+func (e *ActivityKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ActivityKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ActivityKind", str)
+	}
+	return nil
+}
+
+func (e ActivityKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type AuditAction string
+
+const (
+	AuditActionCreate AuditAction = "CREATE"
+	AuditActionUpdate AuditAction = "UPDATE"
+	AuditActionDelete AuditAction = "DELETE"
+)
+
+var AllAuditAction = []AuditAction{
+	AuditActionCreate,
+	AuditActionUpdate,
+	AuditActionDelete,
+}
+
+func (e AuditAction) IsValid() bool {
+	switch e {
+	case AuditActionCreate, AuditActionUpdate, AuditActionDelete:
+		return true
+	}
+	return false
+}
+
+func (e AuditAction) String() string {
+	return string(e)
+}
+
+func (e *AuditAction) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AuditAction(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AuditAction", str)
+	}
+	return nil
+}
+
+func (e AuditAction) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type DurationUnit string
+
+const (
+	DurationUnitHours DurationUnit = "HOURS"
+	DurationUnitDays  DurationUnit = "DAYS"
+)
+
+var AllDurationUnit = []DurationUnit{
+	DurationUnitHours,
+	DurationUnitDays,
+}
+
+func (e DurationUnit) IsValid() bool {
+	switch e {
+	case DurationUnitHours, DurationUnitDays:
+		return true
+	}
+	return false
+}
+
+func (e DurationUnit) String() string {
+	return string(e)
+}
+
+func (e *DurationUnit) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DurationUnit(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DurationUnit", str)
+	}
+	return nil
+}
+
+func (e DurationUnit) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type GenerationJobStatus string
+
+const (
+	GenerationJobStatusQueued    GenerationJobStatus = "QUEUED"
+	GenerationJobStatusRunning   GenerationJobStatus = "RUNNING"
+	GenerationJobStatusSucceeded GenerationJobStatus = "SUCCEEDED"
+	GenerationJobStatusFailed    GenerationJobStatus = "FAILED"
+	GenerationJobStatusCancelled GenerationJobStatus = "CANCELLED"
+)
+
+var AllGenerationJobStatus = []GenerationJobStatus{
+	GenerationJobStatusQueued,
+	GenerationJobStatusRunning,
+	GenerationJobStatusSucceeded,
+	GenerationJobStatusFailed,
+	GenerationJobStatusCancelled,
+}
+
+func (e GenerationJobStatus) IsValid() bool {
+	switch e {
+	case GenerationJobStatusQueued, GenerationJobStatusRunning, GenerationJobStatusSucceeded, GenerationJobStatusFailed, GenerationJobStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e GenerationJobStatus) String() string {
+	return string(e)
+}
+
+func (e *GenerationJobStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = GenerationJobStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid GenerationJobStatus", str)
+	}
+	return nil
+}
+
+func (e GenerationJobStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type GoalRole string
+
+const (
+	GoalRoleViewer    GoalRole = "VIEWER"
+	GoalRoleCommenter GoalRole = "COMMENTER"
+	GoalRoleEditor    GoalRole = "EDITOR"
+	GoalRoleOwner     GoalRole = "OWNER"
+)
+
+var AllGoalRole = []GoalRole{
+	GoalRoleViewer,
+	GoalRoleCommenter,
+	GoalRoleEditor,
+	GoalRoleOwner,
+}
+
+func (e GoalRole) IsValid() bool {
+	switch e {
+	case GoalRoleViewer, GoalRoleCommenter, GoalRoleEditor, GoalRoleOwner:
+		return true
+	}
+	return false
+}
+
+func (e GoalRole) String() string {
+	return string(e)
+}
+
+func (e *GoalRole) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = GoalRole(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid GoalRole", str)
+	}
+	return nil
+}
+
+func (e GoalRole) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReminderKind string
+
+const (
+	ReminderKindStarts  ReminderKind = "STARTS"
+	ReminderKindDue     ReminderKind = "DUE"
+	ReminderKindOverdue ReminderKind = "OVERDUE"
+)
+
+var AllReminderKind = []ReminderKind{
+	ReminderKindStarts,
+	ReminderKindDue,
+	ReminderKindOverdue,
+}
+
+func (e ReminderKind) IsValid() bool {
+	switch e {
+	case ReminderKindStarts, ReminderKindDue, ReminderKindOverdue:
+		return true
+	}
+	return false
+}
+
+func (e ReminderKind) String() string {
+	return string(e)
+}
+
+func (e *ReminderKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReminderKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReminderKind", str)
+	}
+	return nil
+}
+
+func (e ReminderKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type TrashKind string
+
+const (
+	TrashKindGoal     TrashKind = "GOAL"
+	TrashKindTimeline TrashKind = "TIMELINE"
+	TrashKindTask     TrashKind = "TASK"
+)
+
+var AllTrashKind = []TrashKind{
+	TrashKindGoal,
+	TrashKindTimeline,
+	TrashKindTask,
+}
+
+func (e TrashKind) IsValid() bool {
+	switch e {
+	case TrashKindGoal, TrashKindTimeline, TrashKindTask:
+		return true
+	}
+	return false
+}
+
+func (e TrashKind) String() string {
+	return string(e)
+}
+
+func (e *TrashKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TrashKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TrashKind", str)
+	}
+	return nil
+}
+
+func (e TrashKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type UsageGrouping string
+
+const (
+	UsageGroupingUser  UsageGrouping = "USER"
+	UsageGroupingDay   UsageGrouping = "DAY"
+	UsageGroupingModel UsageGrouping = "MODEL"
+)
+
+var AllUsageGrouping = []UsageGrouping{
+	UsageGroupingUser,
+	UsageGroupingDay,
+	UsageGroupingModel,
+}
+
+func (e UsageGrouping) IsValid() bool {
+	switch e {
+	case UsageGroupingUser, UsageGroupingDay, UsageGroupingModel:
+		return true
+	}
+	return false
+}
+
+func (e UsageGrouping) String() string {
+	return string(e)
+}
+
+func (e *UsageGrouping) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = UsageGrouping(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid UsageGrouping", str)
+	}
+	return nil
+}
+
+func (e UsageGrouping) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type UserRole string
+
+const (
+	UserRoleUser  UserRole = "USER"
+	UserRoleAdmin UserRole = "ADMIN"
+)
+
+var AllUserRole = []UserRole{
+	UserRoleUser,
+	UserRoleAdmin,
+}
+
+func (e UserRole) IsValid() bool {
+	switch e {
+	case UserRoleUser, UserRoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e UserRole) String() string {
+	return string(e)
+}
+
+func (e *UserRole) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = UserRole(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid UserRole", str)
+	}
+	return nil
+}
+
+func (e UserRole) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending    WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryStatusDelivering WebhookDeliveryStatus = "DELIVERING"
+	WebhookDeliveryStatusSucceeded  WebhookDeliveryStatus = "SUCCEEDED"
+	WebhookDeliveryStatusFailed     WebhookDeliveryStatus = "FAILED"
+)
+
+var AllWebhookDeliveryStatus = []WebhookDeliveryStatus{
+	WebhookDeliveryStatusPending,
+	WebhookDeliveryStatusDelivering,
+	WebhookDeliveryStatusSucceeded,
+	WebhookDeliveryStatusFailed,
+}
+
+func (e WebhookDeliveryStatus) IsValid() bool {
+	switch e {
+	case WebhookDeliveryStatusPending, WebhookDeliveryStatusDelivering, WebhookDeliveryStatusSucceeded, WebhookDeliveryStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e WebhookDeliveryStatus) String() string {
+	return string(e)
+}
+
+func (e *WebhookDeliveryStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WebhookDeliveryStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WebhookDeliveryStatus", str)
+	}
+	return nil
+}
+
+func (e WebhookDeliveryStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type WebhookEventType string
+
+const (
+	WebhookEventTypeTimelineGenerated   WebhookEventType = "TIMELINE_GENERATED"
+	WebhookEventTypeTaskCompleted       WebhookEventType = "TASK_COMPLETED"
+	WebhookEventTypeTaskDeadlineMissed  WebhookEventType = "TASK_DEADLINE_MISSED"
+	WebhookEventTypeTaskReminder        WebhookEventType = "TASK_REMINDER"
+	WebhookEventTypeGoalInvitation      WebhookEventType = "GOAL_INVITATION"
+	WebhookEventTypeTimelineRescheduled WebhookEventType = "TIMELINE_RESCHEDULED"
+	WebhookEventTypeCommentMention      WebhookEventType = "COMMENT_MENTION"
+)
+
+var AllWebhookEventType = []WebhookEventType{
+	WebhookEventTypeTimelineGenerated,
+	WebhookEventTypeTaskCompleted,
+	WebhookEventTypeTaskDeadlineMissed,
+	WebhookEventTypeTaskReminder,
+	WebhookEventTypeGoalInvitation,
+	WebhookEventTypeTimelineRescheduled,
+	WebhookEventTypeCommentMention,
+}
+
+func (e WebhookEventType) IsValid() bool {
+	switch e {
+	case WebhookEventTypeTimelineGenerated, WebhookEventTypeTaskCompleted, WebhookEventTypeTaskDeadlineMissed, WebhookEventTypeTaskReminder, WebhookEventTypeGoalInvitation, WebhookEventTypeTimelineRescheduled, WebhookEventTypeCommentMention:
+		return true
+	}
+	return false
+}
+
+func (e WebhookEventType) String() string {
+	return string(e)
+}
+
+func (e *WebhookEventType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WebhookEventType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WebhookEventType", str)
+	}
+	return nil
+}
+
+func (e WebhookEventType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Weekday string
+
+const (
+	WeekdaySunday    Weekday = "SUNDAY"
+	WeekdayMonday    Weekday = "MONDAY"
+	WeekdayTuesday   Weekday = "TUESDAY"
+	WeekdayWednesday Weekday = "WEDNESDAY"
+	WeekdayThursday  Weekday = "THURSDAY"
+	WeekdayFriday    Weekday = "FRIDAY"
+	WeekdaySaturday  Weekday = "SATURDAY"
+)
+
+var AllWeekday = []Weekday{
+	WeekdaySunday,
+	WeekdayMonday,
+	WeekdayTuesday,
+	WeekdayWednesday,
+	WeekdayThursday,
+	WeekdayFriday,
+	WeekdaySaturday,
+}
+
+func (e Weekday) IsValid() bool {
+	switch e {
+	case WeekdaySunday, WeekdayMonday, WeekdayTuesday, WeekdayWednesday, WeekdayThursday, WeekdayFriday, WeekdaySaturday:
+		return true
+	}
+	return false
+}
+
+func (e Weekday) String() string {
+	return string(e)
+}
+
+func (e *Weekday) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Weekday(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Weekday", str)
+	}
+	return nil
+}
+
+func (e Weekday) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
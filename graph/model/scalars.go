@@ -0,0 +1,59 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// DateLayout is the format of the Date scalar
+const DateLayout = "2006-01-02"
+
+// MarshalDate writes a calendar date as YYYY-MM-DD.
+// Dates are stored without a time zone, so the date is written as is rather than converted to UTC.
+func MarshalDate(t time.Time) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(t.Format(DateLayout)))
+	})
+}
+
+// UnmarshalDate reads a YYYY-MM-DD calendar date as midnight UTC
+func UnmarshalDate(v interface{}) (time.Time, error) {
+	value, ok := v.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("Date must be a string in YYYY-MM-DD format, got %T", v)
+	}
+
+	date, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid Date %q: must be a valid calendar date in YYYY-MM-DD format", value)
+	}
+
+	return date, nil
+}
+
+// MarshalDateTime writes a timestamp in RFC 3339 format, normalized to UTC
+func MarshalDateTime(t time.Time) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(t.UTC().Format(time.RFC3339)))
+	})
+}
+
+// UnmarshalDateTime reads an RFC 3339 timestamp. A time zone offset is required so the instant is unambiguous;
+// the result is converted to UTC.
+func UnmarshalDateTime(v interface{}) (time.Time, error) {
+	value, ok := v.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("DateTime must be a string in RFC 3339 format, got %T", v)
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid DateTime %q: must be in RFC 3339 format with a time zone, such as 2024-01-02T15:04:05Z", value)
+	}
+
+	return t.UTC(), nil
+}
@@ -0,0 +1,143 @@
+package resolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jukemori/timeline-generator/internal/auth"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+	"github.com/jukemori/timeline-generator/internal/service"
+	"github.com/jukemori/timeline-generator/internal/templates"
+)
+
+// Error codes for authentication and authorization failures
+const (
+	codeUnauthenticated = "UNAUTHENTICATED"
+	codeForbidden       = "FORBIDDEN"
+)
+
+// currentUser loads the user making the request
+func currentUser(ctx context.Context) (*models.User, error) {
+	userID := auth.UserID(ctx)
+	if userID == "" {
+		return nil, newCodedError(ctx, codeUnauthenticated, "authentication required")
+	}
+
+	user, err := repository.NewUserRepository().GetByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, newCodedError(ctx, codeUnauthenticated, "unknown user")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// requireAdmin loads the user making the request and checks they are an admin
+func requireAdmin(ctx context.Context) (*models.User, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsAdmin() {
+		return nil, newCodedError(ctx, codeForbidden, "admin role required")
+	}
+	return user, nil
+}
+
+// loadGenerationJob loads a job owned by the current user, or any job in their organization for
+// admins. Other users' jobs are not found.
+func loadGenerationJob(ctx context.Context, user *models.User, id string) (*models.GenerationJob, error) {
+	job, err := repository.NewGenerationJobRepository().GetInOrganization(ctx, user.OrganizationID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if job.UserID != user.ID && !user.IsAdmin() {
+		return nil, nil
+	}
+
+	return job, nil
+}
+
+// loadWebhookSubscription loads a webhook subscription owned by the current user, or one of their
+// organization's if they are an admin. Other subscriptions are not found.
+func loadWebhookSubscription(ctx context.Context, user *models.User, id string) (*models.WebhookSubscription, error) {
+	subscription, err := repository.NewWebhookSubscriptionRepository().GetByID(ctx, user.OrganizationID, id)
+	if err == nil && subscription.OrganizationWide() {
+		if !user.IsAdmin() {
+			return nil, service.NotFound("webhook subscription", id)
+		}
+		return subscription, nil
+	}
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && subscription.UserID != user.ID) {
+		return nil, service.NotFound("webhook subscription", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// loadGoalInvitation loads an invitation sent to the current user or to a goal they own
+func loadGoalInvitation(ctx context.Context, user *models.User, id string) (*models.GoalInvitation, error) {
+	invitation, err := repository.NewGoalInvitationRepository().GetByID(ctx, user.OrganizationID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.NotFound("invitation", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(invitation.Email, user.Email) {
+		return invitation, nil
+	}
+	if err := service.CheckGoalAccess(ctx, user, invitation.GoalID, models.GoalRoleOwner); err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// loadComment loads a comment on a goal the current user has at least the required role on
+func loadComment(ctx context.Context, user *models.User, id, required string) (*models.Comment, error) {
+	comment, err := repository.NewCommentRepository().GetByID(ctx, user.OrganizationID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.NotFound("comment", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckGoalAccess(ctx, user, comment.GoalID, required); err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// loadGoalTemplate loads a curated template or one saved by the current user. Templates saved by
+// other users are not found.
+func loadGoalTemplate(ctx context.Context, user *models.User, id string) (*models.GoalTemplate, error) {
+	if template, ok := templates.Lookup(id); ok {
+		return template, nil
+	}
+
+	template, err := repository.NewGoalTemplateRepository().GetByID(ctx, user.OrganizationID, id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && template.UserID != user.ID) {
+		return nil, service.NotFound("goal template", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
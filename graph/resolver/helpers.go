@@ -1,31 +1,655 @@
 package resolver
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/jukemori/timeline-generator/graph/model"
+	"github.com/jukemori/timeline-generator/internal/calendar"
+	"github.com/jukemori/timeline-generator/internal/events"
 	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/ratelimit"
+	"github.com/jukemori/timeline-generator/internal/repository"
+	"github.com/jukemori/timeline-generator/internal/service"
+	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
+// Helper function to convert internal user to GraphQL model
+func convertUserToGraphQL(user *models.User) *model.User {
+	return &model.User{
+		ID:             user.ID,
+		OrganizationID: user.OrganizationID,
+		Email:          user.Email,
+		Role:           model.UserRole(strings.ToUpper(user.Role)),
+		TimeZone:       user.TimeZone,
+		CreatedAt:      user.CreatedAt,
+		UpdatedAt:      user.UpdatedAt,
+	}
+}
+
+// Helper function to convert internal organization model to GraphQL model
+func convertOrganizationToGraphQL(organization *models.Organization) *model.Organization {
+	return &model.Organization{
+		ID:   organization.ID,
+		Name: organization.Name,
+		Settings: &model.OrganizationSettings{
+			DefaultModel:   optionalString(organization.Settings.DefaultModel),
+			PromptTemplate: optionalString(organization.Settings.PromptTemplate),
+			MonthlyQuota:   organization.Settings.MonthlyQuota,
+		},
+		CreatedAt: organization.CreatedAt,
+		UpdatedAt: organization.UpdatedAt,
+	}
+}
+
 // Helper function to convert internal timeline model to GraphQL model
 func convertTimelineToGraphQL(timeline *models.Timeline) *model.Timeline {
 	tasks := make([]*model.TimelineTask, len(timeline.Tasks))
-	for i, task := range timeline.Tasks {
-		tasks[i] = &model.TimelineTask{
-			ID:          task.ID,
-			Title:       task.Title,
-			Description: task.Description,
-			StartDate:   task.StartDate.Format("2006-01-02"),
-			EndDate:     task.EndDate.Format("2006-01-02"),
-			Duration:    task.Duration,
-			Priority:    task.Priority,
-		}
+	for i := range timeline.Tasks {
+		tasks[i] = convertTaskToGraphQL(&timeline.Tasks[i])
 	}
 
 	return &model.Timeline{
 		ID:          timeline.ID,
+		GoalID:      timeline.GoalID,
 		Title:       timeline.Title,
 		Description: timeline.Description,
-		StartDate:   timeline.StartDate.Format("2006-01-02"),
-		EndDate:     timeline.EndDate.Format("2006-01-02"),
+		StartDate:   timeline.StartDate,
+		EndDate:     timeline.EndDate,
 		Tasks:       tasks,
+		CreatedAt:   timeline.CreatedAt,
+		UpdatedAt:   timeline.UpdatedAt,
+	}
+}
+
+// Helper function to convert an internal duration to GraphQL model
+func convertDurationToGraphQL(duration models.Duration) *model.Duration {
+	return &model.Duration{
+		Label: duration.Label,
+		Value: duration.Value,
+		Unit:  model.DurationUnit(strings.ToUpper(duration.Unit)),
+		Days:  duration.Days(),
+		Hours: duration.Hours(),
+	}
+}
+
+// Helper function to convert GraphQL timeline input to the internal model.
+// The Date scalar has already checked the dates are well formed; this checks they are in order.
+func convertTimelineInput(input model.TimelineInput) (models.TimelineInput, error) {
+	result := models.TimelineInput{
+		CurrentLevel: input.CurrentLevel,
+		Goal:         input.Goal,
+		Objectives:   input.Objectives,
+		CurrentDate:  input.CurrentDate.Format(model.DateLayout),
+	}
+	if input.TargetDate != nil {
+		if !input.TargetDate.After(input.CurrentDate) {
+			return result, fmt.Errorf("invalid targetDate: %s must be after currentDate %s",
+				input.TargetDate.Format(model.DateLayout), result.CurrentDate)
+		}
+		result.TargetDate = input.TargetDate.Format(model.DateLayout)
+	}
+	return result, nil
+}
+
+// Helper function to convert internal generation usage to GraphQL model
+func convertGenerationUsageToGraphQL(usage *models.GenerationUsage) *model.GenerationUsage {
+	return &model.GenerationUsage{
+		UserID:       usage.UserID,
+		Period:       usage.Period,
+		Generations:  usage.Generations,
+		MonthlyQuota: usage.MonthlyQuota,
+		Remaining:    usage.Remaining(),
+	}
+}
+
+// Error codes for requests that ran out of time or were abandoned by the client
+const (
+	codeTimeout   = "TIMEOUT"
+	codeCancelled = "CANCELLED"
+)
+
+// PresentError is the server's error presenter. Errors services report for requests they refuse carry
+// their code. Errors caused by a query timing out or the request being cancelled are reported with a
+// code saying so instead of the underlying database or API error.
+func PresentError(ctx context.Context, err error) *gqlerror.Error {
+	var serviceErr *service.Error
+	switch {
+	case errors.As(err, &serviceErr):
+		return newCodedError(ctx, serviceErr.Code, serviceErr.Message)
+	case errors.Is(err, context.DeadlineExceeded):
+		return newCodedError(ctx, codeTimeout, "the request timed out")
+	case errors.Is(err, context.Canceled):
+		return newCodedError(ctx, codeCancelled, "the request was cancelled")
+	}
+	return graphql.DefaultErrorPresenter(ctx, err)
+}
+
+// maxPageLimit bounds how many items a list query returns at once
+const maxPageLimit = 100
+
+// pageLimit returns how many items a list query asked for, or defaultLimit when it did not say,
+// clamped to between 1 and maxPageLimit
+func pageLimit(limit *int, defaultLimit int) int {
+	if limit == nil {
+		return defaultLimit
+	}
+	return min(max(*limit, 1), maxPageLimit)
+}
+
+// newCodedError creates a GraphQL error carrying a machine-readable code
+func newCodedError(ctx context.Context, code, message string) *gqlerror.Error {
+	return &gqlerror.Error{
+		Message:    message,
+		Path:       graphql.GetPath(ctx),
+		Extensions: map[string]interface{}{"code": code},
+	}
+}
+
+// convertLimitError exposes rate limit and quota errors with their code and retry-after seconds
+func convertLimitError(ctx context.Context, err error) error {
+	var limitErr *ratelimit.Error
+	if !errors.As(err, &limitErr) {
+		return err
+	}
+
+	gqlErr := newCodedError(ctx, limitErr.Code, limitErr.Message)
+	gqlErr.Extensions["retryAfter"] = int(math.Ceil(limitErr.RetryAfter.Seconds()))
+	return gqlErr
+}
+
+// Helper function to convert internal LLM usage summary to GraphQL model
+func convertLLMUsageSummaryToGraphQL(summary *models.LLMUsageSummary) *model.LLMUsageSummary {
+	return &model.LLMUsageSummary{
+		Key:              summary.Key,
+		Calls:            summary.Calls,
+		Failures:         summary.Failures,
+		PromptTokens:     summary.PromptTokens,
+		CompletionTokens: summary.CompletionTokens,
+		TotalTokens:      summary.PromptTokens + summary.CompletionTokens,
+		EstimatedCost:    summary.EstimatedCost,
+		AverageLatencyMs: summary.AverageLatencyMs,
+	}
+}
+
+// Helper function to convert internal LLM usage record to GraphQL model
+func convertLLMCallToGraphQL(usage *models.LLMUsage) *model.LLMCall {
+	return &model.LLMCall{
+		ID:               usage.ID,
+		UserID:           optionalString(usage.UserID),
+		GoalID:           optionalString(usage.GoalID),
+		TimelineID:       optionalString(usage.TimelineID),
+		Operation:        usage.Operation,
+		Model:            usage.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens(),
+		LatencyMs:        int(usage.LatencyMs),
+		EstimatedCost:    usage.EstimatedCost,
+		Success:          usage.Success,
+		CreatedAt:        usage.CreatedAt,
+	}
+}
+
+// usageFilterFor converts a GraphQL usage filter, limiting admins to their organization's usage and
+// everyone else to their own. The "to" date is inclusive.
+func usageFilterFor(ctx context.Context, user *models.User, filter *model.UsageFilter) (repository.UsageFilter, error) {
+	result := repository.UsageFilter{OrganizationID: user.OrganizationID}
+	if filter == nil {
+		filter = &model.UsageFilter{}
+	}
+
+	if filter.UserID != nil {
+		result.UserID = *filter.UserID
+	}
+	if !user.IsAdmin() {
+		if result.UserID != "" && result.UserID != user.ID {
+			return result, newCodedError(ctx, codeForbidden, "admin role required")
+		}
+		result.UserID = user.ID
+	}
+
+	result.From = filter.From
+	if filter.To != nil {
+		to := filter.To.AddDate(0, 0, 1)
+		result.To = &to
+	}
+	if result.From != nil && result.To != nil && !result.From.Before(*result.To) {
+		return result, fmt.Errorf("invalid date range: from must not be after to")
+	}
+
+	return result, nil
+}
+
+// optionalString returns nil for empty strings
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// Helper function to convert internal generation job to GraphQL model
+func convertGenerationJobToGraphQL(job *models.GenerationJob) *model.GenerationJob {
+	return &model.GenerationJob{
+		ID:          job.ID,
+		Status:      model.GenerationJobStatus(strings.ToUpper(job.Status)),
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		LastError:   optionalString(job.LastError),
+		TimelineID:  job.TimelineID,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+		StartedAt:   job.StartedAt,
+		FinishedAt:  job.FinishedAt,
+	}
+}
+
+// Helper function to convert an internal task to GraphQL model
+func convertTaskToGraphQL(task *models.TimelineTask) *model.TimelineTask {
+	var effortHours *float64
+	if task.EffortHours > 0 {
+		effortHours = &task.EffortHours
+	}
+	var recurrence *string
+	if task.IsRecurring() {
+		recurrence = &task.Recurrence
+	}
+
+	return &model.TimelineTask{
+		ID:               task.ID,
+		Title:            task.Title,
+		Description:      task.Description,
+		StartDate:        task.StartDate,
+		EndDate:          task.EndDate,
+		Duration:         task.Duration.Label,
+		DurationEstimate: convertDurationToGraphQL(task.Duration),
+		EffortHours:      effortHours,
+		Recurrence:       recurrence,
+		Priority:         task.Priority,
+		Completed:        task.Completed,
+		CreatedAt:        task.CreatedAt,
+		UpdatedAt:        task.UpdatedAt,
+	}
+}
+
+// convertTaskFromGraphQL recovers the fields of an internal task needed to work out its occurrences
+func convertTaskFromGraphQL(task *model.TimelineTask) *models.TimelineTask {
+	result := &models.TimelineTask{
+		ID:        task.ID,
+		StartDate: task.StartDate,
+		EndDate:   task.EndDate,
+		Completed: task.Completed,
+	}
+	if task.Recurrence != nil {
+		result.Recurrence = *task.Recurrence
+	}
+	return result
+}
+
+// webhookEventTypes maps GraphQL webhook event types to internal event types
+var webhookEventTypes = map[model.WebhookEventType]string{
+	model.WebhookEventTypeTimelineGenerated:  events.TimelineGenerated,
+	model.WebhookEventTypeTaskCompleted:      events.TaskCompleted,
+	model.WebhookEventTypeTaskDeadlineMissed: events.TaskDeadlineMissed,
+	model.WebhookEventTypeTaskReminder:       events.TaskReminder,
+	model.WebhookEventTypeGoalInvitation:     events.GoalInvitation,
+	model.WebhookEventTypeTimelineRescheduled: events.TimelineRescheduled,
+	model.WebhookEventTypeCommentMention:     events.CommentMention,
+}
+
+// convertWebhookEventTypeToGraphQL maps an internal event type to its GraphQL enum value
+func convertWebhookEventTypeToGraphQL(eventType string) model.WebhookEventType {
+	for graphQLType, internalType := range webhookEventTypes {
+		if internalType == eventType {
+			return graphQLType
+		}
+	}
+	return model.WebhookEventType(strings.ToUpper(eventType))
+}
+
+// Helper function to convert internal webhook subscription to GraphQL model
+func convertWebhookSubscriptionToGraphQL(subscription *models.WebhookSubscription) *model.WebhookSubscription {
+	eventTypes := make([]model.WebhookEventType, len(subscription.EventTypes))
+	for i, eventType := range subscription.EventTypes {
+		eventTypes[i] = convertWebhookEventTypeToGraphQL(eventType)
+	}
+
+	return &model.WebhookSubscription{
+		ID:               subscription.ID,
+		OrganizationWide: subscription.OrganizationWide(),
+		URL:              subscription.URL,
+		EventTypes:       eventTypes,
+		Active:           subscription.Active,
+		CreatedAt:        subscription.CreatedAt,
+		UpdatedAt:        subscription.UpdatedAt,
+	}
+}
+
+// Helper function to convert internal webhook delivery to GraphQL model
+func convertWebhookDeliveryToGraphQL(delivery *models.WebhookDelivery) *model.WebhookDelivery {
+	result := &model.WebhookDelivery{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      convertWebhookEventTypeToGraphQL(delivery.EventType),
+		Payload:        delivery.Payload,
+		Status:         model.WebhookDeliveryStatus(strings.ToUpper(delivery.Status)),
+		Attempts:       delivery.Attempts,
+		LastError:      optionalString(delivery.LastError),
+		NextAttemptAt:  delivery.NextAttemptAt,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+	}
+	if delivery.ResponseStatus != 0 {
+		responseStatus := delivery.ResponseStatus
+		result.ResponseStatus = &responseStatus
+	}
+	return result
+}
+
+// Helper function to convert internal reminder settings to GraphQL model
+func convertReminderSettingsToGraphQL(settings *models.ReminderSettings) *model.ReminderSettings {
+	return &model.ReminderSettings{
+		Enabled:       settings.Enabled,
+		StartLeadDays: settings.StartLeadDays,
+		DueLeadDays:   settings.DueLeadDays,
+		Overdue:       settings.Overdue,
+		CreatedAt:     settings.CreatedAt,
+		UpdatedAt:     settings.UpdatedAt,
+	}
+}
+
+// Helper function to convert internal notification preferences to GraphQL model
+func convertNotificationPreferencesToGraphQL(preferences *models.NotificationPreferences) *model.NotificationPreferences {
+	return &model.NotificationPreferences{
+		Email:          preferences.Email,
+		EmailReminders: preferences.EmailReminders,
+		WeeklyDigest:   preferences.WeeklyDigest,
+		DigestDay:      preferences.DigestDay,
+		LastDigestAt:   preferences.LastDigestAt,
+		CreatedAt:      preferences.CreatedAt,
+		UpdatedAt:      preferences.UpdatedAt,
+	}
+}
+
+// Helper function to convert internal task reminder to GraphQL model
+func convertTaskReminderToGraphQL(reminder *models.TaskReminder) *model.TaskReminder {
+	return &model.TaskReminder{
+		TaskID:   reminder.TaskID,
+		Kind:     model.ReminderKind(strings.ToUpper(reminder.Kind)),
+		LeadDays: reminder.LeadDays,
+		DueOn:    reminder.DueOn,
+		Message:  reminder.Message,
+		SentAt:   reminder.SentAt,
+	}
+}
+
+// Helper function to convert internal availability to GraphQL model
+func convertAvailabilityToGraphQL(availability *models.Availability) *model.Availability {
+	workingDays := make([]model.Weekday, len(availability.WorkingDays))
+	for i, weekday := range availability.WorkingDays {
+		workingDays[i] = model.Weekday(strings.ToUpper(weekday.String()))
+	}
+
+	result := &model.Availability{
+		WorkingDays:    workingDays,
+		HoursPerDay:    availability.HoursPerDay,
+		WeeklyCapacity: availability.Capacity(),
+		BlackoutDates:  availability.BlackoutDates,
+		CreatedAt:      availability.CreatedAt,
+		UpdatedAt:      availability.UpdatedAt,
+	}
+	if set, ok := calendar.LookupHolidaySet(availability.HolidaySet); ok {
+		result.HolidaySet = convertHolidaySetToGraphQL(set)
+	}
+
+	return result
+}
+
+// Helper function to convert a bundled holiday set to GraphQL model
+func convertHolidaySetToGraphQL(set *calendar.HolidaySet) *model.HolidaySet {
+	return &model.HolidaySet{
+		Code: set.Code,
+		Name: set.Name,
+	}
+}
+
+// workloadWeeks is how many weeks ahead the workload covers by default
+const workloadWeeks = 12
+
+// Helper function to convert a user's workload to GraphQL model
+func convertWorkloadToGraphQL(workload *service.Workload) *model.Workload {
+	weeks := make([]*model.WorkloadWeek, len(workload.Weeks))
+	for i, week := range workload.Weeks {
+		timelines := make([]*model.TimelineLoad, len(week.Timelines))
+		for j, load := range week.Timelines {
+			timelines[j] = &model.TimelineLoad{
+				TimelineID: load.TimelineID,
+				Title:      load.Title,
+				Hours:      math.Round(load.Hours*100) / 100,
+			}
+		}
+		weeks[i] = &model.WorkloadWeek{
+			WeekStart:    week.WeekStart,
+			PlannedHours: math.Round(week.PlannedHours*100) / 100,
+			Capacity:     workload.WeeklyCapacity,
+			OverCapacity: week.PlannedHours > workload.WeeklyCapacity+1e-9,
+			Timelines:    timelines,
+		}
+	}
+
+	conflicts := make([]*model.ScheduleConflict, len(workload.Conflicts))
+	for i, conflict := range workload.Conflicts {
+		conflicts[i] = &model.ScheduleConflict{
+			GoalID:           conflict.Goal.ID,
+			Title:            conflict.Goal.Title,
+			TargetDate:       conflict.Goal.TargetDate,
+			ProjectedEndDate: conflict.ProjectedEndDate,
+		}
+	}
+
+	return &model.Workload{
+		WeeklyCapacity: workload.WeeklyCapacity,
+		Weeks:          weeks,
+		Conflicts:      conflicts,
+	}
+}
+
+// Helper function to convert a goal member to GraphQL model
+func convertGoalMemberToGraphQL(member *models.GoalMember) *model.GoalMember {
+	return &model.GoalMember{
+		UserID:    member.UserID,
+		Email:     member.Email,
+		Role:      model.GoalRole(strings.ToUpper(member.Role)),
+		CreatedAt: member.CreatedAt,
+	}
+}
+
+// Helper function to convert a goal invitation to GraphQL model
+func convertGoalInvitationToGraphQL(invitation *models.GoalInvitation) *model.GoalInvitation {
+	return &model.GoalInvitation{
+		ID:        invitation.ID,
+		GoalID:    invitation.GoalID,
+		GoalTitle: invitation.GoalTitle,
+		Email:     invitation.Email,
+		Role:      model.GoalRole(strings.ToUpper(invitation.Role)),
+		InvitedBy: invitation.InvitedBy,
+		CreatedAt: invitation.CreatedAt,
+	}
+}
+
+// convertGoalTimelinesToGraphQL loads and converts the timelines of each goal
+func convertGoalTimelinesToGraphQL(ctx context.Context, goals []*models.Goal) ([]*model.Timeline, error) {
+	timelineRepo := repository.NewTimelineRepository()
+
+	result := []*model.Timeline{}
+	for _, goal := range goals {
+		timelines, err := timelineRepo.GetByGoalID(ctx, goal.OrganizationID, goal.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, timeline := range timelines {
+			result = append(result, convertTimelineToGraphQL(timeline))
+		}
+	}
+
+	return result, nil
+}
+
+// Helper function to convert a goal template to GraphQL model
+func convertGoalTemplateToGraphQL(template *models.GoalTemplate) *model.GoalTemplate {
+	tasks := make([]*model.TemplateTask, len(template.Tasks))
+	for i, task := range template.Tasks {
+		var recurrence *string
+		if task.Recurrence != "" {
+			recurrence = &template.Tasks[i].Recurrence
+		}
+
+		tasks[i] = &model.TemplateTask{
+			Title:            task.Title,
+			Description:      task.Description,
+			StartOffset:      task.StartOffset,
+			SpanDays:         task.SpanDays,
+			Duration:         task.Duration.Label,
+			DurationEstimate: convertDurationToGraphQL(task.Duration),
+			EffortHours:      task.EffortHours,
+			Recurrence:       recurrence,
+			Priority:         task.Priority,
+		}
+	}
+
+	return &model.GoalTemplate{
+		ID:           template.ID,
+		Title:        template.Title,
+		Description:  template.Description,
+		Goal:         template.Goal,
+		CurrentLevel: template.CurrentLevel,
+		TargetLevel:  template.TargetLevel,
+		Curated:      template.IsCurated(),
+		SpanDays:     template.SpanDays,
+		Tasks:        tasks,
+		CreatedAt:    template.CreatedAt,
+		UpdatedAt:    template.UpdatedAt,
+	}
+}
+
+// Helper function to convert a comment and its replies to GraphQL model
+func convertCommentToGraphQL(comment *models.Comment) *model.Comment {
+	replies := make([]*model.Comment, len(comment.Replies))
+	for i, reply := range comment.Replies {
+		replies[i] = convertCommentToGraphQL(reply)
+	}
+
+	return &model.Comment{
+		ID:          comment.ID,
+		GoalID:      comment.GoalID,
+		TaskID:      optionalString(comment.TaskID),
+		ParentID:    optionalString(comment.ParentID),
+		AuthorID:    comment.UserID,
+		AuthorEmail: comment.AuthorEmail,
+		Body:        comment.Body,
+		Mentions:    comment.Mentions,
+		Replies:     replies,
+		EditedAt:    comment.EditedAt,
+		DeletedAt:   comment.DeletedAt,
+		CreatedAt:   comment.CreatedAt,
+		UpdatedAt:   comment.UpdatedAt,
+	}
+}
+
+// Helper function to convert a timeline activity item to GraphQL model
+func convertActivityItemToGraphQL(item *models.ActivityItem) *model.ActivityItem {
+	result := &model.ActivityItem{
+		ID:         item.ID,
+		TimelineID: item.TimelineID,
+		TaskID:     optionalString(item.TaskID),
+		ActorID:    optionalString(item.UserID),
+		Kind:       model.ActivityKind(strings.ToUpper(item.Kind)),
+		OccurredAt: item.OccurredAt,
+	}
+	if item.Comment != nil {
+		result.Comment = convertCommentToGraphQL(item.Comment)
+	}
+	return result
+}
+
+// Helper function to convert internal trash item to GraphQL model
+func convertTrashItemToGraphQL(item *models.TrashItem, purgeAt time.Time) *model.TrashItem {
+	return &model.TrashItem{
+		Kind:       model.TrashKind(strings.ToUpper(item.Kind)),
+		ID:         item.ID,
+		Title:      item.Title,
+		GoalID:     item.GoalID,
+		TimelineID: optionalString(item.TimelineID),
+		DeletedAt:  item.DeletedAt,
+		PurgeAt:    purgeAt,
+	}
+}
+
+// Helper function to convert internal audit entry to GraphQL model
+func convertAuditEntryToGraphQL(entry *models.AuditEntry) *model.AuditEntry {
+	return &model.AuditEntry{
+		ID:         entry.ID,
+		ActorID:    optionalString(entry.ActorID),
+		Operation:  entry.Operation,
+		Action:     model.AuditAction(strings.ToUpper(entry.Action)),
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		Before:     optionalString(string(entry.Before)),
+		After:      optionalString(string(entry.After)),
+		RequestID:  optionalString(entry.RequestID),
+		IPAddress:  optionalString(entry.IPAddress),
+		UserAgent:  optionalString(entry.UserAgent),
+		CreatedAt:  entry.CreatedAt,
+	}
+}
+
+// auditFilterFor converts a GraphQL audit filter, limited to the admin's organization
+func auditFilterFor(admin *models.User, filter *model.AuditFilter) (repository.AuditFilter, error) {
+	result := repository.AuditFilter{OrganizationID: admin.OrganizationID}
+	if filter == nil {
+		return result, nil
+	}
+
+	for _, field := range []struct {
+		value  *string
+		target *string
+	}{
+		{filter.ActorID, &result.ActorID},
+		{filter.EntityType, &result.EntityType},
+		{filter.EntityID, &result.EntityID},
+		{filter.Operation, &result.Operation},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+	if filter.Action != nil {
+		result.Action = strings.ToLower(string(*filter.Action))
+	}
+
+	result.From = filter.From
+	result.To = filter.To
+	if result.From != nil && result.To != nil && !result.From.Before(*result.To) {
+		return result, fmt.Errorf("invalid time range: from must be before to")
+	}
+
+	return result, nil
+}
+
+// convertWeekday maps a GraphQL weekday to time.Weekday
+func convertWeekday(weekday model.Weekday) time.Weekday {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.ToUpper(day.String()) == string(weekday) {
+			return day
+		}
 	}
-}
\ No newline at end of file
+	return -1
+}
@@ -1,11 +1,35 @@
 package resolver
 
-import "github.com/jukemori/timeline-generator/internal/openai"
+import (
+	"github.com/jukemori/timeline-generator/internal/activity"
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/jobs"
+	"github.com/jukemori/timeline-generator/internal/notify"
+	"github.com/jukemori/timeline-generator/internal/openai"
+	"github.com/jukemori/timeline-generator/internal/reminder"
+	"github.com/jukemori/timeline-generator/internal/service"
+	"github.com/jukemori/timeline-generator/internal/webhook"
+)
 
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
-type Resolver struct{
-	OpenAIClient *openai.Client
-}
\ No newline at end of file
+type Resolver struct {
+	OpenAIClient        *openai.Client
+	TimelineGenerator   *service.TimelineGenerator
+	GenerationLimiter   *service.GenerationLimiter
+	GenerationQueue     *jobs.Queue
+	TaskService         *service.TaskService
+	WebhookDispatcher   *webhook.Dispatcher
+	ReminderScheduler   *reminder.Scheduler
+	Notifier            *notify.Notifier
+	TimelineScheduler   *service.TimelineScheduler
+	TemplateService     *service.TemplateService
+	SharingService      *service.SharingService
+	OrganizationService *service.OrganizationService
+	CommentService      *service.CommentService
+	TrashService        *service.TrashService
+	ActivityHub         *activity.Hub
+	Clock               clock.Clock
+}
@@ -0,0 +1,148 @@
+package resolver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/jukemori/timeline-generator/graph/generated"
+	"github.com/jukemori/timeline-generator/internal/auth"
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/database/databasetest"
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/service"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// withUser makes a test client request as the user
+func withUser(userID string) client.Option {
+	return func(r *client.Request) {
+		r.HTTP = r.HTTP.WithContext(auth.WithUserID(r.HTTP.Context(), userID))
+	}
+}
+
+// newClient serves the schema with the resolver over a client that talks to it directly
+func newClient(r *Resolver) *client.Client {
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{Resolvers: r}))
+	srv.AddTransport(transport.POST{})
+	return client.New(srv)
+}
+
+func TestTimelineRecurringTask(t *testing.T) {
+	db := databasetest.Open(t)
+	created := date(2024, time.January, 1)
+
+	db.Rows("FROM task_occurrences", []string{"occurs_on", "completed_at"},
+		[]driver.Value{date(2024, time.January, 1), date(2024, time.January, 1).Add(9 * time.Hour)},
+		[]driver.Value{date(2024, time.January, 2), date(2024, time.January, 2).Add(9 * time.Hour)},
+	)
+	db.Rows("FROM timeline_tasks WHERE timeline_id = ?", []string{
+		"id", "timeline_id", "title", "description", "start_date", "end_date", "duration", "duration_value",
+		"duration_unit", "effort_hours", "recurrence", "priority", "completed", "created_at", "updated_at",
+	},
+		[]driver.Value{"task-1", "timeline-1", "Practice", "", date(2024, time.January, 1), date(2024, time.January, 5),
+			"5 days", 5.0, "days", 0.5, "FREQ=DAILY", int64(1), false, created, created},
+	)
+	db.Rows("tl.id, tl.goal_id, tl.title", []string{
+		"id", "goal_id", "title", "description", "start_date", "end_date", "created_at", "updated_at",
+	},
+		[]driver.Value{"timeline-1", "goal-1", "Plan", "", date(2024, time.January, 1), date(2024, time.January, 5), created, created},
+	)
+	db.Rows("END FROM timelines tl", []string{"role"}, []driver.Value{"owner"})
+	db.Rows("FROM users WHERE id = ?", []string{
+		"id", "organization_id", "email", "role", "time_zone", "created_at", "updated_at",
+	},
+		[]driver.Value{"user-1", "org-1", "user@example.com", "member", "UTC", created, created},
+	)
+
+	c := newClient(&Resolver{
+		TaskService: service.NewTaskService(events.NewBus()),
+		Clock:       clock.Fixed(date(2024, time.January, 3).Add(12 * time.Hour)),
+	})
+
+	var resp struct {
+		Timeline struct {
+			Tasks []struct {
+				Recurrence  *string
+				EffortHours *float64
+				Occurrences []struct {
+					Date      string
+					Completed bool
+				}
+				Streak *struct {
+					CompletedOccurrences int
+					DueOccurrences       int
+					TotalOccurrences     int
+				}
+			}
+		}
+	}
+	err := c.Post(`query { timeline(id: "timeline-1") { tasks {
+		recurrence effortHours occurrences { date completed } streak { completedOccurrences dueOccurrences totalOccurrences }
+	} } }`, &resp, withUser("user-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Timeline.Tasks) != 1 {
+		t.Fatalf("timeline has %d tasks, want 1", len(resp.Timeline.Tasks))
+	}
+	task := resp.Timeline.Tasks[0]
+	if task.Recurrence == nil || *task.Recurrence != "FREQ=DAILY" {
+		t.Errorf("recurrence = %v, want FREQ=DAILY", task.Recurrence)
+	}
+	if task.EffortHours == nil || *task.EffortHours != 0.5 {
+		t.Errorf("effortHours = %v, want 0.5", task.EffortHours)
+	}
+
+	wantCompleted := []bool{true, true, false, false, false}
+	if len(task.Occurrences) != len(wantCompleted) {
+		t.Fatalf("occurrences = %+v, want one a day from 2024-01-01 to 2024-01-05", task.Occurrences)
+	}
+	for i, occurrence := range task.Occurrences {
+		if want := date(2024, time.January, 1+i).Format("2006-01-02"); occurrence.Date != want || occurrence.Completed != wantCompleted[i] {
+			t.Errorf("occurrence %d = %+v, want %s completed %v", i, occurrence, want, wantCompleted[i])
+		}
+	}
+
+	if task.Streak == nil {
+		t.Fatal("streak = null, want the task's streak")
+	}
+	if task.Streak.CompletedOccurrences != 2 || task.Streak.TotalOccurrences != 5 {
+		t.Errorf("streak = %+v, want 2 of 5 occurrences completed", *task.Streak)
+	}
+}
+
+func TestLoadersNotFound(t *testing.T) {
+	databasetest.Open(t).Rows("SELECT", nil)
+	user := &models.User{ID: "user-1", OrganizationID: "org-1"}
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		load func() error
+	}{
+		{"webhook subscription", func() error { _, err := loadWebhookSubscription(ctx, user, "missing"); return err }},
+		{"goal invitation", func() error { _, err := loadGoalInvitation(ctx, user, "missing"); return err }},
+		{"comment", func() error { _, err := loadComment(ctx, user, "missing", models.GoalRoleViewer); return err }},
+		{"goal template", func() error { _, err := loadGoalTemplate(ctx, user, "missing"); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var serviceErr *service.Error
+			if err := tt.load(); !errors.As(err, &serviceErr) || serviceErr.Code != service.CodeNotFound {
+				t.Errorf("error = %v, want a %s service error", err, service.CodeNotFound)
+			}
+		})
+	}
+}
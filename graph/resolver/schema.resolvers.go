@@ -6,77 +6,1684 @@ package resolver
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/jukemori/timeline-generator/graph/generated"
 	"github.com/jukemori/timeline-generator/graph/model"
+	"github.com/jukemori/timeline-generator/internal/auth"
+	"github.com/jukemori/timeline-generator/internal/calendar"
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/reminder"
 	"github.com/jukemori/timeline-generator/internal/repository"
+	"github.com/jukemori/timeline-generator/internal/service"
+	"github.com/jukemori/timeline-generator/internal/webhook"
 )
 
+// Timeline is the resolver for the timeline field.
+func (r *generationJobResolver) Timeline(ctx context.Context, obj *model.GenerationJob) (*model.Timeline, error) {
+	if obj.TimelineID == "" {
+		return nil, nil
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline, err := repository.NewTimelineRepository().GetVisible(ctx, user.OrganizationID, obj.TimelineID, user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTimelineToGraphQL(timeline), nil
+}
+
+// Holidays is the resolver for the holidays field.
+func (r *holidaySetResolver) Holidays(ctx context.Context, obj *model.HolidaySet, from *time.Time, to *time.Time) ([]*model.Holiday, error) {
+	set, ok := calendar.LookupHolidaySet(obj.Code)
+	if !ok {
+		return nil, fmt.Errorf("unknown holiday set %q", obj.Code)
+	}
+
+	holidays := []*model.Holiday{}
+	for _, holiday := range set.Holidays {
+		if from != nil && holiday.Date.Before(*from) || to != nil && holiday.Date.After(*to) {
+			continue
+		}
+		holidays = append(holidays, &model.Holiday{Date: holiday.Date, Name: holiday.Name})
+	}
+
+	return holidays, nil
+}
+
+// UpdateTimeZone is the resolver for the updateTimeZone field.
+func (r *mutationResolver) UpdateTimeZone(ctx context.Context, timeZone string) (*model.User, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := clock.LoadLocation(timeZone); err != nil {
+		return nil, err
+	}
+
+	if err := repository.NewUserRepository().UpdateTimeZone(ctx, user.ID, timeZone); err != nil {
+		return nil, err
+	}
+	user.TimeZone = timeZone
+	user.UpdatedAt = r.Clock.Now()
+
+	return convertUserToGraphQL(user), nil
+}
+
 // GenerateTimeline is the resolver for the generateTimeline field.
 func (r *mutationResolver) GenerateTimeline(ctx context.Context, input model.TimelineInput) (*model.Timeline, error) {
-	return r.OpenAIClient.GenerateTimeline(ctx, input)
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	timelineInput, err := convertTimelineInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.GenerationLimiter.Acquire(ctx, user.ID, auth.ClientIP(ctx)); err != nil {
+		return nil, convertLimitError(ctx, err)
+	}
+
+	timeline, err := r.TimelineGenerator.GenerateTimeline(ctx, user.ID, timelineInput)
+	if err != nil {
+		if releaseErr := r.GenerationLimiter.Release(ctx, user.ID); releaseErr != nil {
+			log.Printf("failed to release generation usage for user %s: %v", user.ID, releaseErr)
+		}
+		return nil, err
+	}
+
+	return convertTimelineToGraphQL(timeline), nil
+}
+
+// GenerateTimelineAsync is the resolver for the generateTimelineAsync field.
+func (r *mutationResolver) GenerateTimelineAsync(ctx context.Context, input model.TimelineInput) (*model.GenerationJob, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	timelineInput, err := convertTimelineInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.GenerationLimiter.Acquire(ctx, user.ID, auth.ClientIP(ctx)); err != nil {
+		return nil, convertLimitError(ctx, err)
+	}
+
+	job, err := r.GenerationQueue.Enqueue(ctx, user.ID, timelineInput)
+	if err != nil {
+		if releaseErr := r.GenerationLimiter.Release(ctx, user.ID); releaseErr != nil {
+			log.Printf("failed to release generation usage for user %s: %v", user.ID, releaseErr)
+		}
+		return nil, err
+	}
+
+	return convertGenerationJobToGraphQL(job), nil
+}
+
+// CancelGenerationJob is the resolver for the cancelGenerationJob field.
+func (r *mutationResolver) CancelGenerationJob(ctx context.Context, id string) (*model.GenerationJob, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := loadGenerationJob(ctx, user, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, fmt.Errorf("generation job %s not found", id)
+	}
+
+	job, err = r.GenerationQueue.Cancel(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertGenerationJobToGraphQL(job), nil
+}
+
+// UpdateTaskCompletion is the resolver for the updateTaskCompletion field.
+func (r *mutationResolver) UpdateTaskCompletion(ctx context.Context, id string, completed bool) (*model.TimelineTask, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckTaskAccess(ctx, user, id, models.GoalRoleEditor); err != nil {
+		return nil, err
+	}
+
+	task, err := r.TaskService.SetCompleted(ctx, user, id, completed)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTaskToGraphQL(task), nil
+}
+
+// UpdateOccurrenceCompletion is the resolver for the updateOccurrenceCompletion field.
+func (r *mutationResolver) UpdateOccurrenceCompletion(ctx context.Context, taskID string, date time.Time, completed bool) (*model.TimelineTask, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckTaskAccess(ctx, user, taskID, models.GoalRoleEditor); err != nil {
+		return nil, err
+	}
+
+	task, err := r.TaskService.SetOccurrenceCompleted(ctx, user, taskID, date, completed)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTaskToGraphQL(task), nil
+}
+
+// SetTaskRecurrence is the resolver for the setTaskRecurrence field.
+func (r *mutationResolver) SetTaskRecurrence(ctx context.Context, id string, recurrence *string) (*model.TimelineTask, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckTaskAccess(ctx, user, id, models.GoalRoleEditor); err != nil {
+		return nil, err
+	}
+
+	rule := ""
+	if recurrence != nil {
+		rule = *recurrence
+	}
+
+	task, err := r.TaskService.SetRecurrence(ctx, user, id, rule)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTaskToGraphQL(task), nil
+}
+
+// CreateWebhookSubscription is the resolver for the createWebhookSubscription field.
+func (r *mutationResolver) CreateWebhookSubscription(ctx context.Context, input model.WebhookSubscriptionInput) (*model.CreatedWebhookSubscription, error) {
+	// Only admins subscribe to the events of the whole organization
+	organizationWide := input.OrganizationWide != nil && *input.OrganizationWide
+	var user *models.User
+	var err error
+	if organizationWide {
+		user, err = requireAdmin(ctx)
+	} else {
+		user, err = currentUser(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := r.WebhookDispatcher.ValidateURL(ctx, input.URL)
+	if err != nil {
+		return nil, err
+	}
+	if len(input.EventTypes) == 0 {
+		return nil, fmt.Errorf("at least one event type is required")
+	}
+
+	eventTypes := make([]string, len(input.EventTypes))
+	for i, eventType := range input.EventTypes {
+		eventTypes[i] = webhookEventTypes[eventType]
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	subscriberID := user.ID
+	if organizationWide {
+		subscriberID = ""
+	}
+
+	subscription, err := repository.NewWebhookSubscriptionRepository().Create(ctx, user.OrganizationID, subscriberID, endpoint.String(), secret, eventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CreatedWebhookSubscription{
+		Subscription: convertWebhookSubscriptionToGraphQL(subscription),
+		Secret:       secret,
+	}, nil
+}
+
+// SetWebhookSubscriptionActive is the resolver for the setWebhookSubscriptionActive field.
+func (r *mutationResolver) SetWebhookSubscriptionActive(ctx context.Context, id string, active bool) (*model.WebhookSubscription, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := loadWebhookSubscription(ctx, user, id)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptionRepo := repository.NewWebhookSubscriptionRepository()
+	if err := subscriptionRepo.SetActive(ctx, user.OrganizationID, subscription.ID, active); err != nil {
+		return nil, err
+	}
+
+	subscription, err = subscriptionRepo.GetByID(ctx, user.OrganizationID, subscription.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertWebhookSubscriptionToGraphQL(subscription), nil
+}
+
+// DeleteWebhookSubscription is the resolver for the deleteWebhookSubscription field.
+func (r *mutationResolver) DeleteWebhookSubscription(ctx context.Context, id string) (bool, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	subscription, err := loadWebhookSubscription(ctx, user, id)
+	if err != nil {
+		return false, err
+	}
+
+	if err := repository.NewWebhookSubscriptionRepository().Delete(ctx, user.OrganizationID, subscription.ID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RedeliverWebhook is the resolver for the redeliverWebhook field.
+func (r *mutationResolver) RedeliverWebhook(ctx context.Context, deliveryID string) (*model.WebhookDelivery, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := repository.NewWebhookDeliveryRepository().GetByID(ctx, deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.NotFound("webhook delivery", deliveryID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := loadWebhookSubscription(ctx, user, delivery.SubscriptionID); err != nil {
+		return nil, err
+	}
+
+	redelivery, err := r.WebhookDispatcher.Redeliver(ctx, delivery.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertWebhookDeliveryToGraphQL(redelivery), nil
+}
+
+// UpdateReminderSettings is the resolver for the updateReminderSettings field.
+func (r *mutationResolver) UpdateReminderSettings(ctx context.Context, input model.ReminderSettingsInput) (*model.ReminderSettings, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := r.ReminderScheduler.Settings(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Enabled != nil {
+		settings.Enabled = *input.Enabled
+	}
+	if input.Overdue != nil {
+		settings.Overdue = *input.Overdue
+	}
+	if input.StartLeadDays != nil {
+		if settings.StartLeadDays, err = reminder.ValidateLeadDays(input.StartLeadDays); err != nil {
+			return nil, err
+		}
+	}
+	if input.DueLeadDays != nil {
+		if settings.DueLeadDays, err = reminder.ValidateLeadDays(input.DueLeadDays); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := repository.NewReminderRepository().SaveSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	return convertReminderSettingsToGraphQL(settings), nil
+}
+
+// UpdateNotificationPreferences is the resolver for the updateNotificationPreferences field.
+func (r *mutationResolver) UpdateNotificationPreferences(ctx context.Context, input model.NotificationPreferencesInput) (*model.NotificationPreferences, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences, err := r.Notifier.Preferences(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.EmailReminders != nil {
+		preferences.EmailReminders = *input.EmailReminders
+	}
+	if input.WeeklyDigest != nil {
+		preferences.WeeklyDigest = *input.WeeklyDigest
+	}
+	if input.DigestDay != nil {
+		preferences.DigestDay = *input.DigestDay
+	}
+
+	if err := r.Notifier.SavePreferences(ctx, preferences); err != nil {
+		return nil, err
+	}
+
+	return convertNotificationPreferencesToGraphQL(preferences), nil
+}
+
+// SendWeeklyDigest is the resolver for the sendWeeklyDigest field.
+func (r *mutationResolver) SendWeeklyDigest(ctx context.Context) (bool, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.Notifier.SendDigest(ctx, user.ID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UpdateAvailability is the resolver for the updateAvailability field.
+func (r *mutationResolver) UpdateAvailability(ctx context.Context, input model.AvailabilityInput) (*model.Availability, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	availability, err := r.TimelineScheduler.Availability(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.WorkingDays != nil {
+		availability.WorkingDays = make([]time.Weekday, len(input.WorkingDays))
+		for i, weekday := range input.WorkingDays {
+			availability.WorkingDays[i] = convertWeekday(weekday)
+		}
+	}
+	if input.HoursPerDay != nil {
+		availability.HoursPerDay = *input.HoursPerDay
+	}
+	if input.WeeklyCapacity != nil {
+		availability.WeeklyCapacity = *input.WeeklyCapacity
+	}
+	if input.HolidaySet != nil {
+		availability.HolidaySet = *input.HolidaySet
+	}
+	if input.BlackoutDates != nil {
+		availability.BlackoutDates = input.BlackoutDates
+	}
+
+	if err := r.TimelineScheduler.SaveAvailability(ctx, availability); err != nil {
+		return nil, err
+	}
+
+	return convertAvailabilityToGraphQL(availability), nil
+}
+
+// RescheduleTimeline is the resolver for the rescheduleTimeline field.
+func (r *mutationResolver) RescheduleTimeline(ctx context.Context, id string, from *time.Time) (*model.Timeline, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckTimelineAccess(ctx, user, id, models.GoalRoleEditor); err != nil {
+		return nil, err
+	}
+
+	start := clock.Today(r.Clock, user.Location())
+	if from != nil {
+		start = *from
+	}
+
+	timeline, err := r.TimelineScheduler.Reschedule(ctx, user, id, start)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTimelineToGraphQL(timeline), nil
+}
+
+// LevelWorkload is the resolver for the levelWorkload field.
+func (r *mutationResolver) LevelWorkload(ctx context.Context, from *time.Time) (*model.Workload, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := clock.Today(r.Clock, user.Location())
+	if from != nil {
+		start = *from
+	}
+
+	if err := r.TimelineScheduler.Level(ctx, user, start); err != nil {
+		return nil, err
+	}
+
+	workload, err := r.TimelineScheduler.Workload(ctx, user, start, start.AddDate(0, 0, 7*workloadWeeks))
+	if err != nil {
+		return nil, err
+	}
+
+	return convertWorkloadToGraphQL(workload), nil
+}
+
+// InviteToGoal is the resolver for the inviteToGoal field.
+func (r *mutationResolver) InviteToGoal(ctx context.Context, goalID string, email string, role model.GoalRole) (*model.GoalInvitation, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckGoalAccess(ctx, user, goalID, models.GoalRoleOwner); err != nil {
+		return nil, err
+	}
+
+	invitation, err := r.SharingService.Invite(ctx, user, goalID, email, strings.ToLower(string(role)))
+	if err != nil {
+		return nil, err
+	}
+
+	return convertGoalInvitationToGraphQL(invitation), nil
+}
+
+// AcceptInvitation is the resolver for the acceptInvitation field.
+func (r *mutationResolver) AcceptInvitation(ctx context.Context, id string) (*model.GoalMember, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := loadGoalInvitation(ctx, user, id)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := r.SharingService.Accept(ctx, user, invitation)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertGoalMemberToGraphQL(member), nil
+}
+
+// DeleteInvitation is the resolver for the deleteInvitation field.
+func (r *mutationResolver) DeleteInvitation(ctx context.Context, id string) (bool, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// The invitee declines, or the goal's owner revokes
+	if _, err := loadGoalInvitation(ctx, user, id); err != nil {
+		return false, err
+	}
+
+	if err := r.SharingService.DeleteInvitation(ctx, id); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UpdateGoalMemberRole is the resolver for the updateGoalMemberRole field.
+func (r *mutationResolver) UpdateGoalMemberRole(ctx context.Context, goalID string, userID string, role model.GoalRole) (*model.GoalMember, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckGoalAccess(ctx, user, goalID, models.GoalRoleOwner); err != nil {
+		return nil, err
+	}
+
+	member, err := r.SharingService.SetRole(ctx, goalID, userID, strings.ToLower(string(role)))
+	if err != nil {
+		return nil, err
+	}
+
+	return convertGoalMemberToGraphQL(member), nil
+}
+
+// RemoveGoalMember is the resolver for the removeGoalMember field.
+func (r *mutationResolver) RemoveGoalMember(ctx context.Context, goalID string, userID string) (bool, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// Members can leave a goal; only the owner can remove others
+	if userID != user.ID {
+		if err := service.CheckGoalAccess(ctx, user, goalID, models.GoalRoleOwner); err != nil {
+			return false, err
+		}
+	}
+
+	if err := r.SharingService.RemoveMember(ctx, goalID, userID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// InstantiateTemplate is the resolver for the instantiateTemplate field.
+func (r *mutationResolver) InstantiateTemplate(ctx context.Context, id string, startDate time.Time, targetDate *time.Time) (*model.Timeline, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := loadGoalTemplate(ctx, user, id)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline, err := r.TemplateService.Instantiate(ctx, user, template, startDate, targetDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTimelineToGraphQL(timeline), nil
+}
+
+// SaveTimelineAsTemplate is the resolver for the saveTimelineAsTemplate field.
+func (r *mutationResolver) SaveTimelineAsTemplate(ctx context.Context, timelineID string, title *string) (*model.GoalTemplate, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckTimelineAccess(ctx, user, timelineID, models.GoalRoleViewer); err != nil {
+		return nil, err
+	}
+
+	name := ""
+	if title != nil {
+		name = *title
+	}
+
+	template, err := r.TemplateService.SaveFromTimeline(ctx, user, timelineID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertGoalTemplateToGraphQL(template), nil
+}
+
+// DeleteGoalTemplate is the resolver for the deleteGoalTemplate field.
+func (r *mutationResolver) DeleteGoalTemplate(ctx context.Context, id string) (bool, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	template, err := loadGoalTemplate(ctx, user, id)
+	if err != nil {
+		return false, err
+	}
+	if template.IsCurated() {
+		return false, newCodedError(ctx, codeForbidden, "curated templates cannot be deleted")
+	}
+
+	if err := r.TemplateService.Delete(ctx, user, id); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ResetGenerationUsage is the resolver for the resetGenerationUsage field.
+func (r *mutationResolver) ResetGenerationUsage(ctx context.Context, userID string, period *string) (*model.GenerationUsage, error) {
+	admin, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usagePeriod := service.UsagePeriod(r.Clock.Now().UTC())
+	if period != nil {
+		usagePeriod = *period
+	}
+
+	usage, err := r.GenerationLimiter.ResetUsage(ctx, admin.OrganizationID, userID, usagePeriod)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user %s not found", userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return convertGenerationUsageToGraphQL(usage), nil
+}
+
+// SetGenerationQuota is the resolver for the setGenerationQuota field.
+func (r *mutationResolver) SetGenerationQuota(ctx context.Context, userID string, monthlyQuota int) (*model.GenerationUsage, error) {
+	admin, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := r.GenerationLimiter.SetMonthlyQuota(ctx, admin.OrganizationID, userID, monthlyQuota)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user %s not found", userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return convertGenerationUsageToGraphQL(usage), nil
+}
+
+// UpdateOrganizationSettings is the resolver for the updateOrganizationSettings field.
+func (r *mutationResolver) UpdateOrganizationSettings(ctx context.Context, input model.OrganizationSettingsInput) (*model.Organization, error) {
+	admin, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := models.OrganizationSettings{MonthlyQuota: input.MonthlyQuota}
+	if input.DefaultModel != nil {
+		settings.DefaultModel = *input.DefaultModel
+	}
+	if input.PromptTemplate != nil {
+		settings.PromptTemplate = *input.PromptTemplate
+	}
+
+	organization, err := r.OrganizationService.UpdateSettings(ctx, admin.OrganizationID, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertOrganizationToGraphQL(organization), nil
+}
+
+// CreateUser is the resolver for the createUser field.
+func (r *mutationResolver) CreateUser(ctx context.Context, email string, role *model.UserRole) (*model.User, error) {
+	admin, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userRole := models.RoleUser
+	if role != nil {
+		userRole = strings.ToLower(string(*role))
+	}
+
+	user, err := r.OrganizationService.CreateUser(ctx, admin.OrganizationID, email, userRole)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertUserToGraphQL(user), nil
+}
+
+// SetUserRole is the resolver for the setUserRole field.
+func (r *mutationResolver) SetUserRole(ctx context.Context, userID string, role model.UserRole) (*model.User, error) {
+	admin, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.OrganizationService.SetUserRole(ctx, admin, userID, strings.ToLower(string(role)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user %s not found", userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return convertUserToGraphQL(user), nil
+}
+
+// AddComment is the resolver for the addComment field.
+func (r *mutationResolver) AddComment(ctx context.Context, input model.AddCommentInput) (*model.Comment, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replies go on the goal or task of the comment they reply to
+	var goalID, taskID, parentID string
+	switch {
+	case input.ParentID != nil:
+		parent, err := loadComment(ctx, user, *input.ParentID, models.GoalRoleCommenter)
+		if err != nil {
+			return nil, err
+		}
+		goalID, taskID, parentID = parent.GoalID, parent.TaskID, parent.ID
+	case input.TaskID != nil:
+		if err := service.CheckTaskAccess(ctx, user, *input.TaskID, models.GoalRoleCommenter); err != nil {
+			return nil, err
+		}
+		if goalID, err = repository.NewTaskRepository().GetGoalID(ctx, user.OrganizationID, *input.TaskID); err != nil {
+			return nil, err
+		}
+		taskID = *input.TaskID
+	case input.GoalID != nil:
+		if err := service.CheckGoalAccess(ctx, user, *input.GoalID, models.GoalRoleCommenter); err != nil {
+			return nil, err
+		}
+		goalID = *input.GoalID
+	default:
+		return nil, fmt.Errorf("goalId, taskId or parentId is required")
+	}
+
+	comment, err := r.CommentService.Create(ctx, user, goalID, taskID, parentID, input.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertCommentToGraphQL(comment), nil
+}
+
+// EditComment is the resolver for the editComment field.
+func (r *mutationResolver) EditComment(ctx context.Context, id string, body string) (*model.Comment, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := loadComment(ctx, user, id, models.GoalRoleCommenter)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != user.ID {
+		return nil, newCodedError(ctx, codeForbidden, "only the author can edit a comment")
+	}
+
+	comment, err = r.CommentService.Edit(ctx, user, comment, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertCommentToGraphQL(comment), nil
+}
+
+// DeleteComment is the resolver for the deleteComment field.
+func (r *mutationResolver) DeleteComment(ctx context.Context, id string) (bool, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	comment, err := loadComment(ctx, user, id, models.GoalRoleViewer)
+	if err != nil {
+		return false, err
+	}
+
+	// Authors can delete their comments; the goal owner can delete any
+	if comment.UserID != user.ID {
+		if err := service.CheckGoalAccess(ctx, user, comment.GoalID, models.GoalRoleOwner); err != nil {
+			return false, err
+		}
+	}
+
+	if err := r.CommentService.Delete(ctx, user, comment); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DeleteGoal is the resolver for the deleteGoal field.
+func (r *mutationResolver) DeleteGoal(ctx context.Context, id string) (bool, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := service.CheckGoalAccess(ctx, user, id, models.GoalRoleOwner); err != nil {
+		return false, err
+	}
+
+	if err := r.TrashService.Delete(ctx, models.TrashGoal, id); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DeleteTimeline is the resolver for the deleteTimeline field.
+func (r *mutationResolver) DeleteTimeline(ctx context.Context, id string) (bool, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := service.CheckTimelineAccess(ctx, user, id, models.GoalRoleEditor); err != nil {
+		return false, err
+	}
+
+	if err := r.TrashService.Delete(ctx, models.TrashTimeline, id); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DeleteTask is the resolver for the deleteTask field.
+func (r *mutationResolver) DeleteTask(ctx context.Context, id string) (bool, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := service.CheckTaskAccess(ctx, user, id, models.GoalRoleEditor); err != nil {
+		return false, err
+	}
+
+	if err := r.TrashService.Delete(ctx, models.TrashTask, id); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RestoreFromTrash is the resolver for the restoreFromTrash field.
+func (r *mutationResolver) RestoreFromTrash(ctx context.Context, kind model.TrashKind, id string) (bool, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	trashKind := strings.ToLower(string(kind))
+	if err := service.CheckTrashAccess(ctx, user, trashKind, id); err != nil {
+		return false, err
+	}
+
+	if err := r.TrashService.Restore(ctx, trashKind, id); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertUserToGraphQL(user), nil
+}
+
+// Timeline is the resolver for the timeline field.
+func (r *queryResolver) Timeline(ctx context.Context, id string) (*model.Timeline, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckTimelineAccess(ctx, user, id, models.GoalRoleViewer); err != nil {
+		return nil, err
+	}
+
+	timelineRepo := repository.NewTimelineRepository()
+	timeline, err := timelineRepo.GetVisible(ctx, user.OrganizationID, id, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTimelineToGraphQL(timeline), nil
+}
+
+// Timelines is the resolver for the timelines field.
+func (r *queryResolver) Timelines(ctx context.Context, goalID string) ([]*model.Timeline, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckGoalAccess(ctx, user, goalID, models.GoalRoleViewer); err != nil {
+		return nil, err
+	}
+
+	timelineRepo := repository.NewTimelineRepository()
+	timelines, err := timelineRepo.GetVisibleByGoalID(ctx, user.OrganizationID, goalID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Timeline, len(timelines))
+	for i, timeline := range timelines {
+		result[i] = convertTimelineToGraphQL(timeline)
+	}
+
+	return result, nil
+}
+
+// UserTimelines is the resolver for the userTimelines field.
+func (r *queryResolver) UserTimelines(ctx context.Context, userID string) ([]*model.Timeline, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the user's goals the caller can see: all of their own, or those shared with them
+	goals, err := repository.NewGoalRepository().GetVisibleByUserID(ctx, user.OrganizationID, userID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertGoalTimelinesToGraphQL(ctx, goals)
+}
+
+// GenerationUsage is the resolver for the generationUsage field.
+func (r *queryResolver) GenerationUsage(ctx context.Context, userID *string, period *string) ([]*model.GenerationUsage, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usagePeriod := service.UsagePeriod(r.Clock.Now().UTC())
+	if period != nil {
+		usagePeriod = *period
+	}
+
+	// Users can see their own usage, admins can see everyone's in their organization
+	if userID == nil || *userID != user.ID {
+		if !user.IsAdmin() {
+			return nil, newCodedError(ctx, codeForbidden, "admin role required")
+		}
+	}
+
+	var usages []*models.GenerationUsage
+	if userID != nil {
+		usage, err := r.GenerationLimiter.Usage(ctx, user.OrganizationID, *userID, usagePeriod)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("user %s not found", *userID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	} else {
+		usages, err = r.GenerationLimiter.AllUsage(ctx, user.OrganizationID, usagePeriod)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]*model.GenerationUsage, len(usages))
+	for i, usage := range usages {
+		result[i] = convertGenerationUsageToGraphQL(usage)
+	}
+
+	return result, nil
+}
+
+// LlmUsage is the resolver for the llmUsage field.
+func (r *queryResolver) LlmUsage(ctx context.Context, groupBy model.UsageGrouping, filter *model.UsageFilter) ([]*model.LLMUsageSummary, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usageFilter, err := usageFilterFor(ctx, user, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := repository.NewLLMUsageRepository().Summarize(ctx, strings.ToLower(string(groupBy)), usageFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.LLMUsageSummary, len(summaries))
+	for i, summary := range summaries {
+		result[i] = convertLLMUsageSummaryToGraphQL(summary)
+	}
+
+	return result, nil
+}
+
+// ExpensiveLLMCalls is the resolver for the expensiveLLMCalls field.
+func (r *queryResolver) ExpensiveLLMCalls(ctx context.Context, filter *model.UsageFilter, limit *int) ([]*model.LLMCall, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usageFilter, err := usageFilterFor(ctx, user, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	calls, err := repository.NewLLMUsageRepository().GetMostExpensive(ctx, usageFilter, pageLimit(limit, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.LLMCall, len(calls))
+	for i, call := range calls {
+		result[i] = convertLLMCallToGraphQL(call)
+	}
+
+	return result, nil
+}
+
+// GenerationJob is the resolver for the generationJob field.
+func (r *queryResolver) GenerationJob(ctx context.Context, id string) (*model.GenerationJob, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := loadGenerationJob(ctx, user, id)
+	if err != nil || job == nil {
+		return nil, err
+	}
+
+	return convertGenerationJobToGraphQL(job), nil
+}
+
+// GenerationJobs is the resolver for the generationJobs field.
+func (r *queryResolver) GenerationJobs(ctx context.Context, status *model.GenerationJobStatus) ([]*model.GenerationJob, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jobStatus := ""
+	if status != nil {
+		jobStatus = strings.ToLower(string(*status))
+	}
+
+	jobs, err := repository.NewGenerationJobRepository().GetByUserID(ctx, user.ID, jobStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.GenerationJob, len(jobs))
+	for i, job := range jobs {
+		result[i] = convertGenerationJobToGraphQL(job)
+	}
+
+	return result, nil
+}
+
+// WebhookSubscriptions is the resolver for the webhookSubscriptions field.
+func (r *queryResolver) WebhookSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptionRepo := repository.NewWebhookSubscriptionRepository()
+	subscriptions, err := subscriptionRepo.GetByUserID(ctx, user.OrganizationID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Admins also manage the organization's subscriptions
+	if user.IsAdmin() {
+		organizationWide, err := subscriptionRepo.GetOrganizationWide(ctx, user.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, organizationWide...)
+	}
+
+	result := make([]*model.WebhookSubscription, len(subscriptions))
+	for i, subscription := range subscriptions {
+		result[i] = convertWebhookSubscriptionToGraphQL(subscription)
+	}
+
+	return result, nil
+}
+
+// WebhookDeliveries is the resolver for the webhookDeliveries field.
+func (r *queryResolver) WebhookDeliveries(ctx context.Context, subscriptionID string, limit *int) ([]*model.WebhookDelivery, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := loadWebhookSubscription(ctx, user, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := repository.NewWebhookDeliveryRepository().GetBySubscriptionID(ctx, subscriptionID, pageLimit(limit, 50))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.WebhookDelivery, len(deliveries))
+	for i, delivery := range deliveries {
+		result[i] = convertWebhookDeliveryToGraphQL(delivery)
+	}
+
+	return result, nil
+}
+
+// ReminderSettings is the resolver for the reminderSettings field.
+func (r *queryResolver) ReminderSettings(ctx context.Context) (*model.ReminderSettings, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := r.ReminderScheduler.Settings(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertReminderSettingsToGraphQL(settings), nil
+}
+
+// Reminders is the resolver for the reminders field.
+func (r *queryResolver) Reminders(ctx context.Context, limit *int) ([]*model.TaskReminder, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reminders, err := repository.NewReminderRepository().GetByUserID(ctx, user.ID, pageLimit(limit, 50))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.TaskReminder, len(reminders))
+	for i, taskReminder := range reminders {
+		result[i] = convertTaskReminderToGraphQL(taskReminder)
+	}
+
+	return result, nil
 }
 
-// Timeline is the resolver for the timeline field.
-func (r *queryResolver) Timeline(ctx context.Context, id string) (*model.Timeline, error) {
-	timelineRepo := repository.NewTimelineRepository()
-	timeline, err := timelineRepo.GetByID(id)
+// NotificationPreferences is the resolver for the notificationPreferences field.
+func (r *queryResolver) NotificationPreferences(ctx context.Context) (*model.NotificationPreferences, error) {
+	user, err := currentUser(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return convertTimelineToGraphQL(timeline), nil
+	preferences, err := r.Notifier.Preferences(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertNotificationPreferencesToGraphQL(preferences), nil
 }
 
-// Timelines is the resolver for the timelines field.
-func (r *queryResolver) Timelines(ctx context.Context, goalID string) ([]*model.Timeline, error) {
-	timelineRepo := repository.NewTimelineRepository()
-	timelines, err := timelineRepo.GetByGoalID(goalID)
+// Availability is the resolver for the availability field.
+func (r *queryResolver) Availability(ctx context.Context) (*model.Availability, error) {
+	user, err := currentUser(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]*model.Timeline, len(timelines))
-	for i, timeline := range timelines {
-		result[i] = convertTimelineToGraphQL(timeline)
+	availability, err := r.TimelineScheduler.Availability(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertAvailabilityToGraphQL(availability), nil
+}
+
+// HolidaySets is the resolver for the holidaySets field.
+func (r *queryResolver) HolidaySets(ctx context.Context) ([]*model.HolidaySet, error) {
+	sets := calendar.HolidaySets()
+	result := make([]*model.HolidaySet, len(sets))
+	for i, set := range sets {
+		result[i] = convertHolidaySetToGraphQL(set)
 	}
 
 	return result, nil
 }
 
-// UserTimelines is the resolver for the userTimelines field.
-func (r *queryResolver) UserTimelines(ctx context.Context, userID string) ([]*model.Timeline, error) {
-	goalRepo := repository.NewGoalRepository()
-	timelineRepo := repository.NewTimelineRepository()
+// Workload is the resolver for the workload field.
+func (r *queryResolver) Workload(ctx context.Context, from *time.Time, to *time.Time) (*model.Workload, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := clock.Today(r.Clock, user.Location())
+	if from != nil {
+		start = *from
+	}
+	end := start.AddDate(0, 0, 7*workloadWeeks)
+	if to != nil {
+		end = *to
+	}
 
-	// Get all goals for the user
-	goals, err := goalRepo.GetByUserID(userID)
+	workload, err := r.TimelineScheduler.Workload(ctx, user, start, end)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get timelines for each goal
-	var result []*model.Timeline
-	for _, goal := range goals {
-		timelines, err := timelineRepo.GetByGoalID(goal.ID)
-		if err != nil {
-			return nil, err
+	return convertWorkloadToGraphQL(workload), nil
+}
+
+// SharedTimelines is the resolver for the sharedTimelines field.
+func (r *queryResolver) SharedTimelines(ctx context.Context) ([]*model.Timeline, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	goals, err := r.SharingService.SharedGoals(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertGoalTimelinesToGraphQL(ctx, goals)
+}
+
+// GoalMembers is the resolver for the goalMembers field.
+func (r *queryResolver) GoalMembers(ctx context.Context, goalID string) ([]*model.GoalMember, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckGoalAccess(ctx, user, goalID, models.GoalRoleViewer); err != nil {
+		return nil, err
+	}
+
+	members, err := r.SharingService.Members(ctx, goalID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.GoalMember, len(members))
+	for i, member := range members {
+		result[i] = convertGoalMemberToGraphQL(member)
+	}
+
+	return result, nil
+}
+
+// GoalInvitations is the resolver for the goalInvitations field.
+func (r *queryResolver) GoalInvitations(ctx context.Context, goalID string) ([]*model.GoalInvitation, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckGoalAccess(ctx, user, goalID, models.GoalRoleOwner); err != nil {
+		return nil, err
+	}
+
+	invitations, err := r.SharingService.GoalInvitations(ctx, goalID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.GoalInvitation, len(invitations))
+	for i, invitation := range invitations {
+		result[i] = convertGoalInvitationToGraphQL(invitation)
+	}
+
+	return result, nil
+}
+
+// Invitations is the resolver for the invitations field.
+func (r *queryResolver) Invitations(ctx context.Context) ([]*model.GoalInvitation, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := r.SharingService.Invitations(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.GoalInvitation, len(invitations))
+	for i, invitation := range invitations {
+		result[i] = convertGoalInvitationToGraphQL(invitation)
+	}
+
+	return result, nil
+}
+
+// GoalTemplates is the resolver for the goalTemplates field.
+func (r *queryResolver) GoalTemplates(ctx context.Context) ([]*model.GoalTemplate, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := r.TemplateService.List(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.GoalTemplate, len(list))
+	for i, template := range list {
+		result[i] = convertGoalTemplateToGraphQL(template)
+	}
+
+	return result, nil
+}
+
+// GoalTemplate is the resolver for the goalTemplate field.
+func (r *queryResolver) GoalTemplate(ctx context.Context, id string) (*model.GoalTemplate, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := loadGoalTemplate(ctx, user, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertGoalTemplateToGraphQL(template), nil
+}
+
+// Organization is the resolver for the organization field.
+func (r *queryResolver) Organization(ctx context.Context) (*model.Organization, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	organization, err := r.OrganizationService.Get(ctx, user.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertOrganizationToGraphQL(organization), nil
+}
+
+// OrganizationUsers is the resolver for the organizationUsers field.
+func (r *queryResolver) OrganizationUsers(ctx context.Context) ([]*model.User, error) {
+	admin, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := r.OrganizationService.Users(ctx, admin.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.User, len(users))
+	for i, user := range users {
+		result[i] = convertUserToGraphQL(user)
+	}
+
+	return result, nil
+}
+
+// GoalComments is the resolver for the goalComments field.
+func (r *queryResolver) GoalComments(ctx context.Context, goalID string) ([]*model.Comment, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckGoalAccess(ctx, user, goalID, models.GoalRoleViewer); err != nil {
+		return nil, err
+	}
+
+	comments, err := r.CommentService.GoalComments(ctx, user, goalID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Comment, len(comments))
+	for i, comment := range comments {
+		result[i] = convertCommentToGraphQL(comment)
+	}
+
+	return result, nil
+}
+
+// TaskComments is the resolver for the taskComments field.
+func (r *queryResolver) TaskComments(ctx context.Context, taskID string) ([]*model.Comment, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckTaskAccess(ctx, user, taskID, models.GoalRoleViewer); err != nil {
+		return nil, err
+	}
+
+	comments, err := r.CommentService.TaskComments(ctx, user, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Comment, len(comments))
+	for i, comment := range comments {
+		result[i] = convertCommentToGraphQL(comment)
+	}
+
+	return result, nil
+}
+
+// TimelineActivity is the resolver for the timelineActivity field.
+func (r *queryResolver) TimelineActivity(ctx context.Context, timelineID string, before *time.Time, limit *int) ([]*model.ActivityItem, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckTimelineAccess(ctx, user, timelineID, models.GoalRoleViewer); err != nil {
+		return nil, err
+	}
+
+	activityBefore := r.Clock.Now()
+	if before != nil {
+		activityBefore = *before
+	}
+	items, err := r.CommentService.Feed(ctx, user, timelineID, activityBefore, pageLimit(limit, 50))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.ActivityItem, len(items))
+	for i, item := range items {
+		result[i] = convertActivityItemToGraphQL(item)
+	}
+
+	return result, nil
+}
+
+// Trash is the resolver for the trash field.
+func (r *queryResolver) Trash(ctx context.Context) ([]*model.TrashItem, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := r.TrashService.Items(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.TrashItem, len(items))
+	for i, item := range items {
+		result[i] = convertTrashItemToGraphQL(item, r.TrashService.PurgeAt(item))
+	}
+
+	return result, nil
+}
+
+// AuditLog is the resolver for the auditLog field.
+func (r *queryResolver) AuditLog(ctx context.Context, filter *model.AuditFilter, before *time.Time, limit *int) ([]*model.AuditEntry, error) {
+	admin, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	auditFilter, err := auditFilterFor(admin, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	entriesBefore := r.Clock.Now()
+	if before != nil {
+		entriesBefore = *before
+	}
+	entries, err := repository.NewAuditRepository().GetEntries(ctx, auditFilter, entriesBefore, pageLimit(limit, maxPageLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.AuditEntry, len(entries))
+	for i, entry := range entries {
+		result[i] = convertAuditEntryToGraphQL(entry)
+	}
+
+	return result, nil
+}
+
+// TimelineActivity is the resolver for the timelineActivity field.
+func (r *subscriptionResolver) TimelineActivity(ctx context.Context, timelineID string) (<-chan *model.ActivityItem, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.CheckTimelineAccess(ctx, user, timelineID, models.GoalRoleViewer); err != nil {
+		return nil, err
+	}
+
+	items := r.ActivityHub.Subscribe(ctx, timelineID)
+	result := make(chan *model.ActivityItem)
+	go func() {
+		defer close(result)
+		for item := range items {
+			select {
+			case result <- convertActivityItemToGraphQL(item):
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
 
-		// Convert and add to result
-		for _, timeline := range timelines {
-			result = append(result, convertTimelineToGraphQL(timeline))
+	return result, nil
+}
+
+// Streak is the resolver for the streak field.
+func (r *timelineTaskResolver) Streak(ctx context.Context, obj *model.TimelineTask) (*model.Streak, error) {
+	if obj.Recurrence == nil {
+		return nil, nil
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	streak, err := r.TaskService.Streak(ctx, user, convertTaskFromGraphQL(obj), clock.Today(r.Clock, user.Location()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Streak{
+		Current:              streak.Current,
+		Longest:              streak.Longest,
+		CompletedOccurrences: streak.CompletedOccurrences,
+		DueOccurrences:       streak.DueOccurrences,
+		TotalOccurrences:     streak.TotalOccurrences,
+	}, nil
+}
+
+// Occurrences is the resolver for the occurrences field.
+func (r *timelineTaskResolver) Occurrences(ctx context.Context, obj *model.TimelineTask, from *time.Time, to *time.Time) ([]*model.TaskOccurrence, error) {
+	if obj.Recurrence == nil {
+		return []*model.TaskOccurrence{}, nil
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := obj.StartDate, obj.EndDate
+	if from != nil {
+		start = *from
+	}
+	if to != nil {
+		end = *to
+	}
+
+	occurrences, err := r.TaskService.Occurrences(ctx, user, convertTaskFromGraphQL(obj), start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.TaskOccurrence, len(occurrences))
+	for i, occurrence := range occurrences {
+		result[i] = &model.TaskOccurrence{
+			Date:        occurrence.Date,
+			Completed:   occurrence.Completed,
+			CompletedAt: occurrence.CompletedAt,
 		}
 	}
 
 	return result, nil
 }
 
+// GenerationJob returns generated.GenerationJobResolver implementation.
+func (r *Resolver) GenerationJob() generated.GenerationJobResolver { return &generationJobResolver{r} }
+
+// HolidaySet returns generated.HolidaySetResolver implementation.
+func (r *Resolver) HolidaySet() generated.HolidaySetResolver { return &holidaySetResolver{r} }
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
+// TimelineTask returns generated.TimelineTaskResolver implementation.
+func (r *Resolver) TimelineTask() generated.TimelineTaskResolver { return &timelineTaskResolver{r} }
+
+type generationJobResolver struct{ *Resolver }
+type holidaySetResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
+type timelineTaskResolver struct{ *Resolver }
@@ -0,0 +1,70 @@
+package activity
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// subscriberBuffer is how many items a subscriber can fall behind before items are dropped for it
+const subscriberBuffer = 32
+
+// Hub sends activity on timelines to the clients subscribed to their feeds
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan *models.ActivityItem]struct{}
+}
+
+// NewHub creates a new Hub
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: map[string]map[chan *models.ActivityItem]struct{}{},
+	}
+}
+
+// Subscribe returns a channel receiving a timeline's activity. The channel is closed once ctx is done.
+func (h *Hub) Subscribe(ctx context.Context, timelineID string) <-chan *models.ActivityItem {
+	ch := make(chan *models.ActivityItem, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[timelineID] == nil {
+		h.subscribers[timelineID] = map[chan *models.ActivityItem]struct{}{}
+	}
+	h.subscribers[timelineID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[timelineID], ch)
+		if len(h.subscribers[timelineID]) == 0 {
+			delete(h.subscribers, timelineID)
+		}
+		close(ch)
+	}()
+
+	return ch
+}
+
+// Broadcast sends an item to everyone subscribed to its timeline. It never blocks: subscribers that
+// are not keeping up miss the item. Broadcasting on a nil Hub does nothing.
+func (h *Hub) Broadcast(item *models.ActivityItem) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[item.TimelineID] {
+		select {
+		case ch <- item:
+		default:
+			log.Printf("activity subscriber for timeline %s is not keeping up, dropping %s", item.TimelineID, item.Kind)
+		}
+	}
+}
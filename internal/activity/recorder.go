@@ -0,0 +1,57 @@
+package activity
+
+import (
+	"context"
+	"log"
+
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// Recorder adds rescheduled timelines and completed tasks to the activity feeds of their timelines
+type Recorder struct {
+	activityRepo *repository.ActivityRepository
+	hub          *Hub
+}
+
+// NewRecorder creates a new Recorder that sends what it records to the hub's subscribers
+func NewRecorder(hub *Hub) *Recorder {
+	return &Recorder{
+		activityRepo: repository.NewActivityRepository(),
+		hub:          hub,
+	}
+}
+
+// Handle records timeline.rescheduled and task.completed events. It is an events.Handler; recording
+// is a single insert, so the activity is in the feed by the time the publisher returns.
+func (r *Recorder) Handle(ctx context.Context, event events.Event) {
+	item := &models.ActivityItem{
+		UserID:     event.UserID,
+		OccurredAt: event.OccurredAt,
+	}
+
+	switch data := event.Data.(type) {
+	case events.TimelineData:
+		if event.Type != events.TimelineRescheduled {
+			return
+		}
+		item.TimelineID = data.TimelineID
+		item.Kind = models.ActivityRescheduled
+	case events.TaskData:
+		if event.Type != events.TaskCompleted {
+			return
+		}
+		item.TimelineID = data.TimelineID
+		item.TaskID = data.TaskID
+		item.Kind = models.ActivityCompleted
+	default:
+		return
+	}
+
+	if err := r.activityRepo.Create(ctx, item); err != nil {
+		log.Printf("failed to record %s activity on timeline %s: %v", item.Kind, item.TimelineID, err)
+		return
+	}
+	r.hub.Broadcast(item)
+}
@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/jukemori/timeline-generator/internal/auth"
+)
+
+// RequestIDHeader is the request header carrying a caller-chosen request ID
+const RequestIDHeader = "X-Request-ID"
+
+// Metadata describes who or what is making changes, for the audit log
+type Metadata struct {
+	// ActorID is the user making the change, or empty for the system
+	ActorID string
+	// Operation is the GraphQL mutation or background job making the change
+	Operation string
+	RequestID string
+	IPAddress string
+	UserAgent string
+}
+
+type contextKey struct{}
+
+// WithMetadata returns a copy of ctx carrying the given metadata
+func WithMetadata(ctx context.Context, metadata Metadata) context.Context {
+	return context.WithValue(ctx, contextKey{}, metadata)
+}
+
+// FromContext returns the metadata of the changes made with ctx
+func FromContext(ctx context.Context) Metadata {
+	metadata, _ := ctx.Value(contextKey{}).(Metadata)
+	return metadata
+}
+
+// WithOperation returns a copy of ctx whose changes are attributed to the named operation
+func WithOperation(ctx context.Context, operation string) context.Context {
+	metadata := FromContext(ctx)
+	metadata.Operation = operation
+	return WithMetadata(ctx, metadata)
+}
+
+// WithActor returns a copy of ctx whose changes are made by the system on behalf of a user,
+// such as a generation job the user queued
+func WithActor(ctx context.Context, actorID, operation string) context.Context {
+	return WithMetadata(ctx, Metadata{ActorID: actorID, Operation: operation})
+}
+
+// Middleware stores the calling user and request metadata on the request context.
+// It expects auth.Authenticator.Middleware to have identified the caller.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := strings.TrimSpace(r.Header.Get(RequestIDHeader))
+		if requestID == "" || len(requestID) > 64 {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := WithMetadata(r.Context(), Metadata{
+			ActorID:   auth.UserID(r.Context()),
+			Operation: r.Method + " " + r.URL.Path,
+			RequestID: requestID,
+			IPAddress: ipAddress(auth.ClientIP(r.Context())),
+			UserAgent: r.UserAgent(),
+		})
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RootFieldMiddleware attributes the changes made by each GraphQL mutation to the mutation's name.
+// Install it with AroundRootFields.
+func RootFieldMiddleware(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+	if field := graphql.GetRootFieldContext(ctx); field != nil && field.Object == "Mutation" {
+		ctx = WithOperation(ctx, field.Field.Name)
+	}
+	return next(ctx)
+}
+
+// ipAddress returns the canonical form of an IP address, or empty if it is not one
+func ipAddress(s string) string {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
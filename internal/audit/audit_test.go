@@ -0,0 +1,22 @@
+package audit
+
+import "testing"
+
+func TestIPAddress(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"203.0.113.7", "203.0.113.7"},
+		{"2001:DB8::1", "2001:db8::1"},
+		{"", ""},
+		{"not-an-ip", ""},
+		{"203.0.113.7, 10.0.0.1", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ipAddress(tt.in); got != tt.want {
+			t.Errorf("ipAddress(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
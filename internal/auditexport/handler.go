@@ -0,0 +1,158 @@
+package auditexport
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/auth"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// csvHeader names the columns of CSV exports
+var csvHeader = []string{
+	"id", "created_at", "actor_id", "operation", "action", "entity_type", "entity_id",
+	"before", "after", "request_id", "ip_address", "user_agent",
+}
+
+// Handler serves the audit log of the caller's organization as CSV (the default) or, with
+// format=jsonl, as one JSON entry per line, oldest first. Entries can be filtered with the actorId,
+// entityType, entityId, action, operation, from and to query parameters, where from and to are
+// RFC 3339 times. Only admins can export; the handler expects auth.Authenticator.Middleware to have
+// identified the caller.
+func Handler() http.Handler {
+	userRepo := repository.NewUserRepository()
+	auditRepo := repository.NewAuditRepository()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.UserID(ctx)
+		if userID == "" {
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := userRepo.GetByID(ctx, userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to export audit log: %v", err)
+			http.Error(w, "failed to export audit log", http.StatusInternalServerError)
+			return
+		}
+		if !user.IsAdmin() {
+			http.Error(w, "admin role required", http.StatusForbidden)
+			return
+		}
+
+		filter, err := parseFilter(r, user.OrganizationID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var write func(entry *models.AuditEntry) error
+		var flush func() error
+		format := r.URL.Query().Get("format")
+		switch format {
+		case "", "csv":
+			format = "csv"
+			writer := csv.NewWriter(w)
+			write = func(entry *models.AuditEntry) error { return writer.Write(csvRecord(entry)) }
+			flush = func() error {
+				writer.Flush()
+				return writer.Error()
+			}
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			// Write errors are buffered and reported by flush
+			writer.Write(csvHeader)
+		case "jsonl":
+			encoder := json.NewEncoder(w)
+			write = func(entry *models.AuditEntry) error { return encoder.Encode(entry) }
+			flush = func() error { return nil }
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		default:
+			http.Error(w, fmt.Sprintf("unsupported format %q: use csv or jsonl", format), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-log.%s"`, format))
+
+		// Once streaming has started the status can no longer change, so failures are only logged
+		if err := auditRepo.Export(ctx, filter, write); err != nil {
+			log.Printf("failed to export audit log: %v", err)
+			return
+		}
+		if err := flush(); err != nil {
+			log.Printf("failed to export audit log: %v", err)
+		}
+	})
+}
+
+// parseFilter reads the audit filter from the query parameters
+func parseFilter(r *http.Request, organizationID string) (repository.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := repository.AuditFilter{
+		OrganizationID: organizationID,
+		ActorID:        query.Get("actorId"),
+		EntityType:     query.Get("entityType"),
+		EntityID:       query.Get("entityId"),
+		Action:         strings.ToLower(query.Get("action")),
+		Operation:      query.Get("operation"),
+	}
+
+	switch filter.Action {
+	case "", models.AuditCreate, models.AuditUpdate, models.AuditDelete:
+	default:
+		return filter, fmt.Errorf("invalid action %q", filter.Action)
+	}
+
+	for _, bound := range []struct {
+		name   string
+		target **time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	} {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s time %q: use RFC 3339", bound.name, value)
+		}
+		*bound.target = &t
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, fmt.Errorf("invalid time range: from must be before to")
+	}
+
+	return filter, nil
+}
+
+// csvRecord lays out an entry in the order of csvHeader
+func csvRecord(entry *models.AuditEntry) []string {
+	return []string{
+		entry.ID,
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		entry.ActorID,
+		entry.Operation,
+		entry.Action,
+		entry.EntityType,
+		entry.EntityID,
+		string(entry.Before),
+		string(entry.After),
+		entry.RequestID,
+		entry.IPAddress,
+		entry.UserAgent,
+	}
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MinSecretLength is the fewest bytes a token signing secret may have
+const MinSecretLength = 32
+
+type contextKey string
+
+const (
+	userIDKey   contextKey = "userID"
+	clientIPKey contextKey = "clientIP"
+)
+
+// Authenticator identifies the calling user from a bearer token signed with a shared secret, and the
+// client from the connection it came in on
+type Authenticator struct {
+	secret []byte
+	issuer string
+	// trustedProxies are the networks whose X-Forwarded-For headers are believed
+	trustedProxies []*net.IPNet
+}
+
+// NewAuthenticator creates an Authenticator verifying HS256 tokens signed with secret. When issuer is
+// set, tokens must have been issued by it. trustedProxies lists the IP addresses or CIDR ranges of the
+// proxies in front of the server.
+func NewAuthenticator(secret, issuer string, trustedProxies []string) (*Authenticator, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("token secret must be at least %d bytes", MinSecretLength)
+	}
+
+	a := &Authenticator{secret: []byte(secret), issuer: issuer}
+	for _, proxy := range trustedProxies {
+		network, err := ParseNetwork(proxy)
+		if err != nil {
+			return nil, err
+		}
+		a.trustedProxies = append(a.trustedProxies, network)
+	}
+	return a, nil
+}
+
+// ParseNetwork parses a CIDR range, or a single IP address as the range holding only it
+func ParseNetwork(s string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(s); err == nil {
+		return network, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid trusted proxy %q: not an IP address or CIDR range", s)
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// IssueToken creates a token identifying the user that expires after ttl
+func (a *Authenticator) IssueToken(userID string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Subject:   userID,
+		Issuer:    a.issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.secret)
+}
+
+// VerifyToken returns the user a token identifies. The token must be signed with the secret, carry a
+// subject and not have expired.
+func (a *Authenticator) VerifyToken(token string) (string, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if a.issuer != "" {
+		options = append(options, jwt.WithIssuer(a.issuer))
+	}
+
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	}, options...)
+	if err != nil {
+		return "", err
+	}
+	if claims.Subject == "" {
+		return "", errors.New("token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+// Middleware stores the calling user and client IP on the request context. The user is taken from a
+// valid token in the Authorization header; requests without one are anonymous.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if token, ok := bearerToken(r); ok {
+			if userID, err := a.VerifyToken(token); err == nil {
+				ctx = WithUserID(ctx, userID)
+			}
+		}
+		ctx = context.WithValue(ctx, clientIPKey, a.clientIP(r))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// bearerToken returns the token of an Authorization header using the Bearer scheme
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// WithUserID returns a copy of ctx carrying the given user ID
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserID returns the calling user's ID, or an empty string for anonymous requests
+func UserID(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey).(string)
+	return userID
+}
+
+// ClientIP returns the IP address the request came from
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+// clientIP returns the address of the connection, unless it comes from a trusted proxy. Then the
+// X-Forwarded-For hops are walked from the right, past any other trusted proxies, to the first
+// address that is not one: the client as the nearest trusted proxy saw it.
+func (a *Authenticator) clientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	ip := net.ParseIP(remote)
+	if ip == nil || !a.trusted(ip) {
+		return remote
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// An address the proxies did not write; what is left of it cannot be believed
+			break
+		}
+		ip = hop
+		if !a.trusted(hop) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+// trusted reports whether an address belongs to a trusted proxy
+func (a *Authenticator) trusted(ip net.IP) bool {
+	for _, network := range a.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// sign signs claims with a method and key as a token from elsewhere would be
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyToken(t *testing.T) {
+	a, err := NewAuthenticator(testSecret, "timeline", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewAuthenticator(testSecret, "elsewhere", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid, err := a.IssueToken("user-1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := a.IssueToken("user-1", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongIssuer, err := other.IssueToken("user-1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	future := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	tests := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr bool
+	}{
+		{name: "valid", token: valid, want: "user-1"},
+		{name: "expired", token: expired, wantErr: true},
+		{name: "wrong issuer", token: wrongIssuer, wantErr: true},
+		{
+			name:    "alg none",
+			token:   sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, jwt.RegisteredClaims{Subject: "user-1", Issuer: "timeline", ExpiresAt: future}),
+			wantErr: true,
+		},
+		{
+			name:    "other HMAC algorithm",
+			token:   sign(t, jwt.SigningMethodHS512, []byte(testSecret), jwt.RegisteredClaims{Subject: "user-1", Issuer: "timeline", ExpiresAt: future}),
+			wantErr: true,
+		},
+		{
+			name:    "wrong secret",
+			token:   sign(t, jwt.SigningMethodHS256, []byte("fedcba9876543210fedcba9876543210"), jwt.RegisteredClaims{Subject: "user-1", Issuer: "timeline", ExpiresAt: future}),
+			wantErr: true,
+		},
+		{
+			name:    "no expiry",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.RegisteredClaims{Subject: "user-1", Issuer: "timeline"}),
+			wantErr: true,
+		},
+		{
+			name:    "no subject",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), jwt.RegisteredClaims{Issuer: "timeline", ExpiresAt: future}),
+			wantErr: true,
+		},
+		{name: "garbage", token: "not.a.token", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.VerifyToken(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("VerifyToken = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyToken: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("VerifyToken = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAuthenticatorShortSecret(t *testing.T) {
+	if _, err := NewAuthenticator(testSecret[:MinSecretLength-1], "", nil); err == nil {
+		t.Error("NewAuthenticator accepted a secret shorter than MinSecretLength")
+	}
+}
+
+func TestMiddlewareClientIP(t *testing.T) {
+	a, err := NewAuthenticator(testSecret, "", []string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{name: "direct", remoteAddr: "203.0.113.5:1234", want: "203.0.113.5"},
+		{name: "untrusted peer", remoteAddr: "203.0.113.5:1234", forwardedFor: []string{"198.51.100.7"}, want: "203.0.113.5"},
+		{name: "trusted proxy", remoteAddr: "10.1.2.3:1234", forwardedFor: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "trusted single address", remoteAddr: "192.0.2.1:1234", forwardedFor: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{
+			name:         "spoofed hops before the client",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: []string{"1.1.1.1, 198.51.100.7, 10.4.5.6"},
+			want:         "198.51.100.7",
+		},
+		{name: "hops in several headers", remoteAddr: "10.1.2.3:1234", forwardedFor: []string{"1.1.1.1", "198.51.100.7"}, want: "198.51.100.7"},
+		{name: "unparseable hop", remoteAddr: "10.1.2.3:1234", forwardedFor: []string{"198.51.100.7, junk"}, want: "10.1.2.3"},
+		{name: "only trusted hops", remoteAddr: "10.1.2.3:1234", forwardedFor: []string{"10.9.9.9"}, want: "10.9.9.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareUser(t *testing.T) {
+	a, err := NewAuthenticator(testSecret, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := a.IssueToken("user-1", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          string
+	}{
+		{name: "bearer token", authorization: "Bearer " + token, want: "user-1"},
+		{name: "scheme in lower case", authorization: "bearer " + token, want: "user-1"},
+		{name: "anonymous"},
+		{name: "other scheme", authorization: "Basic " + token},
+		{name: "invalid token", authorization: "Bearer " + token + "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = UserID(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tt.want {
+				t.Errorf("UserID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package calendar
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// Holiday is a public holiday in a holiday set
+type Holiday struct {
+	Date time.Time
+	Name string
+}
+
+// HolidaySet is a bundled calendar of public holidays, such as a country's
+type HolidaySet struct {
+	Code     string
+	Name     string
+	Holidays []Holiday
+}
+
+//go:embed holidays/*.json
+var holidayFS embed.FS
+
+// holidaySets are the bundled holiday sets by code
+var holidaySets = mustLoadHolidaySets()
+
+// HolidaySets returns the bundled holiday sets ordered by code
+func HolidaySets() []*HolidaySet {
+	sets := make([]*HolidaySet, 0, len(holidaySets))
+	for _, set := range holidaySets {
+		sets = append(sets, set)
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Code < sets[j].Code })
+	return sets
+}
+
+// LookupHolidaySet gets a bundled holiday set by code
+func LookupHolidaySet(code string) (*HolidaySet, bool) {
+	set, ok := holidaySets[code]
+	return set, ok
+}
+
+// Calendar knows which days a user is available to work on tasks
+type Calendar struct {
+	workingDays    [7]bool
+	hoursPerDay    float64
+	weeklyCapacity float64
+	closed         map[string]string
+}
+
+// New creates a Calendar from a user's availability
+func New(availability *models.Availability) (*Calendar, error) {
+	if err := Validate(availability); err != nil {
+		return nil, err
+	}
+
+	c := &Calendar{
+		hoursPerDay:    availability.HoursPerDay,
+		weeklyCapacity: availability.Capacity(),
+		closed:         map[string]string{},
+	}
+	for _, weekday := range availability.WorkingDays {
+		c.workingDays[weekday] = true
+	}
+	if availability.HolidaySet != "" {
+		for _, holiday := range holidaySets[availability.HolidaySet].Holidays {
+			c.closed[holiday.Date.Format(dateLayout)] = holiday.Name
+		}
+	}
+	for _, date := range availability.BlackoutDates {
+		c.closed[date.Format(dateLayout)] = "Unavailable"
+	}
+
+	return c, nil
+}
+
+// Validate checks an availability can be turned into a calendar
+func Validate(availability *models.Availability) error {
+	if len(availability.WorkingDays) == 0 {
+		return fmt.Errorf("at least one working day is required")
+	}
+	for _, weekday := range availability.WorkingDays {
+		if weekday < time.Sunday || weekday > time.Saturday {
+			return fmt.Errorf("invalid weekday %d", weekday)
+		}
+	}
+	if availability.HoursPerDay <= 0 || availability.HoursPerDay > 24 {
+		return fmt.Errorf("hours per day must be greater than 0 and at most 24")
+	}
+	if availability.WeeklyCapacity < 0 || availability.WeeklyCapacity > 168 {
+		return fmt.Errorf("weekly capacity must be between 0 and 168 hours")
+	}
+	if availability.HolidaySet != "" {
+		if _, ok := holidaySets[availability.HolidaySet]; !ok {
+			return fmt.Errorf("unknown holiday set %q", availability.HolidaySet)
+		}
+	}
+	return nil
+}
+
+// HoursPerDay returns how many hours the user works on a working day
+func (c *Calendar) HoursPerDay() float64 {
+	return c.hoursPerDay
+}
+
+// WeeklyCapacity returns how many hours the user can work in a week
+func (c *Calendar) WeeklyCapacity() float64 {
+	return c.weeklyCapacity
+}
+
+// IsAvailable reports whether the user works on the date
+func (c *Calendar) IsAvailable(date time.Time) bool {
+	if !c.workingDays[date.Weekday()] {
+		return false
+	}
+	_, closed := c.closed[date.Format(dateLayout)]
+	return !closed
+}
+
+// NextAvailable returns the first available day on or after the date.
+// A calendar always has a working day each week, and only finitely many closed dates, so this terminates.
+func (c *Calendar) NextAvailable(date time.Time) time.Time {
+	date = truncate(date)
+	for !c.IsAvailable(date) {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// AddWorkingDays returns the last day of a span of n working days starting on the first available day
+// on or after the date
+func (c *Calendar) AddWorkingDays(date time.Time, n int) time.Time {
+	date = c.NextAvailable(date)
+	for i := 1; i < n; i++ {
+		date = c.NextAvailable(date.AddDate(0, 0, 1))
+	}
+	return date
+}
+
+// WorkingDaysBetween counts the available days in a date range, counting both ends
+func (c *Calendar) WorkingDaysBetween(startDate, endDate time.Time) int {
+	days := 0
+	for date := truncate(startDate); !date.After(truncate(endDate)); date = date.AddDate(0, 0, 1) {
+		if c.IsAvailable(date) {
+			days++
+		}
+	}
+	return days
+}
+
+// WorkingDays converts an effort estimate into the number of working days it takes, at least one.
+// Estimates in hours are spread over the user's hours per day.
+func (c *Calendar) WorkingDays(duration models.Duration) int {
+	days := duration.Value
+	if duration.Unit == models.DurationHours {
+		days = duration.Value / c.hoursPerDay
+	}
+	return max(1, int(math.Ceil(days-1e-9)))
+}
+
+// Place schedules work of the given number of working days to start no earlier than the date.
+// It returns the first and last days the work is done on, both of which are available days.
+func (c *Calendar) Place(earliest time.Time, workingDays int) (time.Time, time.Time) {
+	startDate := c.NextAvailable(earliest)
+	return startDate, c.AddWorkingDays(startDate, workingDays)
+}
+
+// ClosedDates lists up to limit holidays and blackout dates within a date range that fall on working days,
+// in date order, formatted like "2025-12-25 (Christmas Day)"
+func (c *Calendar) ClosedDates(startDate, endDate time.Time, limit int) []string {
+	dates := []string{}
+	for date := truncate(startDate); !date.After(truncate(endDate)) && len(dates) < limit; date = date.AddDate(0, 0, 1) {
+		if !c.workingDays[date.Weekday()] {
+			continue
+		}
+		if reason, closed := c.closed[date.Format(dateLayout)]; closed {
+			dates = append(dates, fmt.Sprintf("%s (%s)", date.Format(dateLayout), reason))
+		}
+	}
+	return dates
+}
+
+// truncate drops the time of day, keeping the calendar date
+func truncate(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// holidayFile is the format of the bundled holiday data files
+type holidayFile struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Holidays []struct {
+		Date string `json:"date"`
+		Name string `json:"name"`
+	} `json:"holidays"`
+}
+
+func mustLoadHolidaySets() map[string]*HolidaySet {
+	files, err := holidayFS.ReadDir("holidays")
+	if err != nil {
+		panic(err)
+	}
+
+	sets := map[string]*HolidaySet{}
+	for _, file := range files {
+		data, err := holidayFS.ReadFile(path.Join("holidays", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		var parsed holidayFile
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			panic(fmt.Sprintf("invalid holiday file %s: %v", file.Name(), err))
+		}
+
+		set := &HolidaySet{Code: parsed.Code, Name: parsed.Name}
+		for _, holiday := range parsed.Holidays {
+			date, err := time.Parse(dateLayout, holiday.Date)
+			if err != nil {
+				panic(fmt.Sprintf("invalid holiday date in %s: %v", file.Name(), err))
+			}
+			set.Holidays = append(set.Holidays, Holiday{Date: date, Name: holiday.Name})
+		}
+		sets[set.Code] = set
+	}
+
+	return sets
+}
@@ -0,0 +1,97 @@
+package calendar
+
+import (
+	"math"
+	"time"
+)
+
+// Capacity tracks how many hours are booked on each day and week against a user's limits:
+// the calendar's hours per day and its weekly capacity
+type Capacity struct {
+	cal   *Calendar
+	days  map[time.Time]float64
+	weeks map[time.Time]float64
+}
+
+// NewCapacity creates an empty Capacity for the calendar
+func (c *Calendar) NewCapacity() *Capacity {
+	return &Capacity{
+		cal:   c,
+		days:  map[time.Time]float64{},
+		weeks: map[time.Time]float64{},
+	}
+}
+
+// Book records work that is already scheduled as the hours it takes on each day, such as those
+// returned by Spread. Booked work may exceed capacity; that is what a workload report shows.
+func (c *Capacity) Book(hours map[time.Time]float64) {
+	for date, booked := range hours {
+		date = truncate(date)
+		c.days[date] += booked
+		c.weeks[WeekStart(date)] += booked
+	}
+}
+
+// Fit schedules work taking the given hours, starting no earlier than the date, using whatever daily
+// and weekly capacity is left on each available day. It books the work and returns the first and last
+// days it is done on.
+func (c *Capacity) Fit(earliest time.Time, hours float64) (time.Time, time.Time) {
+	if hours <= 0 {
+		date := c.cal.NextAvailable(earliest)
+		return date, date
+	}
+
+	var startDate, endDate time.Time
+	remaining := hours
+
+	for date := c.cal.NextAvailable(earliest); ; date = c.cal.NextAvailable(date.AddDate(0, 0, 1)) {
+		week := WeekStart(date)
+		free := math.Min(c.cal.hoursPerDay-c.days[date], c.cal.WeeklyCapacity()-c.weeks[week])
+		if free <= 1e-9 {
+			continue
+		}
+
+		if startDate.IsZero() {
+			startDate = date
+		}
+		booked := math.Min(free, remaining)
+		c.days[date] += booked
+		c.weeks[week] += booked
+		remaining -= booked
+
+		if remaining <= 1e-9 {
+			endDate = date
+			break
+		}
+	}
+
+	return startDate, endDate
+}
+
+// Spread divides hours evenly over the available days in a date range, returning the hours per day
+func (c *Calendar) Spread(startDate, endDate time.Time, hours float64) map[time.Time]float64 {
+	spread := map[time.Time]float64{}
+	days := c.WorkingDaysBetween(startDate, endDate)
+	if days == 0 || hours <= 0 {
+		return spread
+	}
+
+	perDay := hours / float64(days)
+	for date := truncate(startDate); !date.After(truncate(endDate)); date = date.AddDate(0, 0, 1) {
+		if c.IsAvailable(date) {
+			spread[date] = perDay
+		}
+	}
+	return spread
+}
+
+// Week returns the hours booked in the week starting on weekStart
+func (c *Capacity) Week(weekStart time.Time) float64 {
+	return c.weeks[truncate(weekStart)]
+}
+
+// WeekStart returns the Monday of the week the date falls in
+func WeekStart(date time.Time) time.Time {
+	date = truncate(date)
+	return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+}
@@ -0,0 +1,86 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCapacityFit(t *testing.T) {
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+	// 2025-03-03 is a Monday
+	tests := []struct {
+		name           string
+		weeklyCapacity float64
+		blackoutDates  []time.Time
+		booked         map[time.Time]float64
+		earliest       string
+		hours          float64
+		wantStart      string
+		wantEnd        string
+	}{
+		{name: "no effort takes the next available day", earliest: "2025-03-01", hours: 0, wantStart: "2025-03-03", wantEnd: "2025-03-03"},
+		{name: "one day", earliest: "2025-03-03", hours: 4, wantStart: "2025-03-03", wantEnd: "2025-03-03"},
+		{name: "spills over days", earliest: "2025-03-03", hours: 10, wantStart: "2025-03-03", wantEnd: "2025-03-05"},
+		{name: "starts after the weekend", earliest: "2025-03-01", hours: 6, wantStart: "2025-03-03", wantEnd: "2025-03-04"},
+		{name: "skips blackout dates", blackoutDates: []time.Time{date("2025-03-04")}, earliest: "2025-03-03", hours: 8, wantStart: "2025-03-03", wantEnd: "2025-03-05"},
+		{name: "weekly capacity moves work to the next week", weeklyCapacity: 10, earliest: "2025-03-03", hours: 12, wantStart: "2025-03-03", wantEnd: "2025-03-10"},
+		{name: "uses what is left of booked days", booked: map[time.Time]float64{date("2025-03-03"): 3}, earliest: "2025-03-03", hours: 4, wantStart: "2025-03-03", wantEnd: "2025-03-04"},
+		{name: "skips fully booked days", booked: map[time.Time]float64{date("2025-03-03"): 4}, earliest: "2025-03-03", hours: 2, wantStart: "2025-03-04", wantEnd: "2025-03-04"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cal, err := New(&models.Availability{
+				WorkingDays:    weekdays,
+				HoursPerDay:    4,
+				WeeklyCapacity: tt.weeklyCapacity,
+				BlackoutDates:  tt.blackoutDates,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			capacity := cal.NewCapacity()
+			capacity.Book(tt.booked)
+
+			start, end := capacity.Fit(date(tt.earliest), tt.hours)
+			if got := start.Format(dateLayout); got != tt.wantStart {
+				t.Errorf("start = %s, want %s", got, tt.wantStart)
+			}
+			if got := end.Format(dateLayout); got != tt.wantEnd {
+				t.Errorf("end = %s, want %s", got, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestCapacityFitBooksWork(t *testing.T) {
+	cal, err := New(&models.Availability{
+		WorkingDays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		HoursPerDay: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	capacity := cal.NewCapacity()
+
+	capacity.Fit(date("2025-03-03"), 6)
+	start, end := capacity.Fit(date("2025-03-03"), 4)
+
+	if start != date("2025-03-04") || end != date("2025-03-05") {
+		t.Errorf("second fit = %s to %s, want 2025-03-04 to 2025-03-05", start.Format(dateLayout), end.Format(dateLayout))
+	}
+	if got := capacity.Week(date("2025-03-03")); got != 10 {
+		t.Errorf("week booked = %v hours, want 10", got)
+	}
+}
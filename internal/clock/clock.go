@@ -0,0 +1,72 @@
+package clock
+
+import (
+	"fmt"
+	"time"
+
+	// Embed the time zone database so zones resolve in minimal containers
+	_ "time/tzdata"
+)
+
+// DateLayout is the format of civil dates
+const DateLayout = "2006-01-02"
+
+// Clock tells the current time. Services take a Clock rather than calling time.Now
+// so that "now" can be controlled.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the Clock backed by the system time
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Fixed is a Clock stopped at a single instant
+type Fixed time.Time
+
+// Now returns the fixed instant
+func (f Fixed) Now() time.Time {
+	return time.Time(f)
+}
+
+// Date returns the civil date an instant falls on in a time zone.
+// Civil dates are represented as midnight UTC, which is how DATE columns are scanned.
+func Date(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Today returns the civil date it currently is in a time zone
+func Today(c Clock, loc *time.Location) time.Time {
+	return Date(c.Now(), loc)
+}
+
+// LoadLocation resolves an IANA time zone name such as "Europe/Berlin"
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, fmt.Errorf("time zone is required")
+	}
+	// The server's own zone is not a zone users can pick
+	if name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// Location resolves a time zone name, falling back to UTC for names that do not resolve
+func Location(name string) *time.Location {
+	loc, err := LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,310 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/auth"
+	"github.com/jukemori/timeline-generator/internal/database"
+)
+
+// Config is the configuration of the server. Each setting can be given in a YAML file, an environment
+// variable and a command-line flag; see Load for the order they apply in.
+type Config struct {
+	Server     Server     `yaml:"server"`
+	Auth       Auth       `yaml:"auth"`
+	Database   Database   `yaml:"database"`
+	LLM        LLM        `yaml:"llm"`
+	Generation Generation `yaml:"generation"`
+	Webhooks   Webhooks   `yaml:"webhooks"`
+	Email      Email      `yaml:"email"`
+	Reminders  Reminders  `yaml:"reminders"`
+	Deadlines  Deadlines  `yaml:"deadlines"`
+	Trash      Trash      `yaml:"trash"`
+	Tracing    Tracing    `yaml:"tracing"`
+	Features   Features   `yaml:"features"`
+}
+
+// Server configures the HTTP server
+type Server struct {
+	Port int `yaml:"port" env:"PORT"`
+	// MetricsPort serves /metrics when metrics are on. It is separate from Port so that metrics can be
+	// kept off the public network.
+	MetricsPort int `yaml:"metrics_port" env:"METRICS_PORT"`
+	// PublicURL is the externally reachable base URL of the API. It defaults to localhost on Port.
+	PublicURL string `yaml:"public_url" env:"PUBLIC_URL"`
+	// AllowOrigins lists the origins allowed to make cross-origin requests
+	AllowOrigins []string `yaml:"allow_origins" env:"ALLOW_ORIGINS"`
+	// TrustedProxies lists the IP addresses or CIDR ranges of the proxies in front of the server, whose
+	// X-Forwarded-For headers are believed when identifying clients
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	// ShutdownTimeout bounds how long in-flight requests and background work are waited for on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT_SECONDS" unit:"s"`
+	// DrainDelay is how long the server keeps taking requests on shutdown after /readyz starts failing,
+	// so that load balancers stop sending it traffic first
+	DrainDelay time.Duration `yaml:"drain_delay" env:"DRAIN_DELAY_SECONDS" unit:"s"`
+}
+
+// Auth configures the bearer tokens that identify users
+type Auth struct {
+	// TokenSecret signs and verifies tokens
+	TokenSecret string `yaml:"token_secret" env:"AUTH_TOKEN_SECRET" secret:"true"`
+	// TokenIssuer, when set, is written into issued tokens and required of the tokens presented
+	TokenIssuer string `yaml:"token_issuer" env:"AUTH_TOKEN_ISSUER"`
+	// TokenTTL is how long issued tokens stay valid
+	TokenTTL time.Duration `yaml:"token_ttl" env:"AUTH_TOKEN_TTL_HOURS" unit:"h"`
+}
+
+// Database configures the MySQL connection and its pool
+type Database struct {
+	Host     string `yaml:"host" env:"MYSQL_HOST"`
+	Port     int    `yaml:"port" env:"MYSQL_PORT"`
+	User     string `yaml:"user" env:"MYSQL_USER"`
+	Password string `yaml:"password" env:"MYSQL_PASSWORD" secret:"true"`
+	Name     string `yaml:"name" env:"MYSQL_DATABASE"`
+	// MaxOpenConns limits the connections in the pool; zero means no limit
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"MYSQL_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"MYSQL_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"MYSQL_CONN_MAX_LIFETIME_SECONDS" unit:"s"`
+	// QueryTimeout bounds each repository statement or transaction; zero leaves them bounded only by their request
+	QueryTimeout time.Duration `yaml:"query_timeout" env:"DB_QUERY_TIMEOUT_SECONDS" unit:"s"`
+	// ConnectTimeout is how long to keep retrying the database at startup
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT_SECONDS" unit:"s"`
+}
+
+// Connection returns the settings in the form database.InitDB takes them
+func (d Database) Connection() database.Config {
+	return database.Config{
+		Host:            d.Host,
+		Port:            d.Port,
+		User:            d.User,
+		Password:        d.Password,
+		Name:            d.Name,
+		MaxOpenConns:    d.MaxOpenConns,
+		MaxIdleConns:    d.MaxIdleConns,
+		ConnMaxLifetime: d.ConnMaxLifetime,
+		QueryTimeout:    d.QueryTimeout,
+		ConnectTimeout:  d.ConnectTimeout,
+	}
+}
+
+// LLM configures the language model timelines are generated with
+type LLM struct {
+	APIKey string `yaml:"api_key" env:"OPENAI_API_KEY" secret:"true"`
+	// Model is used unless the user's organization chooses another
+	Model       string        `yaml:"model" env:"OPENAI_MODEL"`
+	Temperature float64       `yaml:"temperature" env:"OPENAI_TEMPERATURE"`
+	Timeout     time.Duration `yaml:"timeout" env:"OPENAI_TIMEOUT_SECONDS" unit:"s"`
+}
+
+// Generation configures the rate limits, quota and workers of timeline generation
+type Generation struct {
+	RatePerMinute   float64 `yaml:"rate_per_minute" env:"GENERATION_RATE_PER_MINUTE"`
+	RateBurst       int     `yaml:"rate_burst" env:"GENERATION_RATE_BURST"`
+	IPRatePerMinute float64 `yaml:"ip_rate_per_minute" env:"GENERATION_IP_RATE_PER_MINUTE"`
+	IPRateBurst     int     `yaml:"ip_rate_burst" env:"GENERATION_IP_RATE_BURST"`
+	MonthlyQuota    int     `yaml:"monthly_quota" env:"GENERATION_MONTHLY_QUOTA"`
+	Workers         int     `yaml:"workers" env:"GENERATION_WORKERS"`
+	MaxAttempts     int     `yaml:"max_attempts" env:"GENERATION_MAX_ATTEMPTS"`
+}
+
+// Webhooks configures webhook delivery
+type Webhooks struct {
+	Workers     int           `yaml:"workers" env:"WEBHOOK_WORKERS"`
+	MaxAttempts int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	Timeout     time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT_SECONDS" unit:"s"`
+	// AllowPrivateNetworks lets webhooks reach loopback, private and link-local addresses, for development
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" env:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"`
+}
+
+// Email configures the SMTP server emails are sent through
+type Email struct {
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+	From         string `yaml:"from" env:"SMTP_FROM"`
+	// DigestInterval is how often to check for weekly digests that are due
+	DigestInterval time.Duration `yaml:"digest_interval" env:"DIGEST_INTERVAL_MINUTES" unit:"m"`
+}
+
+// Reminders configures the task reminder scheduler
+type Reminders struct {
+	Interval time.Duration `yaml:"interval" env:"REMINDER_INTERVAL_MINUTES" unit:"m"`
+}
+
+// Deadlines configures the missed deadline monitor
+type Deadlines struct {
+	CheckInterval time.Duration `yaml:"check_interval" env:"DEADLINE_CHECK_MINUTES" unit:"m"`
+}
+
+// Trash configures how long deleted items are kept
+type Trash struct {
+	Retention     time.Duration `yaml:"retention" env:"TRASH_RETENTION_DAYS" unit:"d"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"TRASH_PURGE_MINUTES" unit:"m"`
+}
+
+// Tracing configures where traces are exported
+type Tracing struct {
+	// Exporter is none, stdout or otlp
+	Exporter    string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+	// OTLPEndpoint is the base URL of the collector, as in the OpenTelemetry environment variable. Its
+	// scheme decides whether spans are sent over HTTPS.
+	OTLPEndpoint string `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+}
+
+// Features turns parts of the server on and off
+type Features struct {
+	// Playground serves the GraphQL playground at /
+	Playground bool `yaml:"playground" env:"FEATURE_PLAYGROUND"`
+	// Metrics serves Prometheus metrics at /metrics on server.metrics_port
+	Metrics bool `yaml:"metrics" env:"FEATURE_METRICS"`
+	// Webhooks delivers events to webhook subscribers
+	Webhooks bool `yaml:"webhooks" env:"FEATURE_WEBHOOKS"`
+	// Emails sends reminder, invitation and mention emails and weekly digests
+	Emails bool `yaml:"emails" env:"FEATURE_EMAILS"`
+	// Reminders publishes task reminders
+	Reminders bool `yaml:"reminders" env:"FEATURE_REMINDERS"`
+}
+
+// Default returns the configuration used for settings that are not given
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:            8080,
+			MetricsPort:     9090,
+			ShutdownTimeout: 30 * time.Second,
+			DrainDelay:      5 * time.Second,
+		},
+		Auth: Auth{TokenTTL: 24 * time.Hour},
+		Database: Database{
+			Host:            "localhost",
+			Port:            3306,
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+			QueryTimeout:    10 * time.Second,
+			ConnectTimeout:  time.Minute,
+		},
+		LLM: LLM{
+			Model:       "gpt-3.5-turbo",
+			Temperature: 0.7,
+			Timeout:     2 * time.Minute,
+		},
+		Generation: Generation{
+			RatePerMinute:   2,
+			RateBurst:       3,
+			IPRatePerMinute: 5,
+			IPRateBurst:     10,
+			MonthlyQuota:    30,
+			Workers:         2,
+			MaxAttempts:     3,
+		},
+		Webhooks: Webhooks{
+			Workers:     2,
+			MaxAttempts: 8,
+			Timeout:     10 * time.Second,
+		},
+		Email: Email{
+			SMTPHost:       "localhost",
+			SMTPPort:       1025,
+			From:           "Timeline Generator <no-reply@localhost>",
+			DigestInterval: time.Hour,
+		},
+		Reminders: Reminders{Interval: 10 * time.Minute},
+		Deadlines: Deadlines{CheckInterval: 15 * time.Minute},
+		Trash: Trash{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Tracing: Tracing{
+			Exporter:     "none",
+			ServiceName:  "timeline-generator",
+			OTLPEndpoint: "http://localhost:4318",
+		},
+		Features: Features{
+			Playground: true,
+			Metrics:    true,
+			Webhooks:   true,
+			Emails:     true,
+			Reminders:  true,
+		},
+	}
+}
+
+// Validate checks that the settings make sense together, returning every problem found
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port must be between 1 and 65535")
+	if c.Features.Metrics {
+		check(c.Server.MetricsPort > 0 && c.Server.MetricsPort < 65536, "server.metrics_port must be between 1 and 65535")
+		check(c.Server.MetricsPort != c.Server.Port, "server.metrics_port must differ from server.port")
+	}
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay must not be negative")
+	for _, proxy := range c.Server.TrustedProxies {
+		_, err := auth.ParseNetwork(proxy)
+		check(err == nil, "server.trusted_proxies: %v", err)
+	}
+
+	check(len(c.Auth.TokenSecret) >= auth.MinSecretLength, "auth.token_secret must be at least %d bytes", auth.MinSecretLength)
+	check(c.Auth.TokenTTL > 0, "auth.token_ttl must be positive")
+
+	check(c.Database.Host != "", "database.host is required")
+	check(c.Database.Port > 0 && c.Database.Port < 65536, "database.port must be between 1 and 65535")
+	check(c.Database.User != "", "database.user is required")
+	check(c.Database.Name != "", "database.name is required")
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns must not be more than database.max_open_conns")
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+	check(c.Database.QueryTimeout >= 0, "database.query_timeout must not be negative")
+	check(c.Database.ConnectTimeout > 0, "database.connect_timeout must be positive")
+
+	check(c.LLM.Model != "", "llm.model is required")
+	check(c.LLM.Temperature >= 0 && c.LLM.Temperature <= 2, "llm.temperature must be between 0 and 2")
+	check(c.LLM.Timeout > 0, "llm.timeout must be positive")
+
+	check(c.Generation.RatePerMinute > 0, "generation.rate_per_minute must be positive")
+	check(c.Generation.RateBurst > 0, "generation.rate_burst must be positive")
+	check(c.Generation.IPRatePerMinute > 0, "generation.ip_rate_per_minute must be positive")
+	check(c.Generation.IPRateBurst > 0, "generation.ip_rate_burst must be positive")
+	check(c.Generation.MonthlyQuota >= 0, "generation.monthly_quota must not be negative")
+	check(c.Generation.Workers > 0, "generation.workers must be positive")
+	check(c.Generation.MaxAttempts > 0, "generation.max_attempts must be positive")
+
+	check(c.Webhooks.Workers > 0, "webhooks.workers must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+
+	check(c.Email.SMTPPort > 0 && c.Email.SMTPPort < 65536, "email.smtp_port must be between 1 and 65535")
+	check(c.Email.From != "", "email.from is required")
+	check(c.Email.DigestInterval > 0, "email.digest_interval must be positive")
+
+	check(c.Reminders.Interval > 0, "reminders.interval must be positive")
+	check(c.Deadlines.CheckInterval > 0, "deadlines.check_interval must be positive")
+	check(c.Trash.Retention > 0, "trash.retention must be positive")
+	check(c.Trash.PurgeInterval > 0, "trash.purge_interval must be positive")
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		endpoint, err := url.Parse(c.Tracing.OTLPEndpoint)
+		check(err == nil && (endpoint.Scheme == "http" || endpoint.Scheme == "https") && endpoint.Host != "",
+			"tracing.otlp_endpoint must be an http or https URL, such as http://localhost:4318")
+	default:
+		check(false, "tracing.exporter must be none, stdout or otlp, not %q", c.Tracing.Exporter)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces the value of secret settings when the configuration is printed
+const redacted = "[redacted]"
+
+// units are the units durations given as bare numbers can be in, by the unit tag of their setting
+var units = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+}
+
+// setting is a single configurable value along with where it can be given
+type setting struct {
+	// path is the setting's dotted YAML path, which is also the name of its flag
+	path   string
+	env    string
+	unit   time.Duration
+	secret bool
+	value  reflect.Value
+}
+
+// settings lists every setting of c
+func (c *Config) settings() []setting {
+	var result []setting
+	var walk func(value reflect.Value, prefix string)
+	walk = func(value reflect.Value, prefix string) {
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			path := prefix + field.Tag.Get("yaml")
+			if field.Type.Kind() == reflect.Struct {
+				walk(value.Field(i), path+".")
+				continue
+			}
+			result = append(result, setting{
+				path:   path,
+				env:    field.Tag.Get("env"),
+				unit:   units[field.Tag.Get("unit")],
+				secret: field.Tag.Get("secret") == "true",
+				value:  value.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return result
+}
+
+// Load reads the configuration. Settings not given keep their defaults; otherwise the YAML file named by
+// the -config flag or the CONFIG_FILE environment variable applies first, then environment variables,
+// then command-line flags, each named by the setting's YAML path, such as -database.max_open_conns.
+// Durations are written like 90s or 15m; in environment variables whose names give a unit, such as
+// TRASH_RETENTION_DAYS, they may also be a bare number of that unit.
+func Load(name string, args []string) (*Config, error) {
+	c := Default()
+	settings := c.settings()
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	file := flags.String("config", os.Getenv("CONFIG_FILE"), "path of a YAML configuration file")
+	given := map[string]*string{}
+	for _, s := range settings {
+		value := new(string)
+		given[s.path] = value
+		usage := "sets " + s.path
+		if s.env != "" {
+			usage += ", overriding $" + s.env
+		}
+		flags.Func(s.path, usage, func(v string) error {
+			*value = v
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *file != "" {
+		if err := c.readFile(*file); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok && s.env != "" && value != "" {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("invalid $%s: %w", s.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		if value, ok := given[f.Name]; ok && flagErr == nil {
+			if err := settingByPath(settings, f.Name).set(*value); err != nil {
+				flagErr = fmt.Errorf("invalid -%s: %w", f.Name, err)
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if c.Server.PublicURL == "" {
+		c.Server.PublicURL = fmt.Sprintf("http://localhost:%d", c.Server.Port)
+	}
+	c.Server.PublicURL = strings.TrimSuffix(c.Server.PublicURL, "/")
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readFile applies the settings in a YAML file, rejecting those it does not know
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Redacted renders the configuration as YAML with secrets left out, for printing at startup
+func (c *Config) Redacted() string {
+	copied := *c
+	for _, s := range copied.settings() {
+		if s.secret && s.value.String() != "" {
+			s.value.SetString(redacted)
+		}
+	}
+
+	data, err := yaml.Marshal(&copied)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+func settingByPath(settings []setting, path string) setting {
+	for _, s := range settings {
+		if s.path == path {
+			return s
+		}
+	}
+	return setting{}
+}
+
+// set parses a value given as text into the setting
+func (s setting) set(text string) error {
+	text = strings.TrimSpace(text)
+	if s.value.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := parseDuration(text, s.unit)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(d))
+		return nil
+	}
+
+	switch s.value.Kind() {
+	case reflect.String:
+		s.value.SetString(text)
+	case reflect.Int:
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return err
+		}
+		s.value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		s.value.SetBool(b)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(text, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+	return nil
+}
+
+// parseDuration parses a duration such as 90s, or a bare number of unit when the setting has one
+func parseDuration(text string, unit time.Duration) (time.Duration, error) {
+	if unit != 0 {
+		if n, err := strconv.ParseFloat(text, 64); err == nil {
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(text)
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// writeFile writes a YAML configuration file for a test and returns its path
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// clearEnv empties every environment variable Load reads, so that the environment the tests run in
+// does not leak into them. Load ignores empty variables.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	for _, s := range Default().settings() {
+		if s.env != "" {
+			t.Setenv(s.env, "")
+		}
+	}
+}
+
+func TestLoadOrder(t *testing.T) {
+	file := writeFile(t, `
+server:
+  port: 1000
+  drain_delay: 1s
+auth:
+  token_secret: `+testSecret+`
+database:
+  user: file-user
+  name: file-db
+`)
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		args      []string
+		wantPort  int
+		wantDrain time.Duration
+		wantUser  string
+	}{
+		{
+			name:      "file over defaults",
+			wantPort:  1000,
+			wantDrain: time.Second,
+			wantUser:  "file-user",
+		},
+		{
+			name:      "environment over file",
+			env:       map[string]string{"PORT": "2000", "DRAIN_DELAY_SECONDS": "3", "MYSQL_USER": "env-user"},
+			wantPort:  2000,
+			wantDrain: 3 * time.Second,
+			wantUser:  "env-user",
+		},
+		{
+			name:      "flags over environment",
+			env:       map[string]string{"PORT": "2000", "DRAIN_DELAY_SECONDS": "3", "MYSQL_USER": "env-user"},
+			args:      []string{"-server.port=3000", "-server.drain_delay=90s"},
+			wantPort:  3000,
+			wantDrain: 90 * time.Second,
+			wantUser:  "env-user",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			c, err := Load("test", append([]string{"-config", file}, tt.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Server.Port != tt.wantPort {
+				t.Errorf("server.port = %d, want %d", c.Server.Port, tt.wantPort)
+			}
+			if c.Server.DrainDelay != tt.wantDrain {
+				t.Errorf("server.drain_delay = %v, want %v", c.Server.DrainDelay, tt.wantDrain)
+			}
+			if c.Database.User != tt.wantUser {
+				t.Errorf("database.user = %q, want %q", c.Database.User, tt.wantUser)
+			}
+			if want := fmt.Sprintf("http://localhost:%d", tt.wantPort); c.Server.PublicURL != want {
+				t.Errorf("server.public_url = %q, want %q", c.Server.PublicURL, want)
+			}
+
+			// Settings given nowhere keep their defaults
+			if c.Database.Name != "file-db" {
+				t.Errorf("database.name = %q, want file-db", c.Database.Name)
+			}
+			if want := Default().LLM.Model; c.LLM.Model != want {
+				t.Errorf("llm.model = %q, want the default %q", c.LLM.Model, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "database:\n  user: file-user\n  name: file-db\n"))
+	t.Setenv("AUTH_TOKEN_SECRET", testSecret)
+	t.Setenv("TRASH_RETENTION_DAYS", "7")
+
+	c, err := Load("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Database.User != "file-user" {
+		t.Errorf("database.user = %q, want file-user", c.Database.User)
+	}
+	if c.Trash.Retention != 7*24*time.Hour {
+		t.Errorf("trash.retention = %v, want 7 days", c.Trash.Retention)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	valid := "auth:\n  token_secret: " + testSecret + "\ndatabase:\n  user: u\n  name: n\n"
+
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		wantErr string
+	}{
+		{name: "unknown file setting", file: valid + "unknown: true\n", wantErr: "field unknown not found"},
+		{name: "invalid environment variable", file: valid, env: map[string]string{"PORT": "eighty"}, wantErr: "invalid $PORT"},
+		{name: "invalid flag", file: valid, args: []string{"-server.drain_delay=soon"}, wantErr: "invalid -server.drain_delay"},
+		{name: "unknown flag", file: valid, args: []string{"-server.nope=1"}, wantErr: "flag provided but not defined"},
+		{name: "invalid setting", file: valid, args: []string{"-server.port=0"}, wantErr: "server.port must be between 1 and 65535"},
+		{name: "missing secret", file: "database:\n  user: u\n  name: n\n", wantErr: "auth.token_secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := Load("test", append([]string{"-config", writeFile(t, tt.file)}, tt.args...))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	c := Default()
+	c.Auth.TokenSecret = testSecret
+	c.Database.Password = "database-password"
+	c.Database.User = "timeline"
+
+	printed := c.Redacted()
+	for _, secret := range []string{testSecret, "database-password"} {
+		if strings.Contains(printed, secret) {
+			t.Errorf("Redacted output contains the secret %q:\n%s", secret, printed)
+		}
+	}
+	if !strings.Contains(printed, redacted) || !strings.Contains(printed, "user: timeline") {
+		t.Errorf("Redacted output lacks the redacted secrets or other settings:\n%s", printed)
+	}
+	// Secrets that are not set are left empty, showing they are missing
+	if strings.Contains(printed, "api_key: "+redacted) {
+		t.Errorf("Redacted output marks an empty secret as set:\n%s", printed)
+	}
+
+	// The configuration itself keeps its secrets
+	if c.Auth.TokenSecret != testSecret || c.Database.Password != "database-password" {
+		t.Error("Redacted changed the configuration it printed")
+	}
+}
@@ -0,0 +1,261 @@
+// Package databasetest stands in for the MySQL database in tests. Statements are answered with rows
+// and results set up by the test for fragments of their SQL, and recorded so the test can check them.
+package databasetest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jukemori/timeline-generator/internal/database"
+)
+
+// driverName is the name the stand-in driver is registered under
+const driverName = "databasetest"
+
+var (
+	registerOnce sync.Once
+	// databases are the open stand-ins by their data source name
+	databases sync.Map
+	nextID    atomic.Int64
+)
+
+// Statement is a statement run against the stand-in, with its arguments
+type Statement struct {
+	Query string
+	Args  []driver.Value
+}
+
+// response answers the statements whose SQL contains match
+type response struct {
+	match    string
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+	err      error
+}
+
+// DB is a stand-in database. Statements no response matches fail, so that a test notices them.
+type DB struct {
+	mu        sync.Mutex
+	responses []response
+	queries   []Statement
+	execs     []Statement
+}
+
+// Open installs a new stand-in as database.DB for the duration of the test. Repositories created
+// after it is installed use it.
+func Open(t *testing.T) *DB {
+	t.Helper()
+	registerOnce.Do(func() { sql.Register(driverName, stubDriver{}) })
+
+	d := &DB{}
+	name := strconv.FormatInt(nextID.Add(1), 10)
+	databases.Store(name, d)
+
+	db, err := sql.Open(driverName, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		db.Close()
+		databases.Delete(name)
+	})
+	return d
+}
+
+// Rows answers queries containing match with rows of the columns. Responses are tried in the order they
+// were set up, so set up those for more specific fragments first.
+func (d *DB) Rows(match string, columns []string, rows ...[]driver.Value) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.responses = append(d.responses, response{match: match, columns: columns, rows: rows})
+}
+
+// Exec answers statements containing match with the number of rows they affected
+func (d *DB) Exec(match string, affected int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.responses = append(d.responses, response{match: match, affected: affected})
+}
+
+// Fail answers statements containing match with err
+func (d *DB) Fail(match string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.responses = append(d.responses, response{match: match, err: err})
+}
+
+// Queries returns the queries run so far
+func (d *DB) Queries() []Statement {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Statement(nil), d.queries...)
+}
+
+// Execs returns the statements that returned no rows run so far
+func (d *DB) Execs() []Statement {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Statement(nil), d.execs...)
+}
+
+// respond records a statement and finds the response to it
+func (d *DB) respond(query string, args []driver.NamedValue, exec bool) (response, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	statement := Statement{Query: strings.Join(strings.Fields(query), " "), Args: make([]driver.Value, len(args))}
+	for i, arg := range args {
+		statement.Args[i] = arg.Value
+	}
+	if exec {
+		d.execs = append(d.execs, statement)
+	} else {
+		d.queries = append(d.queries, statement)
+	}
+
+	for _, r := range d.responses {
+		if strings.Contains(statement.Query, strings.Join(strings.Fields(r.match), " ")) {
+			return r, r.err
+		}
+	}
+	return response{}, fmt.Errorf("databasetest: unexpected statement %q", statement.Query)
+}
+
+type stubDriver struct{}
+
+func (stubDriver) Open(name string) (driver.Conn, error) {
+	d, ok := databases.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("databasetest: database %s is closed", name)
+	}
+	return &conn{db: d.(*DB)}, nil
+}
+
+// conn is a connection to a stand-in. Transactions only group statements; rolling one back undoes nothing.
+type conn struct {
+	db *DB
+}
+
+var (
+	_ driver.QueryerContext    = (*conn)(nil)
+	_ driver.ExecerContext     = (*conn)(nil)
+	_ driver.ConnBeginTx       = (*conn)(nil)
+	_ driver.NamedValueChecker = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return tx{}, nil
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return tx{}, nil
+}
+
+// CheckNamedValue accepts arguments of any type, as the MySQL driver does
+func (c *conn) CheckNamedValue(value *driver.NamedValue) error {
+	if valuer, ok := value.Value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		value.Value = v
+		return err
+	}
+	return nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	r, err := c.db.respond(query, args, false)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: r.columns, values: r.rows}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r, err := c.db.respond(query, args, true)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(r.affected), nil
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	result := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		result[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return result
+}
+
+type tx struct{}
+
+func (tx) Commit() error {
+	return nil
+}
+
+func (tx) Rollback() error {
+	return nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
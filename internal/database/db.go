@@ -1,36 +1,82 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
+	"time"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/go-sql-driver/mysql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 // DB is the database connection
 var DB *sql.DB
 
-// InitDB initializes the database connection
-func InitDB() {
-	user := os.Getenv("MYSQL_USER")
-	password := os.Getenv("MYSQL_PASSWORD")
-	host := os.Getenv("MYSQL_HOST")
-	port := os.Getenv("MYSQL_PORT")
-	dbName := os.Getenv("MYSQL_DATABASE")
+// QueryTimeout bounds how long each repository statement or transaction may keep the database busy.
+// Zero leaves them bounded only by their context.
+var QueryTimeout = 10 * time.Second
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", user, password, host, port, dbName)
+// Delays between attempts to reach the database at startup
+const (
+	minRetryDelay = 500 * time.Millisecond
+	maxRetryDelay = 10 * time.Second
+)
+
+// Config configures the database connection and its pool
+type Config struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+	// MaxOpenConns limits the connections in the pool; zero means no limit
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// QueryTimeout becomes the package QueryTimeout bounding each repository statement
+	QueryTimeout time.Duration
+	// ConnectTimeout is how long to keep retrying the database before giving up
+	ConnectTimeout time.Duration
+}
+
+// InitDB initializes the database connection. Until the database answers, it retries with exponential
+// backoff; it gives up after the connect timeout or when ctx is done.
+func InitDB(ctx context.Context, config Config) {
+	// Timestamps are stored and read in UTC regardless of the server's zone, so DATE columns
+	// scan as midnight UTC, the representation of civil dates used throughout
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&loc=UTC&time_zone=%%27%%2B00%%3A00%%27", config.User, config.Password, config.Host, config.Port, config.Name)
 	
 	var err error
-	DB, err = sql.Open("mysql", dsn)
+	// Every query is traced as a child of the span in its context
+	DB, err = otelsql.Open("mysql", dsn, otelsql.WithAttributes(semconv.DBSystemMySQL))
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	DB.SetMaxOpenConns(config.MaxOpenConns)
+	DB.SetMaxIdleConns(config.MaxIdleConns)
+	DB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	QueryTimeout = config.QueryTimeout
 
-	err = DB.Ping()
-	if err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+	ctx, cancel := context.WithTimeout(ctx, config.ConnectTimeout)
+	defer cancel()
+
+	delay := minRetryDelay
+	for {
+		err = DB.PingContext(ctx)
+		if err == nil {
+			break
+		}
+		log.Printf("Failed to ping database, retrying in %s: %v", delay, err)
+
+		select {
+		case <-ctx.Done():
+			log.Fatalf("Failed to ping database: %v", err)
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxRetryDelay)
 	}
 
 	log.Println("Connected to database successfully")
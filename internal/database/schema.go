@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	timeline "github.com/jukemori/timeline-generator"
+)
+
+var (
+	createTablePattern = regexp.MustCompile(`(?s)CREATE TABLE (\w+) \((.*?)\n\);`)
+	// columnPattern matches column definitions, leaving out keys, indexes and constraints, which are
+	// written in upper case
+	columnPattern = regexp.MustCompile(`^\s*([a-z_][a-z0-9_]*)\s`)
+)
+
+// expectedColumns lists the columns of every table in schema.sql by table
+func expectedColumns() map[string][]string {
+	tables := map[string][]string{}
+	for _, match := range createTablePattern.FindAllStringSubmatch(timeline.Schema, -1) {
+		columns := []string{}
+		for _, line := range strings.Split(match[2], "\n") {
+			if column := columnPattern.FindStringSubmatch(line); column != nil {
+				columns = append(columns, column[1])
+			}
+		}
+		tables[match[1]] = columns
+	}
+	return tables
+}
+
+// CheckSchema checks that every table and column in schema.sql exists in the database, so the server
+// does not take requests before the schema has been brought up to date
+func CheckSchema(ctx context.Context) error {
+	rows, err := DB.QueryContext(ctx, "SELECT table_name, column_name FROM information_schema.columns WHERE table_schema = DATABASE()")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	existing := map[string]bool{}
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return err
+		}
+		existing[table+"."+column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	missing := []string{}
+	for table, columns := range expectedColumns() {
+		for _, column := range columns {
+			if !existing[table+"."+column] {
+				missing = append(missing, table+"."+column)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("schema is not up to date, missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
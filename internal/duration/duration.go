@@ -0,0 +1,121 @@
+package duration
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// amountPattern matches an amount or range of amounts followed by a unit, such as "30 minutes", "3 days", "1.5h" or "2-3 weeks"
+var amountPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)(?:\s*(?:-|–|to)\s*(\d+(?:\.\d+)?))?\s*(minutes?|mins?|hours?|hrs?|h|days?|d|weeks?|wks?|w|months?|mos?)\b`)
+
+// wordPattern matches spelled-out quantities, which are replaced with digits before matching amounts
+var wordPattern = regexp.MustCompile(`\b(half an?|a couple(?: of)?|a few|few|an?|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)\b`)
+
+var numberWords = map[string]string{
+	"half a": "0.5", "half an": "0.5", "a couple": "2", "a couple of": "2", "a few": "3", "few": "3",
+	"a": "1", "an": "1", "one": "1", "two": "2", "three": "3", "four": "4", "five": "5", "six": "6",
+	"seven": "7", "eight": "8", "nine": "9", "ten": "10", "eleven": "11", "twelve": "12",
+}
+
+// Parse normalizes a free-text duration such as "X days", "2 weeks" or "about a month".
+// Amounts are summed ("1 week 2 days") and ranges use their midpoint ("2-3 weeks").
+// Anything under a day of work is kept in hours; everything else is in days, counting
+// weeks as 7 days and months as 30.
+func Parse(label string) (models.Duration, error) {
+	text := wordPattern.ReplaceAllStringFunc(strings.ToLower(label), func(word string) string {
+		return numberWords[word]
+	})
+
+	matches := amountPattern.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return models.Duration{}, fmt.Errorf("unrecognized duration %q", label)
+	}
+
+	hours, days := 0.0, 0.0
+	for _, match := range matches {
+		amount, _ := strconv.ParseFloat(match[1], 64)
+		if match[2] != "" {
+			upper, _ := strconv.ParseFloat(match[2], 64)
+			amount = (amount + upper) / 2
+		}
+
+		switch {
+		case strings.HasPrefix(match[3], "mi"):
+			hours += amount / 60
+		case match[3][0] == 'h':
+			hours += amount
+		case match[3][0] == 'd':
+			days += amount
+		case match[3][0] == 'w':
+			days += amount * 7
+		case match[3][0] == 'm':
+			days += amount * 30
+		}
+	}
+
+	if hours+days == 0 {
+		return models.Duration{}, fmt.Errorf("duration %q is zero", label)
+	}
+
+	result := models.Duration{Label: strings.TrimSpace(label)}
+	if days == 0 && hours < models.HoursPerDay {
+		result.Value, result.Unit = round(hours), models.DurationHours
+	} else {
+		result.Value, result.Unit = round(days+hours/models.HoursPerDay), models.DurationDays
+	}
+
+	return result, nil
+}
+
+// Reconcile parses a duration label and makes it agree with the task's date range.
+// The dates are what tasks are scheduled by, so an estimate that does not fit between
+// them, or a label that cannot be parsed, is replaced by the length of the range.
+func Reconcile(label string, startDate, endDate time.Time) models.Duration {
+	span := SpanDays(startDate, endDate)
+
+	result, err := Parse(label)
+	if err != nil || result.Days() > float64(span) {
+		result = models.Duration{Value: float64(span), Unit: models.DurationDays}
+		result.Label = Format(result)
+	}
+
+	return result
+}
+
+// SpanDays returns the number of calendar days a date range covers, counting both ends
+func SpanDays(startDate, endDate time.Time) int {
+	startDate = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, time.UTC)
+
+	days := int(endDate.Sub(startDate).Hours()/24) + 1
+	if days < 1 {
+		return 1
+	}
+	return days
+}
+
+// Format returns a human-readable label for a duration, such as "3 hours", "2 weeks" or "10 days"
+func Format(d models.Duration) string {
+	value, unit := d.Value, "day"
+	if d.Unit == models.DurationHours {
+		unit = "hour"
+	} else if value >= 7 && math.Mod(value, 7) == 0 {
+		value, unit = value/7, "week"
+	}
+
+	if value != 1 {
+		unit += "s"
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64) + " " + unit
+}
+
+// round rounds to two decimal places, which is what the database stores
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,82 @@
+package duration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		label     string
+		wantValue float64
+		wantUnit  string
+		wantErr   bool
+	}{
+		{label: "3 days", wantValue: 3, wantUnit: models.DurationDays},
+		{label: "1 day", wantValue: 1, wantUnit: models.DurationDays},
+		{label: "2 weeks", wantValue: 14, wantUnit: models.DurationDays},
+		{label: "about a month", wantValue: 30, wantUnit: models.DurationDays},
+		{label: "1 week 2 days", wantValue: 9, wantUnit: models.DurationDays},
+		{label: "2-3 weeks", wantValue: 17.5, wantUnit: models.DurationDays},
+		{label: "2 to 4 days", wantValue: 3, wantUnit: models.DurationDays},
+		{label: "30 minutes", wantValue: 0.5, wantUnit: models.DurationHours},
+		{label: "1.5h", wantValue: 1.5, wantUnit: models.DurationHours},
+		{label: "half an hour", wantValue: 0.5, wantUnit: models.DurationHours},
+		{label: "a couple of hours", wantValue: 2, wantUnit: models.DurationHours},
+		{label: "a few days", wantValue: 3, wantUnit: models.DurationDays},
+		{label: "Two Weeks", wantValue: 14, wantUnit: models.DurationDays},
+		{label: "one day and 4 hours", wantValue: 1.5, wantUnit: models.DurationDays},
+		// Number words only count as whole words
+		{label: "often 3 days", wantValue: 3, wantUnit: models.DurationDays},
+		{label: "someone's 2 hours", wantValue: 2, wantUnit: models.DurationHours},
+		{label: "tenfold 1 week", wantValue: 7, wantUnit: models.DurationDays},
+		{label: "", wantErr: true},
+		{label: "soon", wantErr: true},
+		{label: "0 days", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			got, err := Parse(tt.label)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %+v, want an error", tt.label, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.label, err)
+			}
+			if got.Value != tt.wantValue || got.Unit != tt.wantUnit {
+				t.Errorf("Parse(%q) = %v %s, want %v %s", tt.label, got.Value, got.Unit, tt.wantValue, tt.wantUnit)
+			}
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		label     string
+		endDate   time.Time
+		wantValue float64
+		wantLabel string
+	}{
+		{"fits", "2 days", start.AddDate(0, 0, 4), 2, "2 days"},
+		{"longer than the dates", "3 weeks", start.AddDate(0, 0, 6), 7, "1 week"},
+		{"unparseable", "a while", start.AddDate(0, 0, 2), 3, "3 days"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Reconcile(tt.label, start, tt.endDate)
+			if got.Value != tt.wantValue || got.Label != tt.wantLabel {
+				t.Errorf("Reconcile(%q) = %v %q, want %v %q", tt.label, got.Value, got.Label, tt.wantValue, tt.wantLabel)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// Event types published by the application
+const (
+	TimelineGenerated   = "timeline.generated"
+	TimelineRescheduled = "timeline.rescheduled"
+	TaskCompleted       = "task.completed"
+	TaskDeadlineMissed  = "task.deadline_missed"
+	TaskReminder        = "task.reminder"
+	GoalInvitation      = "goal.invitation"
+	CommentMention      = "comment.mention"
+)
+
+// Types lists every event type that can be subscribed to
+var Types = []string{
+	TimelineGenerated,
+	TimelineRescheduled,
+	TaskCompleted,
+	TaskDeadlineMissed,
+	TaskReminder,
+	GoalInvitation,
+	CommentMention,
+}
+
+// Event is something that happened to a user's data
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	UserID     string      `json:"userId"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data"`
+}
+
+// Handler reacts to published events. Handlers run synchronously with the publisher's context and
+// should return quickly.
+type Handler func(ctx context.Context, event Event)
+
+// Bus delivers published events to every subscribed handler
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewBus creates a new Bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler for all events
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish sends an event to every handler. Publishing on a nil Bus does nothing.
+func (b *Bus) Publish(ctx context.Context, eventType, userID string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	event := Event{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		UserID:     userID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		dispatch(ctx, handler, event)
+	}
+}
+
+// dispatch runs a handler, keeping a panicking handler from affecting the publisher
+func dispatch(ctx context.Context, handler Handler, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("event handler panicked on %s %s: %v", event.Type, event.ID, r)
+		}
+	}()
+	handler(ctx, event)
+}
+
+// TimelineData is the payload of timeline events
+type TimelineData struct {
+	TimelineID string `json:"timelineId"`
+	GoalID     string `json:"goalId"`
+	Title      string `json:"title"`
+	StartDate  string `json:"startDate"`
+	EndDate    string `json:"endDate"`
+	TaskCount  int    `json:"taskCount"`
+}
+
+// NewTimelineData builds the event payload for a timeline
+func NewTimelineData(timeline *models.Timeline) TimelineData {
+	return TimelineData{
+		TimelineID: timeline.ID,
+		GoalID:     timeline.GoalID,
+		Title:      timeline.Title,
+		StartDate:  timeline.StartDate.Format("2006-01-02"),
+		EndDate:    timeline.EndDate.Format("2006-01-02"),
+		TaskCount:  len(timeline.Tasks),
+	}
+}
+
+// TaskData is the payload of task events
+type TaskData struct {
+	TaskID     string `json:"taskId"`
+	TimelineID string `json:"timelineId"`
+	Title      string `json:"title"`
+	StartDate  string `json:"startDate"`
+	EndDate    string `json:"endDate"`
+	Completed  bool   `json:"completed"`
+}
+
+// NewTaskData builds the event payload for a task
+func NewTaskData(task *models.TimelineTask) TaskData {
+	return TaskData{
+		TaskID:     task.ID,
+		TimelineID: task.TimelineID,
+		Title:      task.Title,
+		StartDate:  task.StartDate.Format("2006-01-02"),
+		EndDate:    task.EndDate.Format("2006-01-02"),
+		Completed:  task.Completed,
+	}
+}
+
+// ReminderData is the payload of task reminders
+type ReminderData struct {
+	TaskData
+	Kind     string `json:"kind"`
+	LeadDays int    `json:"leadDays"`
+	Message  string `json:"message"`
+}
+
+// InvitationData is the payload of goal invitations
+type InvitationData struct {
+	InvitationID string `json:"invitationId"`
+	GoalID       string `json:"goalId"`
+	GoalTitle    string `json:"goalTitle"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	InvitedBy    string `json:"invitedBy"`
+}
+
+// NewInvitationData builds the event payload for a goal invitation
+func NewInvitationData(invitation *models.GoalInvitation) InvitationData {
+	return InvitationData{
+		InvitationID: invitation.ID,
+		GoalID:       invitation.GoalID,
+		GoalTitle:    invitation.GoalTitle,
+		Email:        invitation.Email,
+		Role:         invitation.Role,
+		InvitedBy:    invitation.InvitedBy,
+	}
+}
+
+// MentionData is the payload of comment mentions, which are published for the mentioned user
+type MentionData struct {
+	CommentID   string `json:"commentId"`
+	GoalID      string `json:"goalId"`
+	TaskID      string `json:"taskId,omitempty"`
+	AuthorEmail string `json:"authorEmail"`
+	Body        string `json:"body"`
+}
+
+// NewMentionData builds the event payload for a mention in a comment
+func NewMentionData(comment *models.Comment) MentionData {
+	return MentionData{
+		CommentID:   comment.ID,
+		GoalID:      comment.GoalID,
+		TaskID:      comment.TaskID,
+		AuthorEmail: comment.AuthorEmail,
+		Body:        comment.Body,
+	}
+}
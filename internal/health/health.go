@@ -0,0 +1,102 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports whether a dependency of the server is usable
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker serves the liveness and readiness probes of the server
+type Checker struct {
+	timeout  time.Duration
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+// NewChecker creates a new Checker whose checks must each pass within timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add adds a check the server must pass to be ready. Checks must be added before serving probes.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Drain marks the server as shutting down, so it is no longer ready whatever its checks report
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Liveness serves /healthz. It answers as long as the server is able to handle requests at all.
+func (c *Checker) Liveness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, http.StatusOK, status{Status: "ok"})
+	})
+}
+
+// Readiness serves /readyz. It runs every check at once and answers 503 Service Unavailable with the
+// failures when any of them fails or the server is shutting down.
+func (c *Checker) Readiness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.draining.Load() {
+			writeStatus(w, http.StatusServiceUnavailable, status{Status: "shutting down"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+		defer cancel()
+
+		result := status{Status: "ok", Checks: map[string]string{}}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, check := range c.checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				outcome := "ok"
+				if err := check.check(ctx); err != nil {
+					outcome = err.Error()
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				result.Checks[check.name] = outcome
+				if outcome != "ok" {
+					result.Status = "unavailable"
+				}
+			}()
+		}
+		wg.Wait()
+
+		code := http.StatusOK
+		if result.Status != "ok" {
+			code = http.StatusServiceUnavailable
+		}
+		writeStatus(w, code, result)
+	})
+}
+
+// status is the JSON body of a probe response
+type status struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func writeStatus(w http.ResponseWriter, code int, body status) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
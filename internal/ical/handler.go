@@ -0,0 +1,66 @@
+package ical
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jukemori/timeline-generator/internal/auth"
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// Handler serves a timeline as an iCalendar file at a path with an {id} wildcard,
+// such as "GET /timelines/{id}/calendar.ics". The timeline's owner and anyone its goal is shared with
+// can download it; the handler expects auth.Authenticator.Middleware to have identified the caller.
+func Handler(clk clock.Clock) http.Handler {
+	userRepo := repository.NewUserRepository()
+	timelineRepo := repository.NewTimelineRepository()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID := auth.UserID(ctx)
+		if userID == "" {
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := userRepo.GetByID(ctx, userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to export timeline: %v", err)
+			http.Error(w, "failed to export timeline", http.StatusInternalServerError)
+			return
+		}
+
+		id := r.PathValue("id")
+		// Timelines the caller cannot see are not found, so their existence is not given away
+		timeline, err := timelineRepo.GetVisible(ctx, user.OrganizationID, id, user.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "timeline not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to export timeline %s: %v", id, err)
+			http.Error(w, "failed to export timeline", http.StatusInternalServerError)
+			return
+		}
+
+		var calendar strings.Builder
+		if err := Encode(&calendar, timeline, clk.Now()); err != nil {
+			log.Printf("failed to export timeline %s: %v", id, err)
+			http.Error(w, "failed to export timeline", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="timeline-%s.ics"`, id))
+		fmt.Fprint(w, calendar.String())
+	})
+}
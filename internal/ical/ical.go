@@ -0,0 +1,110 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/recurrence"
+)
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405Z"
+	// maxLineLength is the longest a content line may be before it is folded, in octets
+	maxLineLength = 75
+)
+
+// Encode writes a timeline as an iCalendar (RFC 5545) calendar with an all-day event per task.
+// Recurring tasks become a single event with an RRULE, occurring on each date the task does.
+func Encode(w io.Writer, timeline *models.Timeline, now time.Time) error {
+	out := &writer{w: bufio.NewWriter(w)}
+
+	out.line("BEGIN:VCALENDAR")
+	out.line("VERSION:2.0")
+	out.line("PRODID:-//Timeline Generator//Timeline Export//EN")
+	out.line("CALSCALE:GREGORIAN")
+	out.line("X-WR-CALNAME:" + escape(timeline.Title))
+
+	for i := range timeline.Tasks {
+		task := &timeline.Tasks[i]
+
+		out.line("BEGIN:VEVENT")
+		out.line("UID:" + task.ID + "@timeline-generator")
+		out.line("DTSTAMP:" + now.UTC().Format(dateTimeLayout))
+		out.line("DTSTART;VALUE=DATE:" + task.StartDate.Format(dateLayout))
+		if task.IsRecurring() {
+			rule, err := recurrence.Export(task)
+			if err != nil {
+				return fmt.Errorf("task %s: %w", task.ID, err)
+			}
+			// Each occurrence is a single all-day event
+			out.line("DTEND;VALUE=DATE:" + task.StartDate.AddDate(0, 0, 1).Format(dateLayout))
+			out.line("RRULE:" + rule)
+		} else {
+			// DTEND is exclusive for all-day events
+			out.line("DTEND;VALUE=DATE:" + task.EndDate.AddDate(0, 0, 1).Format(dateLayout))
+		}
+		out.line("SUMMARY:" + escape(task.Title))
+		if task.Description != "" {
+			out.line("DESCRIPTION:" + escape(task.Description))
+		}
+		out.line(fmt.Sprintf("PRIORITY:%d", priority(task.Priority)))
+		out.line("TRANSP:TRANSPARENT")
+		out.line("END:VEVENT")
+	}
+
+	out.line("END:VCALENDAR")
+
+	if out.err != nil {
+		return out.err
+	}
+	return out.w.Flush()
+}
+
+// priority maps a task priority, 1 to 5 with 5 the highest, to an iCalendar priority, 1 to 9 with 1 the highest
+func priority(p int) int {
+	p = min(max(p, 1), 5)
+	return 11 - 2*p
+}
+
+// escape escapes text property values
+func escape(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(text)
+}
+
+// writer writes CRLF-terminated content lines, folding long ones, and remembers the first error
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (w *writer) line(content string) {
+	if w.err != nil {
+		return
+	}
+
+	// Fold without splitting a UTF-8 sequence; continuation lines start with a space, which counts
+	limit := maxLineLength
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && content[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if _, w.err = w.w.WriteString(content[:cut] + "\r\n "); w.err != nil {
+			return
+		}
+		content = content[cut:]
+		limit = maxLineLength - 1
+	}
+	_, w.err = w.w.WriteString(content + "\r\n")
+}
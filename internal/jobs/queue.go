@@ -0,0 +1,289 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/audit"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+	"github.com/jukemori/timeline-generator/internal/service"
+)
+
+// Config configures the generation queue and its workers
+type Config struct {
+	Workers      int
+	MaxAttempts  int
+	PollInterval time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// StaleAfter is how long a running job may go without its worker renewing its lock before it is
+	// assumed to belong to a worker that died and is queued again. Workers renew the lock every
+	// PollInterval, so it must be several times longer.
+	StaleAfter time.Duration
+}
+
+// DefaultConfig returns the queue configuration used when none is given
+func DefaultConfig() Config {
+	return Config{
+		Workers:      2,
+		MaxAttempts:  3,
+		PollInterval: 2 * time.Second,
+		BaseBackoff:  10 * time.Second,
+		MaxBackoff:   10 * time.Minute,
+		StaleAfter:   10 * time.Minute,
+	}
+}
+
+// Queue is a database-backed queue of timeline generation jobs
+type Queue struct {
+	config    Config
+	jobRepo   *repository.GenerationJobRepository
+	generator *service.TimelineGenerator
+	limiter   *service.GenerationLimiter
+	wg        sync.WaitGroup
+	// running is cancelled by Abort to interrupt the jobs that are running
+	running context.Context
+	abort   context.CancelFunc
+}
+
+// NewQueue creates a new Queue
+func NewQueue(config Config, generator *service.TimelineGenerator, limiter *service.GenerationLimiter) *Queue {
+	running, abort := context.WithCancel(context.Background())
+	return &Queue{
+		config:    config,
+		jobRepo:   repository.NewGenerationJobRepository(),
+		generator: generator,
+		limiter:   limiter,
+		running:   running,
+		abort:     abort,
+	}
+}
+
+// Enqueue queues a timeline generation for the user
+func (q *Queue) Enqueue(ctx context.Context, userID string, input models.TimelineInput) (*models.GenerationJob, error) {
+	return q.jobRepo.Create(ctx, userID, input, q.config.MaxAttempts)
+}
+
+// Cancel cancels a queued or running job.
+// The quota of a queued job is released at once. A running job is stopped the next time its worker
+// renews its lock, and its quota is released then unless it produced a timeline in the meantime.
+func (q *Queue) Cancel(ctx context.Context, id string) (*models.GenerationJob, error) {
+	cancelledIn, err := q.jobRepo.Cancel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := q.jobRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if cancelledIn == models.JobStatusQueued {
+		q.release(ctx, job.UserID)
+	}
+
+	return job, nil
+}
+
+// Depth counts the jobs waiting to run
+func (q *Queue) Depth() (int, error) {
+	return q.jobRepo.CountQueued(context.Background())
+}
+
+// Start launches the workers. They stop claiming jobs when ctx is cancelled but finish the jobs they are
+// running; use Wait to block until they have, and Abort to interrupt the jobs.
+func (q *Queue) Start(ctx context.Context) {
+	for i := 0; i < q.config.Workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			q.work(ctx)
+		}()
+	}
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		q.requeueStale(ctx)
+	}()
+}
+
+// Wait blocks until all workers have stopped
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+// Abort interrupts the jobs that are running, putting them back in the queue to run again after
+// restart, and waits up to timeout for the workers to stop
+func (q *Queue) Abort(timeout time.Duration) {
+	q.abort()
+
+	stopped := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+	}
+}
+
+// work claims and runs jobs until ctx is cancelled
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain every due job before waiting for the next tick
+		for ctx.Err() == nil {
+			job, err := q.jobRepo.ClaimNext(ctx)
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				log.Printf("failed to claim generation job: %v", err)
+				break
+			}
+			q.run(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run generates the timeline for a claimed job and records the outcome.
+// What the job creates is audited as made by the user who queued it.
+func (q *Queue) run(ctx context.Context, job *models.GenerationJob) {
+	// A claimed job is finished even when the workers are stopping, unless Abort interrupts it; the LLM
+	// client timeout bounds it. The outcome is recorded either way.
+	ctx = context.WithoutCancel(ctx)
+	jobCtx, cancel := context.WithCancel(audit.WithActor(ctx, job.UserID, "generation job "+job.ID))
+	defer cancel()
+	defer context.AfterFunc(q.running, cancel)()
+	go q.heartbeat(jobCtx, cancel, job.ID)
+
+	timeline, err := q.generator.GenerateTimeline(jobCtx, job.UserID, job.Input)
+
+	if err == nil {
+		if ok, markErr := q.jobRepo.MarkSucceeded(ctx, job.ID, timeline.ID); markErr != nil {
+			log.Printf("failed to mark generation job %s succeeded: %v", job.ID, markErr)
+		} else if !ok {
+			log.Printf("generation job %s finished after being cancelled, timeline %s kept", job.ID, timeline.ID)
+		}
+		return
+	}
+
+	// The job ended without a timeline, so its quota is given back unless it is to run again.
+	// A job cancelled while running only gets here once it has stopped, when a retry or failure can no
+	// longer be recorded for it.
+	var requeued bool
+	var recordErr error
+	switch {
+	case q.running.Err() != nil:
+		// The job was aborted on shutdown: put it back so it runs after restart
+		requeued, recordErr = q.jobRepo.Retry(ctx, job.ID, "interrupted by shutdown", time.Now())
+	case jobCtx.Err() != nil:
+		// The job stopped running under this worker: cancelled, or requeued as stale
+		var current *models.GenerationJob
+		if current, recordErr = q.jobRepo.GetByID(ctx, job.ID); recordErr == nil {
+			requeued = current.Status != models.JobStatusCancelled
+			log.Printf("generation job %s stopped while running, now %s", job.ID, current.Status)
+		}
+	case job.Attempts < job.MaxAttempts:
+		requeued, recordErr = q.jobRepo.Retry(ctx, job.ID, err.Error(), time.Now().Add(q.backoff(job.Attempts)))
+	default:
+		_, recordErr = q.jobRepo.MarkFailed(ctx, job.ID, err.Error())
+	}
+
+	if recordErr != nil {
+		log.Printf("failed to record the outcome of generation job %s: %v", job.ID, recordErr)
+		return
+	}
+	if !requeued {
+		q.release(ctx, job.UserID)
+	}
+}
+
+// heartbeat renews the lock on a running job every PollInterval, so that it is not requeued while its
+// worker is alive, and cancels the job's context once the job is no longer running because it was cancelled
+func (q *Queue) heartbeat(ctx context.Context, cancel context.CancelFunc, id string) {
+	ticker := time.NewTicker(q.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			running, err := q.jobRepo.Heartbeat(ctx, id)
+			if err != nil {
+				log.Printf("failed to renew the lock on generation job %s: %v", id, err)
+				continue
+			}
+			if !running {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// requeueStale periodically puts back jobs abandoned by workers that died. Those that have used up
+// their attempts fail, and their quota is given back.
+func (q *Queue) requeueStale(ctx context.Context) {
+	ticker := time.NewTicker(q.config.StaleAfter / 2)
+	defer ticker.Stop()
+
+	for {
+		requeued, failedUserIDs, err := q.jobRepo.RequeueStale(ctx, time.Now().Add(-q.config.StaleAfter))
+		if err != nil {
+			log.Printf("failed to requeue stale generation jobs: %v", err)
+		}
+		if requeued > 0 {
+			log.Printf("requeued %d stale generation jobs", requeued)
+		}
+		if len(failedUserIDs) > 0 {
+			log.Printf("failed %d stale generation jobs that used up their attempts", len(failedUserIDs))
+		}
+		for _, userID := range failedUserIDs {
+			q.release(ctx, userID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// backoff returns the delay before retrying a job that has failed attempts times
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.config.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= q.config.MaxBackoff {
+			return q.config.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// release gives back the quota counted when a job that never produced a timeline was enqueued
+func (q *Queue) release(ctx context.Context, userID string) {
+	if q.limiter == nil {
+		return
+	}
+	if err := q.limiter.Release(ctx, userID); err != nil {
+		log.Printf("failed to release generation usage for user %s: %v", userID, err)
+	}
+}
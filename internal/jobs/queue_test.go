@@ -0,0 +1,158 @@
+package jobs
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/database/databasetest"
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/openai"
+	"github.com/jukemori/timeline-generator/internal/service"
+)
+
+// newTestQueue creates a queue on the stand-in database whose workers never poll during a test
+func newTestQueue() *Queue {
+	config := DefaultConfig()
+	config.PollInterval = time.Hour
+	config.StaleAfter = time.Hour
+
+	bus := events.NewBus()
+	generator := service.NewTimelineGenerator(openai.NewClient(openai.Config{}), service.NewTimelineScheduler(clock.System, bus), bus)
+	return NewQueue(config, generator, service.NewGenerationLimiter(service.GenerationLimits{}, clock.System))
+}
+
+// execsContaining returns the statements run that contain fragment
+func execsContaining(db *databasetest.DB, fragment string) []databasetest.Statement {
+	var matching []databasetest.Statement
+	for _, statement := range db.Execs() {
+		if strings.Contains(statement.Query, fragment) {
+			matching = append(matching, statement)
+		}
+	}
+	return matching
+}
+
+func TestBackoff(t *testing.T) {
+	q := &Queue{config: Config{BaseBackoff: 10 * time.Second, MaxBackoff: time.Minute}}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: 10 * time.Second},
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 4, want: time.Minute},
+		{attempts: 100, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := q.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRunAttemptsLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		attempts    int
+		wantRetry   bool
+		wantRelease bool
+	}{
+		{name: "attempts left", attempts: 2, wantRetry: true},
+		{name: "last attempt", attempts: 3, wantRelease: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := databasetest.Open(t)
+			db.Exec("UPDATE generation_jobs", 1)
+			db.Exec("UPDATE generation_usage", 1)
+			q := newTestQueue()
+
+			// A job whose input is invalid fails before reaching the LLM provider
+			job := &models.GenerationJob{
+				ID:          "job-1",
+				UserID:      "user-1",
+				Status:      models.JobStatusRunning,
+				Input:       models.TimelineInput{CurrentDate: "not a date"},
+				Attempts:    tt.attempts,
+				MaxAttempts: 3,
+			}
+			before := time.Now()
+			q.run(context.Background(), job)
+
+			retries := execsContaining(db, "run_at = ?")
+			failures := execsContaining(db, "finished_at = ?")
+			if got := len(retries) == 1; got != tt.wantRetry {
+				t.Errorf("retried = %v, want %v", got, tt.wantRetry)
+			}
+			if got := len(failures) == 1; got != !tt.wantRetry {
+				t.Errorf("failed = %v, want %v", got, !tt.wantRetry)
+			}
+			if got := len(execsContaining(db, "UPDATE generation_usage")) == 1; got != tt.wantRelease {
+				t.Errorf("released quota = %v, want %v", got, tt.wantRelease)
+			}
+
+			// A retry waits for the backoff of the attempts made so far
+			if tt.wantRetry && len(retries) == 1 {
+				runAt, _ := retries[0].Args[2].(time.Time)
+				if wait := runAt.Sub(before); wait < q.backoff(tt.attempts) {
+					t.Errorf("retried after %v, want at least %v", wait, q.backoff(tt.attempts))
+				}
+			}
+		})
+	}
+}
+
+func TestRequeueStaleFailsUsedUpJobs(t *testing.T) {
+	db := databasetest.Open(t)
+	db.Rows("SELECT user_id FROM generation_jobs", []string{"user_id"},
+		[]driver.Value{"user-1"},
+		[]driver.Value{"user-2"},
+	)
+	db.Exec("UPDATE generation_jobs", 1)
+	db.Exec("UPDATE generation_usage", 1)
+	q := newTestQueue()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		q.requeueStale(ctx)
+	}()
+
+	// Stop the loop once it has gone round once, releasing the quota of both failed jobs
+	deadline := time.Now().Add(5 * time.Second)
+	for len(execsContaining(db, "UPDATE generation_usage")) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	failures := execsContaining(db, "attempts >= max_attempts")
+	if len(failures) != 1 || failures[0].Args[0] != models.JobStatusFailed {
+		t.Fatalf("failing statements = %+v, want one failing the used up jobs", failures)
+	}
+	requeues := execsContaining(db, "run_at = ?")
+	if len(requeues) != 1 || requeues[0].Args[0] != models.JobStatusQueued {
+		t.Errorf("requeuing statements = %+v, want one requeuing the others", requeues)
+	}
+
+	releases := execsContaining(db, "UPDATE generation_usage")
+	if len(releases) != 2 {
+		t.Fatalf("released quota %d times, want once for each failed job", len(releases))
+	}
+	for i, userID := range []string{"user-1", "user-2"} {
+		if releases[i].Args[1] != userID {
+			t.Errorf("release %d is for %v, want %s", i, releases[i].Args[1], userID)
+		}
+	}
+}
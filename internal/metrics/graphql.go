@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/jukemori/timeline-generator/internal/operation"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var (
+	operations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "graphql_operations_total",
+		Help:      "GraphQL operations by root field, type and result.",
+	}, []string{"operation", "type", "result"})
+
+	operationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "graphql_operation_duration_seconds",
+		Help:      "Latency of GraphQL queries and mutations, from parsing to the response.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "type"})
+
+	resolverCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "graphql_resolver_calls_total",
+		Help:      "Calls to GraphQL resolvers by field.",
+	}, []string{"field"})
+
+	resolverErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "graphql_resolver_errors_total",
+		Help:      "Errors returned by GraphQL resolvers by field.",
+	}, []string{"field"})
+)
+
+// Tracer is a gqlgen extension recording operation counts and latencies and resolver errors.
+// Install it with the server's Use.
+type Tracer struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = Tracer{}
+
+// ExtensionName implements graphql.HandlerExtension
+func (Tracer) ExtensionName() string {
+	return "Metrics"
+}
+
+// Validate implements graphql.HandlerExtension
+func (Tracer) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse records each operation once its response is ready. Subscriptions are counted for
+// every event they send, and their latency is not recorded.
+func (Tracer) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	response := next(ctx)
+	if !graphql.HasOperationContext(ctx) {
+		return response
+	}
+
+	opCtx := graphql.GetOperationContext(ctx)
+	if opCtx.Operation == nil {
+		return response
+	}
+	name := operation.Name(opCtx)
+	operationType := string(opCtx.Operation.Operation)
+
+	result := "success"
+	if response == nil || len(response.Errors) > 0 {
+		result = "error"
+	}
+	operations.WithLabelValues(name, operationType, result).Inc()
+
+	if opCtx.Operation.Operation != ast.Subscription {
+		operationDuration.WithLabelValues(name, operationType).Observe(time.Since(opCtx.Stats.OperationStart).Seconds())
+	}
+
+	return response
+}
+
+// InterceptField counts calls to resolvers and the errors they return. Fields read straight from their
+// parent object are not counted.
+func (Tracer) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	field := graphql.GetFieldContext(ctx)
+	if field == nil || !field.IsResolver {
+		return next(ctx)
+	}
+
+	name := field.Object + "." + field.Field.Name
+	resolverCalls.WithLabelValues(name).Inc()
+
+	result, err := next(ctx)
+	if err != nil {
+		resolverErrors.WithLabelValues(name).Inc()
+	}
+	return result, err
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"database/sql"
+	"log"
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// namespace prefixes the name of every metric of the server
+const namespace = "timeline"
+
+var (
+	llmCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_calls_total",
+		Help:      "Language model calls by model, operation and result.",
+	}, []string{"model", "operation", "result"})
+
+	llmCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_call_duration_seconds",
+		Help:      "Latency of language model calls.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+	}, []string{"model", "operation"})
+
+	llmTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "Tokens used by language model calls, by model and type (prompt or completion).",
+	}, []string{"model", "type"})
+)
+
+// ObserveLLMCall records the latency, token usage and result of a language model call
+func ObserveLLMCall(operation, model string, latency time.Duration, promptTokens, completionTokens int, callErr error) {
+	result := "success"
+	if callErr != nil {
+		result = "failure"
+	}
+
+	llmCalls.WithLabelValues(model, operation, result).Inc()
+	llmCallDuration.WithLabelValues(model, operation).Observe(latency.Seconds())
+	llmTokens.WithLabelValues(model, "prompt").Add(float64(promptTokens))
+	llmTokens.WithLabelValues(model, "completion").Add(float64(completionTokens))
+}
+
+// RegisterDB reports the connection pool statistics of db
+func RegisterDB(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterQueue reports the number of items waiting in a background worker's queue. depth is called
+// each time metrics are scraped; when it fails, the depth is reported as NaN.
+func RegisterQueue(queue string, depth func() (int, error)) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "queue_depth",
+		Help:        "Items waiting in the queues of background workers.",
+		ConstLabels: prometheus.Labels{"queue": queue},
+	}, func() float64 {
+		n, err := depth()
+		if err != nil {
+			log.Printf("failed to measure the depth of the %s queue: %v", queue, err)
+			return math.NaN()
+		}
+		return float64(n)
+	})
+}
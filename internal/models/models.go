@@ -1,29 +1,107 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
+
+	"github.com/jukemori/timeline-generator/internal/clock"
 )
 
+// Organization is a tenant: it owns its users and their goals, and nothing in one organization is
+// visible to another
+type Organization struct {
+	ID        string               `json:"id"`
+	Name      string               `json:"name"`
+	Settings  OrganizationSettings `json:"settings"`
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// OrganizationSettings overrides server defaults for an organization. Empty and nil settings use the defaults.
+type OrganizationSettings struct {
+	// DefaultModel is the model timelines are generated with
+	DefaultModel string `json:"default_model,omitempty"`
+	// PromptTemplate is a text/template for the generation prompt
+	PromptTemplate string `json:"prompt_template,omitempty"`
+	// MonthlyQuota is the monthly generation quota of users without a quota of their own
+	MonthlyQuota *int `json:"monthly_quota,omitempty"`
+}
+
 // User represents a user in the system
 type User struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	TimeZone       string    `json:"time_zone"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // Goal represents a user's goal
 type Goal struct {
-	ID           string    `json:"id"`
-	UserID       string    `json:"user_id"`
-	Title        string    `json:"title"`
-	Description  string    `json:"description"`
-	CurrentLevel string    `json:"current_level"`
-	TargetLevel  string    `json:"target_level"`
-	StartDate    time.Time `json:"start_date"`
-	TargetDate   time.Time `json:"target_date"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	UserID         string    `json:"user_id"`
+	Title          string    `json:"title"`
+	Description    string    `json:"description"`
+	CurrentLevel   string    `json:"current_level"`
+	TargetLevel    string    `json:"target_level"`
+	StartDate      time.Time `json:"start_date"`
+	TargetDate     time.Time `json:"target_date"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Goal roles, from least to most access. The owner is the user the goal belongs to; the other roles
+// are granted by sharing the goal. Commenters can do everything viewers can and also comment.
+const (
+	GoalRoleViewer    = "viewer"
+	GoalRoleCommenter = "commenter"
+	GoalRoleEditor    = "editor"
+	GoalRoleOwner     = "owner"
+)
+
+// goalRoleRanks orders goal roles by how much access they grant
+var goalRoleRanks = map[string]int{
+	GoalRoleViewer:    1,
+	GoalRoleCommenter: 2,
+	GoalRoleEditor:    3,
+	GoalRoleOwner:     4,
+}
+
+// GoalRoleAllows reports whether a role grants at least the access of the required role.
+// The empty role, held by users the goal is not shared with, grants nothing.
+func GoalRoleAllows(role, required string) bool {
+	return goalRoleRanks[role] > 0 && goalRoleRanks[role] >= goalRoleRanks[required]
+}
+
+// IsShareableGoalRole reports whether a role can be granted by sharing a goal
+func IsShareableGoalRole(role string) bool {
+	return role == GoalRoleViewer || role == GoalRoleCommenter || role == GoalRoleEditor
+}
+
+// GoalMember is a user with access to a goal, including its owner
+type GoalMember struct {
+	GoalID    string    `json:"goal_id"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GoalInvitation is a pending invitation for whoever signs in with an email address to join a goal
+type GoalInvitation struct {
+	ID        string `json:"id"`
+	GoalID    string `json:"goal_id"`
+	GoalTitle string `json:"goal_title"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	// InvitedBy is the email address of the user who sent the invitation
+	InvitedBy string    `json:"invited_by"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // Timeline represents a timeline for a goal
@@ -47,11 +125,75 @@ type TimelineTask struct {
 	Description string    `json:"description"`
 	StartDate   time.Time `json:"start_date"`
 	EndDate     time.Time `json:"end_date"`
-	Duration    string    `json:"duration"`
+	Duration    Duration  `json:"duration"`
 	Priority    int       `json:"priority"`
 	Completed   bool      `json:"completed"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	// EffortHours is how many hours of work the task needs. Zero when it was never estimated.
+	EffortHours float64 `json:"effort_hours"`
+	// Recurrence is an RRULE such as "FREQ=WEEKLY;BYDAY=SA" for tasks that repeat between their
+	// start and end dates, or empty for one-off tasks. The duration of a recurring task is that of
+	// each occurrence, while its effort is the total across all of them.
+	Recurrence string    `json:"recurrence,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// IsRecurring reports whether the task repeats
+func (t *TimelineTask) IsRecurring() bool {
+	return t.Recurrence != ""
+}
+
+// TaskOccurrence is one occurrence of a recurring task
+type TaskOccurrence struct {
+	TaskID      string     `json:"task_id"`
+	Date        time.Time  `json:"date"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Streak summarizes how consistently a recurring task has been done
+type Streak struct {
+	// Current is how many occurrences in a row have been done up to today
+	Current int `json:"current"`
+	// Longest is the most occurrences ever done in a row
+	Longest              int `json:"longest"`
+	CompletedOccurrences int `json:"completed_occurrences"`
+	// DueOccurrences are the occurrences up to and including today
+	DueOccurrences   int `json:"due_occurrences"`
+	TotalOccurrences int `json:"total_occurrences"`
+}
+
+// Duration units
+const (
+	DurationHours = "hours"
+	DurationDays  = "days"
+)
+
+// HoursPerDay is how many hours of work a day of effort stands for
+const HoursPerDay = 8
+
+// Duration is a task's effort estimate, normalized to hours or days,
+// along with the human-readable label it was given
+type Duration struct {
+	Label string  `json:"label"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// Days returns the estimate in days
+func (d Duration) Days() float64 {
+	if d.Unit == DurationHours {
+		return d.Value / HoursPerDay
+	}
+	return d.Value
+}
+
+// Hours returns the estimate in hours
+func (d Duration) Hours() float64 {
+	if d.Unit == DurationHours {
+		return d.Value
+	}
+	return d.Value * HoursPerDay
 }
 
 // TimelineInput represents input for generating a timeline
@@ -61,4 +203,446 @@ type TimelineInput struct {
 	Objectives   string `json:"objectives"`
 	CurrentDate  string `json:"current_date"`
 	TargetDate   string `json:"target_date,omitempty"`
-}
\ No newline at end of file
+}
+
+// Validate checks the input names a goal, a current level and a current date, and that its dates are
+// in the clock.DateLayout layout with the target date, when given, after the current date
+func (i TimelineInput) Validate() error {
+	if i.Goal == "" || i.CurrentLevel == "" || i.CurrentDate == "" {
+		return errors.New("goal, current_level and current_date are required")
+	}
+	currentDate, err := time.Parse(clock.DateLayout, i.CurrentDate)
+	if err != nil {
+		return fmt.Errorf("invalid current_date %q", i.CurrentDate)
+	}
+	if i.TargetDate == "" {
+		return nil
+	}
+	targetDate, err := time.Parse(clock.DateLayout, i.TargetDate)
+	if err != nil {
+		return fmt.Errorf("invalid target_date %q", i.TargetDate)
+	}
+	if !targetDate.After(currentDate) {
+		return errors.New("target_date must be after current_date")
+	}
+	return nil
+}
+
+// User roles. Admins administer their own organization.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// IsAdmin reports whether the user has the admin role
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// IsUserRole reports whether a role is one users can have
+func IsUserRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
+
+// DefaultTimeZone is the time zone of users who have not chosen one
+const DefaultTimeZone = "UTC"
+
+// Location returns the user's time zone, in which "today" is evaluated for them
+func (u *User) Location() *time.Location {
+	return clock.Location(u.TimeZone)
+}
+
+// GenerationUsage represents the number of timeline generations a user made in a month
+type GenerationUsage struct {
+	UserID       string    `json:"user_id"`
+	Period       string    `json:"period"`
+	Generations  int       `json:"generations"`
+	MonthlyQuota int       `json:"monthly_quota"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Remaining returns how many generations are left in the period
+func (u *GenerationUsage) Remaining() int {
+	if u.Generations >= u.MonthlyQuota {
+		return 0
+	}
+	return u.MonthlyQuota - u.Generations
+}
+
+// LLMUsage records a single call to the language model
+type LLMUsage struct {
+	ID               string    `json:"id"`
+	UserID           string    `json:"user_id,omitempty"`
+	GoalID           string    `json:"goal_id,omitempty"`
+	TimelineID       string    `json:"timeline_id,omitempty"`
+	Operation        string    `json:"operation"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+	EstimatedCost    float64   `json:"estimated_cost"`
+	Success          bool      `json:"success"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// TotalTokens returns the number of prompt and completion tokens used
+func (u *LLMUsage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// LLMUsageSummary aggregates language model calls sharing a grouping key
+type LLMUsageSummary struct {
+	Key              string  `json:"key"`
+	Calls            int     `json:"calls"`
+	Failures         int     `json:"failures"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	EstimatedCost    float64 `json:"estimated_cost"`
+	AverageLatencyMs float64 `json:"average_latency_ms"`
+}
+
+// LLM operations recorded in usage
+const (
+	OperationGenerateTimeline = "generate_timeline"
+)
+
+// Generation job statuses
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// GenerationJob is a queued request to generate a timeline in the background
+type GenerationJob struct {
+	ID          string        `json:"id"`
+	UserID      string        `json:"user_id"`
+	Status      string        `json:"status"`
+	Input       TimelineInput `json:"input"`
+	Attempts    int           `json:"attempts"`
+	MaxAttempts int           `json:"max_attempts"`
+	LastError   string        `json:"last_error,omitempty"`
+	TimelineID  string        `json:"timeline_id,omitempty"`
+	RunAt       time.Time     `json:"run_at"`
+	StartedAt   *time.Time    `json:"started_at,omitempty"`
+	FinishedAt  *time.Time    `json:"finished_at,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// Finished reports whether the job has reached a final status
+func (j *GenerationJob) Finished() bool {
+	return j.Status == JobStatusSucceeded || j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
+
+// WebhookSubscription is an endpoint that receives a user's events, or those of all users of an
+// organization when it has no user
+type WebhookSubscription struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	UserID         string    `json:"user_id,omitempty"`
+	URL            string    `json:"url"`
+	Secret         string    `json:"-"`
+	EventTypes     []string  `json:"event_types"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// OrganizationWide reports whether the subscription receives the events of all users of its organization
+func (s *WebhookSubscription) OrganizationWide() bool {
+	return s.UserID == ""
+}
+
+// Accepts reports whether the subscription wants events of the given type
+func (s *WebhookSubscription) Accepts(eventType string) bool {
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryStatusPending    = "pending"
+	DeliveryStatusDelivering = "delivering"
+	DeliveryStatusSucceeded  = "succeeded"
+	DeliveryStatusFailed     = "failed"
+)
+
+// WebhookDelivery is an attempt to send an event to a webhook subscription
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Reminder kinds
+const (
+	ReminderStarts  = "starts"
+	ReminderDue     = "due"
+	ReminderOverdue = "overdue"
+)
+
+// ReminderSettings configures when a user is reminded about their tasks
+type ReminderSettings struct {
+	UserID        string `json:"user_id"`
+	Enabled       bool   `json:"enabled"`
+	StartLeadDays []int  `json:"start_lead_days"`
+	DueLeadDays   []int  `json:"due_lead_days"`
+	Overdue       bool   `json:"overdue"`
+	// CreatedAt and UpdatedAt are nil while the defaults are in effect
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// DefaultReminderSettings returns the settings used for users who have not configured reminders
+func DefaultReminderSettings(userID string) *ReminderSettings {
+	return &ReminderSettings{
+		UserID:        userID,
+		Enabled:       true,
+		StartLeadDays: []int{1},
+		DueLeadDays:   []int{2},
+		Overdue:       true,
+	}
+}
+
+// TaskReminder is a reminder that has been sent about a task
+type TaskReminder struct {
+	TaskID   string    `json:"task_id"`
+	UserID   string    `json:"user_id"`
+	Kind     string    `json:"kind"`
+	LeadDays int       `json:"lead_days"`
+	DueOn    time.Time `json:"due_on"`
+	Message  string    `json:"message"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// GoalProgress summarizes how many of a goal's tasks are done
+type GoalProgress struct {
+	Goal           Goal `json:"goal"`
+	TotalTasks     int  `json:"total_tasks"`
+	CompletedTasks int  `json:"completed_tasks"`
+}
+
+// Completion returns the fraction of tasks completed, between 0 and 1
+func (p *GoalProgress) Completion() float64 {
+	if p.TotalTasks == 0 {
+		return 0
+	}
+	return float64(p.CompletedTasks) / float64(p.TotalTasks)
+}
+
+// Expected returns the fraction of the goal's time span that has elapsed by the civil date today, between 0 and 1
+func (p *GoalProgress) Expected(today time.Time) float64 {
+	total := p.Goal.TargetDate.Sub(p.Goal.StartDate)
+	if total <= 0 {
+		return 1
+	}
+	elapsed := today.Sub(p.Goal.StartDate)
+	switch {
+	case elapsed <= 0:
+		return 0
+	case elapsed >= total:
+		return 1
+	default:
+		return float64(elapsed) / float64(total)
+	}
+}
+
+// NotificationPreferences controls which emails a user receives
+type NotificationPreferences struct {
+	UserID           string     `json:"user_id"`
+	OrganizationID   string     `json:"organization_id"`
+	Email            string     `json:"email"`
+	EmailReminders   bool       `json:"email_reminders"`
+	WeeklyDigest     bool       `json:"weekly_digest"`
+	DigestDay        int        `json:"digest_day"`
+	TimeZone         string     `json:"time_zone"`
+	UnsubscribeToken string     `json:"-"`
+	LastDigestAt     *time.Time `json:"last_digest_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// Availability describes when a user can work on their tasks
+type Availability struct {
+	UserID string `json:"user_id"`
+	// WorkingDays are the weekdays the user works on tasks
+	WorkingDays []time.Weekday `json:"working_days"`
+	HoursPerDay float64        `json:"hours_per_day"`
+	// WeeklyCapacity caps the hours worked in a week. Zero means no cap beyond the working days.
+	WeeklyCapacity float64 `json:"weekly_capacity"`
+	// HolidaySet is the code of the bundled holiday calendar the user observes, if any
+	HolidaySet    string      `json:"holiday_set"`
+	BlackoutDates []time.Time `json:"blackout_dates"`
+	// CreatedAt and UpdatedAt are nil while the defaults are in effect
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Capacity returns how many hours the user can work in a week
+func (a *Availability) Capacity() float64 {
+	dailyHours := a.HoursPerDay * float64(len(a.WorkingDays))
+	if a.WeeklyCapacity > 0 && a.WeeklyCapacity < dailyHours {
+		return a.WeeklyCapacity
+	}
+	return dailyHours
+}
+
+// DefaultAvailability returns the availability used for users who have not configured one:
+// two hours on weekdays, with no holidays
+func DefaultAvailability(userID string) *Availability {
+	return &Availability{
+		UserID:        userID,
+		WorkingDays:   []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		HoursPerDay:   2,
+		BlackoutDates: []time.Time{},
+	}
+}
+
+// GoalTemplate is a reusable timeline skeleton whose tasks are placed by day offsets from the
+// start of the plan rather than by dates
+type GoalTemplate struct {
+	ID string `json:"id"`
+	// UserID is the user who saved the template, or empty for a curated template
+	UserID       string         `json:"user_id,omitempty"`
+	Title        string         `json:"title"`
+	Description  string         `json:"description"`
+	Goal         string         `json:"goal"`
+	CurrentLevel string         `json:"current_level"`
+	TargetLevel  string         `json:"target_level"`
+	SpanDays     int            `json:"span_days"`
+	Tasks        []TemplateTask `json:"tasks"`
+	CreatedAt    *time.Time     `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time     `json:"updated_at,omitempty"`
+}
+
+// IsCurated reports whether the template is bundled with the app rather than saved by a user
+func (t *GoalTemplate) IsCurated() bool {
+	return t.UserID == ""
+}
+
+// TemplateTask is a task in a goal template.
+// Like a generated task, a recurring task's duration and effort are per occurrence.
+type TemplateTask struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// StartOffset is how many days after the start of the plan the task starts
+	StartOffset int      `json:"start_offset"`
+	SpanDays    int      `json:"span_days"`
+	Duration    Duration `json:"duration"`
+	EffortHours float64  `json:"effort_hours"`
+	Recurrence  string   `json:"recurrence,omitempty"`
+	Priority    int      `json:"priority"`
+}
+
+// Comment is a comment on a goal or on one of its tasks. Replies to a comment are on the same goal or task.
+type Comment struct {
+	ID     string `json:"id"`
+	GoalID string `json:"goal_id"`
+	// TaskID is the task commented on, or empty for a comment on the goal itself
+	TaskID string `json:"task_id,omitempty"`
+	// ParentID is the comment this one replies to, or empty for the start of a thread
+	ParentID    string `json:"parent_id,omitempty"`
+	UserID      string `json:"user_id"`
+	AuthorEmail string `json:"author_email"`
+	Body        string `json:"body"`
+	// Mentions are the IDs of the users with access to the goal that the body mentions
+	Mentions []string   `json:"mentions"`
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+	// DeletedAt is when the comment was deleted. A deleted comment has no body or mentions and is
+	// kept only so that its replies stay in their thread.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Replies   []*Comment `json:"replies,omitempty"`
+}
+
+// Activity kinds. Created, rescheduled, completed and commented make up a timeline's activity feed;
+// edited and deleted comments are only sent to live subscribers.
+const (
+	ActivityCreated        = "created"
+	ActivityRescheduled    = "rescheduled"
+	ActivityCompleted      = "completed"
+	ActivityCommented      = "commented"
+	ActivityCommentEdited  = "comment_edited"
+	ActivityCommentDeleted = "comment_deleted"
+)
+
+// ActivityItem is something that happened on a timeline, or a comment on it
+type ActivityItem struct {
+	ID         string `json:"id"`
+	TimelineID string `json:"timeline_id"`
+	// TaskID is the task the activity concerns, if any
+	TaskID string `json:"task_id,omitempty"`
+	// UserID is the user who acted, or empty when the app did
+	UserID string `json:"user_id,omitempty"`
+	Kind   string `json:"kind"`
+	// Comment is set for comment activity
+	Comment    *Comment  `json:"comment,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Audit actions
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditEntry records a change to a row: who made it, how, and its values before and after.
+// Entries are append-only and outlive the rows and users they mention.
+type AuditEntry struct {
+	ID             string `json:"id"`
+	OrganizationID string `json:"organization_id,omitempty"`
+	// ActorID is the user who made the change, or empty when the system did
+	ActorID string `json:"actor_id,omitempty"`
+	// Operation is the GraphQL mutation, HTTP request or background job that made the change
+	Operation  string `json:"operation"`
+	Action     string `json:"action"`
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	// Before and After are the row's columns as JSON objects, nil when the row did not exist
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	IPAddress string          `json:"ip_address,omitempty"`
+	UserAgent string          `json:"user_agent,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Kinds of items in the trash
+const (
+	TrashGoal     = "goal"
+	TrashTimeline = "timeline"
+	TrashTask     = "task"
+)
+
+// TrashItem is a deleted goal, timeline or task that can be restored until it is purged.
+// Timelines and tasks deleted along with their goal or timeline are restored with it and are not
+// items of their own.
+type TrashItem struct {
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// GoalID is the goal the item belongs to, or the goal itself
+	GoalID string `json:"goal_id"`
+	// TimelineID is the timeline a task belongs to, or the timeline itself; empty for goals
+	TimelineID string    `json:"timeline_id,omitempty"`
+	DeletedAt  time.Time `json:"deleted_at"`
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+)
+
+// UnsubscribeHandler serves the unsubscribe links in notification emails.
+// GET, which is what a user clicking the link sends, only asks to confirm, since mail scanners and link
+// previews follow links too. POST unsubscribes; it is what the confirmation form and the one-click
+// unsubscribe of mail clients send.
+func (n *Notifier) UnsubscribeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.URL.Query().Get("token")
+		list := r.URL.Query().Get("list")
+		if token == "" {
+			http.Error(w, "missing unsubscribe token", http.StatusBadRequest)
+			return
+		}
+
+		description := "all notification emails"
+		switch list {
+		case ListReminders:
+			description = "task reminder emails"
+		case ListDigest:
+			description = "the weekly progress digest"
+		case ListAll, "":
+		default:
+			http.Error(w, "unknown mailing list", http.StatusBadRequest)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			preferences, err := n.preferencesRepo.GetByUnsubscribeToken(r.Context(), token)
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "invalid unsubscribe token", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("failed to load unsubscribe token: %v", err)
+				http.Error(w, "failed to unsubscribe", http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprintf(w, `<!DOCTYPE html><html><body><form method="post" action="%s">
+<p>Unsubscribe %s from %s?</p><button type="submit">Unsubscribe</button></form></body></html>`,
+				html.EscapeString(r.URL.RequestURI()), html.EscapeString(preferences.Email), description)
+			return
+		}
+
+		preferences, err := n.Unsubscribe(r.Context(), token, list)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "invalid unsubscribe token", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to unsubscribe: %v", err)
+			http.Error(w, "failed to unsubscribe", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<!DOCTYPE html><html><body><p>%s has been unsubscribed from %s.</p></body></html>",
+			html.EscapeString(preferences.Email), description)
+	})
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/database/databasetest"
+)
+
+// openPreferences stands in for the database holding the preferences of the user with the token "token"
+func openPreferences(t *testing.T) *databasetest.DB {
+	db := databasetest.Open(t)
+	created := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	db.Rows("p.unsubscribe_token = ?", []string{
+		"id", "organization_id", "email", "time_zone", "email_reminders", "weekly_digest", "digest_day",
+		"unsubscribe_token", "last_digest_at", "created_at", "updated_at",
+	},
+		[]driver.Value{"user-1", "org-1", "user@example.com", "UTC", true, true, int64(1), "token", nil, created, created},
+	)
+	db.Rows("SELECT * FROM notification_preferences", []string{"user_id"})
+	db.Exec("INSERT INTO notification_preferences", 1)
+	db.Exec("INSERT INTO audit_log", 1)
+	return db
+}
+
+func TestUnsubscribeHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		target        string
+		wantStatus    int
+		wantBody      string
+		wantReminders bool
+		wantDigest    bool
+		wantSaved     bool
+	}{
+		{
+			name:       "GET asks to confirm",
+			method:     http.MethodGet,
+			target:     "/notifications/unsubscribe?token=token&list=digest",
+			wantStatus: http.StatusOK,
+			wantBody:   `<form method="post" action="/notifications/unsubscribe?token=token&amp;list=digest">`,
+		},
+		{
+			name:          "POST unsubscribes from the list",
+			method:        http.MethodPost,
+			target:        "/notifications/unsubscribe?token=token&list=digest",
+			wantStatus:    http.StatusOK,
+			wantBody:      "user@example.com has been unsubscribed from the weekly progress digest",
+			wantReminders: true,
+			wantSaved:     true,
+		},
+		{
+			name:       "POST unsubscribes from everything",
+			method:     http.MethodPost,
+			target:     "/notifications/unsubscribe?token=token",
+			wantStatus: http.StatusOK,
+			wantBody:   "unsubscribed from all notification emails",
+			wantSaved:  true,
+		},
+		{
+			name:       "unknown list",
+			method:     http.MethodPost,
+			target:     "/notifications/unsubscribe?token=token&list=news",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing token",
+			method:     http.MethodGet,
+			target:     "/notifications/unsubscribe?list=digest",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "other method",
+			method:     http.MethodDelete,
+			target:     "/notifications/unsubscribe?token=token",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openPreferences(t)
+			handler := NewNotifier(nil, clock.System, Config{}).UnsubscribeHandler()
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body, tt.wantBody)
+			}
+
+			var saves []databasetest.Statement
+			for _, statement := range db.Execs() {
+				if strings.Contains(statement.Query, "INSERT INTO notification_preferences") {
+					saves = append(saves, statement)
+				}
+			}
+			if got := len(saves) == 1; got != tt.wantSaved {
+				t.Fatalf("saved preferences %d times, want saved = %v", len(saves), tt.wantSaved)
+			}
+			if tt.wantSaved {
+				if saves[0].Args[1] != tt.wantReminders || saves[0].Args[2] != tt.wantDigest {
+					t.Errorf("saved reminders = %v and digest = %v, want %v and %v",
+						saves[0].Args[1], saves[0].Args[2], tt.wantReminders, tt.wantDigest)
+				}
+			}
+		})
+	}
+}
+
+func TestUnsubscribeHandlerUnknownToken(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		t.Run(method, func(t *testing.T) {
+			databasetest.Open(t).Rows("p.unsubscribe_token = ?", nil)
+			handler := NewNotifier(nil, clock.System, Config{}).UnsubscribeHandler()
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(method, "/notifications/unsubscribe?token=unknown", nil))
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+			}
+		})
+	}
+}
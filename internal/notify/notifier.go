@@ -0,0 +1,503 @@
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/audit"
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// Mailing lists a user can unsubscribe from
+const (
+	ListReminders = "reminders"
+	ListDigest    = "digest"
+	ListAll       = "all"
+)
+
+// Config configures the notifier
+type Config struct {
+	// PublicURL is the externally reachable base URL of the API, used in unsubscribe links
+	PublicURL string
+	// DigestInterval is how often the notifier checks for weekly digests that are due
+	DigestInterval time.Duration
+}
+
+// Notifier emails task reminders, goal invitations, comment mentions and weekly progress digests
+type Notifier struct {
+	sender          Sender
+	config          Config
+	clock           clock.Clock
+	preferencesRepo *repository.NotificationRepository
+	taskRepo        *repository.TaskRepository
+	goalRepo        *repository.GoalRepository
+	queue           chan events.Event
+	wg              sync.WaitGroup
+}
+
+// NewNotifier creates a new Notifier
+func NewNotifier(sender Sender, clk clock.Clock, config Config) *Notifier {
+	return &Notifier{
+		sender:          sender,
+		config:          config,
+		clock:           clk,
+		preferencesRepo: repository.NewNotificationRepository(),
+		taskRepo:        repository.NewTaskRepository(),
+		goalRepo:        repository.NewGoalRepository(),
+		queue:           make(chan events.Event, 256),
+	}
+}
+
+// Handle queues reminder, invitation and mention events to be emailed. It is an events.Handler and never blocks the publisher.
+func (n *Notifier) Handle(ctx context.Context, event events.Event) {
+	if event.Type != events.TaskReminder && event.Type != events.GoalInvitation && event.Type != events.CommentMention {
+		return
+	}
+
+	select {
+	case n.queue <- event:
+	default:
+		log.Printf("notification queue full, dropping %s email for event %s", event.Type, event.ID)
+	}
+}
+
+// Depth counts the emails waiting to be sent
+func (n *Notifier) Depth() int {
+	return len(n.queue)
+}
+
+// Start launches the email and digest workers. They stop when ctx is cancelled; use Wait to block until they have.
+func (n *Notifier) Start(ctx context.Context) {
+	n.wg.Add(2)
+	go func() {
+		defer n.wg.Done()
+		n.sendEmails(ctx)
+	}()
+	go func() {
+		defer n.wg.Done()
+		n.sendDigests(ctx)
+	}()
+}
+
+// Wait blocks until all workers have stopped
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+// Preferences gets a user's notification preferences, saving the defaults the first time
+// so the user has an unsubscribe token
+func (n *Notifier) Preferences(ctx context.Context, userID string) (*models.NotificationPreferences, error) {
+	preferences, err := n.preferencesRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if preferences.UnsubscribeToken == "" {
+		token, err := newToken()
+		if err != nil {
+			return nil, err
+		}
+		preferences.UnsubscribeToken = token
+		if err := n.preferencesRepo.SavePreferences(ctx, preferences); err != nil {
+			return nil, err
+		}
+		preferences.CreatedAt = preferences.UpdatedAt
+	}
+
+	return preferences, nil
+}
+
+// SavePreferences updates a user's notification preferences
+func (n *Notifier) SavePreferences(ctx context.Context, preferences *models.NotificationPreferences) error {
+	if preferences.DigestDay < int(time.Sunday) || preferences.DigestDay > int(time.Saturday) {
+		return fmt.Errorf("digest day must be between 0 (Sunday) and 6 (Saturday)")
+	}
+	return n.preferencesRepo.SavePreferences(ctx, preferences)
+}
+
+// Unsubscribe turns off a mailing list for the user the token belongs to
+func (n *Notifier) Unsubscribe(ctx context.Context, token, list string) (*models.NotificationPreferences, error) {
+	preferences, err := n.preferencesRepo.GetByUnsubscribeToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch list {
+	case ListReminders:
+		preferences.EmailReminders = false
+	case ListDigest:
+		preferences.WeeklyDigest = false
+	case ListAll, "":
+		preferences.EmailReminders = false
+		preferences.WeeklyDigest = false
+	default:
+		return nil, fmt.Errorf("unknown mailing list %q", list)
+	}
+
+	if err := n.preferencesRepo.SavePreferences(ctx, preferences); err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+// SendDigest emails a user their weekly progress digest as of now
+func (n *Notifier) SendDigest(ctx context.Context, userID string) error {
+	preferences, err := n.Preferences(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return n.sendDigest(ctx, preferences, n.clock.Now())
+}
+
+// sendEmails emails queued events until ctx is cancelled, then emails those still queued
+func (n *Notifier) sendEmails(ctx context.Context) {
+	ctx = audit.WithOperation(ctx, "email notifications")
+	for {
+		select {
+		case <-ctx.Done():
+			n.drain(context.WithoutCancel(ctx))
+			return
+		case event := <-n.queue:
+			n.sendEmail(ctx, event)
+		}
+	}
+}
+
+// drain emails the events left in the queue
+func (n *Notifier) drain(ctx context.Context) {
+	for {
+		select {
+		case event := <-n.queue:
+			n.sendEmail(ctx, event)
+		default:
+			return
+		}
+	}
+}
+
+// sendEmail emails a queued event
+func (n *Notifier) sendEmail(ctx context.Context, event events.Event) {
+	send := n.sendReminder
+	switch event.Type {
+	case events.GoalInvitation:
+		send = n.sendInvitation
+	case events.CommentMention:
+		send = n.sendMention
+	}
+	if err := send(ctx, event); err != nil {
+		log.Printf("failed to email %s for event %s: %v", event.Type, event.ID, err)
+	}
+}
+
+// sendReminder emails a single reminder if the user wants reminder emails
+func (n *Notifier) sendReminder(ctx context.Context, event events.Event) error {
+	data, ok := event.Data.(events.ReminderData)
+	if !ok {
+		return fmt.Errorf("unexpected reminder payload %T", event.Data)
+	}
+
+	preferences, err := n.Preferences(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+	if !preferences.EmailReminders {
+		return nil
+	}
+
+	unsubscribeURL := n.unsubscribeURL(preferences.UnsubscribeToken, ListReminders)
+	text, html, err := render("reminder", map[string]interface{}{
+		"Message":        data.Message,
+		"Task":           data.TaskData,
+		"UnsubscribeURL": unsubscribeURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	return n.sender.Send(Message{
+		To:             preferences.Email,
+		Subject:        "Reminder: " + data.Message,
+		Text:           text,
+		HTML:           html,
+		UnsubscribeURL: unsubscribeURL,
+	})
+}
+
+// sendInvitation emails a goal invitation to the invited address.
+// Invitations are sent whatever the notification preferences, since the invitee may not have an account yet.
+func (n *Notifier) sendInvitation(_ context.Context, event events.Event) error {
+	data, ok := event.Data.(events.InvitationData)
+	if !ok {
+		return fmt.Errorf("unexpected invitation payload %T", event.Data)
+	}
+
+	article := "a"
+	if data.Role == models.GoalRoleEditor {
+		article = "an"
+	}
+	text, html, err := render("invitation", map[string]interface{}{
+		"InvitedBy": data.InvitedBy,
+		"GoalTitle": data.GoalTitle,
+		"Email":     data.Email,
+		"Role":      data.Role,
+		"Article":   article,
+	})
+	if err != nil {
+		return err
+	}
+
+	return n.sender.Send(Message{
+		To:      data.Email,
+		Subject: fmt.Sprintf("%s shared \"%s\" with you", data.InvitedBy, data.GoalTitle),
+		Text:    text,
+		HTML:    html,
+	})
+}
+
+// sendMention emails a user mentioned in a comment, if they want reminder emails
+func (n *Notifier) sendMention(ctx context.Context, event events.Event) error {
+	data, ok := event.Data.(events.MentionData)
+	if !ok {
+		return fmt.Errorf("unexpected mention payload %T", event.Data)
+	}
+
+	preferences, err := n.Preferences(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+	if !preferences.EmailReminders {
+		return nil
+	}
+
+	goal, err := n.goalRepo.GetByID(ctx, preferences.OrganizationID, data.GoalID)
+	if err != nil {
+		return err
+	}
+	taskTitle := ""
+	if data.TaskID != "" {
+		task, err := n.taskRepo.GetByID(ctx, preferences.OrganizationID, data.TaskID)
+		if err != nil {
+			return err
+		}
+		taskTitle = task.Title
+	}
+
+	unsubscribeURL := n.unsubscribeURL(preferences.UnsubscribeToken, ListReminders)
+	text, html, err := render("mention", map[string]interface{}{
+		"AuthorEmail":    data.AuthorEmail,
+		"GoalTitle":      goal.Title,
+		"TaskTitle":      taskTitle,
+		"Body":           data.Body,
+		"UnsubscribeURL": unsubscribeURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	return n.sender.Send(Message{
+		To:             preferences.Email,
+		Subject:        fmt.Sprintf("%s mentioned you on \"%s\"", data.AuthorEmail, goal.Title),
+		Text:           text,
+		HTML:           html,
+		UnsubscribeURL: unsubscribeURL,
+	})
+}
+
+// sendDigests periodically emails weekly digests to users whose digest day it is
+func (n *Notifier) sendDigests(ctx context.Context) {
+	ctx = audit.WithOperation(ctx, "weekly digests")
+	ticker := time.NewTicker(n.config.DigestInterval)
+	defer ticker.Stop()
+
+	for {
+		n.sendDueDigests(ctx, n.clock.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDueDigests emails every digest due at now, for users whose digest day it is in their time zone.
+// A digest is claimed before it is sent, so each user gets at most one per week even with several servers
+// running, and the claim is given back when sending fails so that the next run tries again.
+func (n *Notifier) sendDueDigests(ctx context.Context, now time.Time) {
+	sentBefore := now.AddDate(0, 0, -6)
+	sentAt := now.Truncate(time.Second)
+
+	timeZones, err := n.preferencesRepo.GetDigestTimeZones(ctx, sentBefore)
+	if err != nil {
+		log.Printf("failed to load weekly digest time zones: %v", err)
+		return
+	}
+	weekdays := make(map[string]int, len(timeZones))
+	for _, timeZone := range timeZones {
+		weekdays[timeZone] = int(now.In(clock.Location(timeZone)).Weekday())
+	}
+
+	recipients, err := n.preferencesRepo.GetDigestRecipients(ctx, sentBefore, weekdays)
+	if err != nil {
+		log.Printf("failed to load weekly digest recipients: %v", err)
+		return
+	}
+
+	for _, recipient := range recipients {
+		preferences, err := n.Preferences(ctx, recipient.UserID)
+		if err != nil {
+			log.Printf("failed to load notification preferences for user %s: %v", recipient.UserID, err)
+			continue
+		}
+
+		claimed, err := n.preferencesRepo.MarkDigestSent(ctx, preferences.UserID, sentAt, sentBefore)
+		if err != nil {
+			log.Printf("failed to claim weekly digest for user %s: %v", preferences.UserID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := n.sendDigest(ctx, preferences, now); err != nil {
+			log.Printf("failed to email weekly digest to user %s: %v", preferences.UserID, err)
+			if err := n.preferencesRepo.UnmarkDigestSent(ctx, preferences.UserID, sentAt, preferences.LastDigestAt); err != nil {
+				log.Printf("failed to release weekly digest claim for user %s: %v", preferences.UserID, err)
+			}
+		}
+	}
+}
+
+// digestTask is a task line in the weekly digest
+type digestTask struct {
+	Title string
+	Date  string
+}
+
+// digestGoal is a goal line in the weekly digest
+type digestGoal struct {
+	Title           string
+	TargetDate      string
+	DaysLeft        int
+	CompletedTasks  int
+	TotalTasks      int
+	Percent         int
+	ExpectedPercent int
+	Status          string
+}
+
+// sendDigest builds and emails the digest for the week ending at now, with dates in the user's time zone
+func (n *Notifier) sendDigest(ctx context.Context, preferences *models.NotificationPreferences, now time.Time) error {
+	loc := clock.Location(preferences.TimeZone)
+	weekStart := now.AddDate(0, 0, -7)
+	today := clock.Date(now, loc)
+
+	completed, err := n.taskRepo.GetCompletedByUserID(ctx, preferences.OrganizationID, preferences.UserID, weekStart, now)
+	if err != nil {
+		return err
+	}
+	upcoming, err := n.taskRepo.GetUpcomingByUserID(ctx, preferences.OrganizationID, preferences.UserID, today, today.AddDate(0, 0, 7))
+	if err != nil {
+		return err
+	}
+	progress, err := n.goalRepo.GetProgressByUserID(ctx, preferences.OrganizationID, preferences.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to report for users who have not planned anything
+	if len(completed) == 0 && len(upcoming) == 0 && len(progress) == 0 {
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"WeekStart":      weekStart.In(loc).Format(clock.DateLayout),
+		"WeekEnd":        today.Format(clock.DateLayout),
+		"Completed":      digestTasks(completed, true, loc),
+		"Upcoming":       digestTasks(upcoming, false, loc),
+		"Goals":          digestGoals(progress, today),
+		"UnsubscribeURL": n.unsubscribeURL(preferences.UnsubscribeToken, ListDigest),
+	}
+
+	text, html, err := render("digest", data)
+	if err != nil {
+		return err
+	}
+
+	return n.sender.Send(Message{
+		To:             preferences.Email,
+		Subject:        fmt.Sprintf("Your weekly progress: %d tasks completed", len(completed)),
+		Text:           text,
+		HTML:           html,
+		UnsubscribeURL: data["UnsubscribeURL"].(string),
+	})
+}
+
+func digestTasks(tasks []repository.OwnedTask, completed bool, loc *time.Location) []digestTask {
+	result := make([]digestTask, len(tasks))
+	for i, task := range tasks {
+		// End dates are civil dates, completion times are instants shown in the user's zone
+		date := task.EndDate
+		if completed {
+			date = clock.Date(task.UpdatedAt, loc)
+		}
+		result[i] = digestTask{Title: task.Title, Date: date.Format(clock.DateLayout)}
+	}
+	return result
+}
+
+func digestGoals(progress []*models.GoalProgress, today time.Time) []digestGoal {
+	result := make([]digestGoal, len(progress))
+	for i, p := range progress {
+		percent := int(math.Round(p.Completion() * 100))
+		expected := int(math.Round(p.Expected(today) * 100))
+
+		status := "on track"
+		switch {
+		case percent >= 100:
+			status = "complete"
+		case percent+10 < expected:
+			status = "behind"
+		case percent > expected+10:
+			status = "ahead"
+		}
+
+		result[i] = digestGoal{
+			Title:           p.Goal.Title,
+			TargetDate:      p.Goal.TargetDate.Format(clock.DateLayout),
+			DaysLeft:        int(p.Goal.TargetDate.Sub(today).Hours() / 24),
+			CompletedTasks:  p.CompletedTasks,
+			TotalTasks:      p.TotalTasks,
+			Percent:         percent,
+			ExpectedPercent: expected,
+			Status:          status,
+		}
+	}
+	return result
+}
+
+// unsubscribeURL returns the link that turns off a mailing list without logging in
+func (n *Notifier) unsubscribeURL(token, list string) string {
+	query := url.Values{"token": {token}, "list": {list}}
+	return n.config.PublicURL + "/notifications/unsubscribe?" + query.Encode()
+}
+
+// newToken generates a random unsubscribe token
+func newToken() (string, error) {
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
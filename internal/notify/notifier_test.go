@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+func TestDigestGoals(t *testing.T) {
+	// Half the goal's time has passed, so half its tasks are expected done
+	goal := models.Goal{
+		Title:      "Goal",
+		StartDate:  time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		TargetDate: time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC),
+	}
+	today := time.Date(2024, time.March, 6, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		completed  int
+		wantStatus string
+	}{
+		{completed: 0, wantStatus: "behind"},
+		{completed: 39, wantStatus: "behind"},
+		{completed: 40, wantStatus: "on track"},
+		{completed: 50, wantStatus: "on track"},
+		{completed: 60, wantStatus: "on track"},
+		{completed: 61, wantStatus: "ahead"},
+		{completed: 100, wantStatus: "complete"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantStatus, func(t *testing.T) {
+			got := digestGoals([]*models.GoalProgress{{Goal: goal, TotalTasks: 100, CompletedTasks: tt.completed}}, today)[0]
+			if got.Status != tt.wantStatus {
+				t.Errorf("%d of 100 tasks done at 50%% of the time: status %q, want %q", tt.completed, got.Status, tt.wantStatus)
+			}
+			if got.Percent != tt.completed || got.ExpectedPercent != 50 || got.DaysLeft != 5 {
+				t.Errorf("digest goal = %+v, want %d%% done of 50%% expected with 5 days left", got, tt.completed)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeURL(t *testing.T) {
+	n := &Notifier{config: Config{PublicURL: "https://timeline.example.com"}, clock: clock.System}
+
+	tests := []struct {
+		token string
+		list  string
+		want  string
+	}{
+		{
+			token: "abc123",
+			list:  ListDigest,
+			want:  "https://timeline.example.com/notifications/unsubscribe?list=digest&token=abc123",
+		},
+		{
+			token: "a+b/c=",
+			list:  ListReminders,
+			want:  "https://timeline.example.com/notifications/unsubscribe?list=reminders&token=a%2Bb%2Fc%3D",
+		},
+		{
+			token: "x&list=all",
+			list:  ListReminders,
+			want:  "https://timeline.example.com/notifications/unsubscribe?list=reminders&token=x%26list%3Dall",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			if got := n.unsubscribeURL(tt.token, tt.list); got != tt.want {
+				t.Errorf("unsubscribeURL(%q, %q) = %s, want %s", tt.token, tt.list, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,66 +2,101 @@ package openai
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/jukemori/timeline-generator/graph/model"
+	"github.com/jukemori/timeline-generator/internal/tracing"
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Config configures the OpenAI client
+type Config struct {
+	APIKey string
+	// Model is used for completions that do not ask for another
+	Model       string
+	Temperature float32
+	// Timeout bounds each completion call
+	Timeout time.Duration
+}
+
 type Client struct {
 	client *openai.Client
+	config Config
 }
 
-func NewClient(apiKey string) *Client {
+func NewClient(config Config) *Client {
 	return &Client{
-		client: openai.NewClient(apiKey),
+		client: openai.NewClient(config.APIKey),
+		config: config,
 	}
 }
 
-func (c *Client) GenerateTimeline(ctx context.Context, input model.TimelineInput) (*model.Timeline, error) {
-	prompt := fmt.Sprintf(`
-Create a detailed timeline for achieving the following goal:
-
-Current Level: %s
-Goal: %s
-Objectives: %s
-Current Date: %s
-Target Date: %s
-
-Please provide a timeline with specific tasks, including:
-- Task title
-- Task description
-- Start date
-- End date
-- Duration (in days)
-- Priority level (1-5, with 5 being highest)
-
-Format your response as a JSON object with the following structure:
-{
-  "title": "Timeline title",
-  "description": "Overall timeline description",
-  "startDate": "YYYY-MM-DD",
-  "endDate": "YYYY-MM-DD",
-  "tasks": [
-    {
-      "title": "Task 1 title",
-      "description": "Task 1 description",
-      "startDate": "YYYY-MM-DD",
-      "endDate": "YYYY-MM-DD",
-      "duration": "X days",
-      "priority": 5
-    },
-    ...
-  ]
+// Configured reports whether the client has an API key to call OpenAI with
+func (c *Client) Configured() bool {
+	return c.config.APIKey != ""
+}
+
+// Model returns the name of the model used for completions that do not ask for another
+func (c *Client) Model() string {
+	return c.config.Model
+}
+
+// Completion is the result of a single chat completion call
+type Completion struct {
+	Content          string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	Latency          time.Duration
+}
+
+// TotalTokens returns the number of prompt and completion tokens used
+func (c *Completion) TotalTokens() int {
+	return c.PromptTokens + c.CompletionTokens
+}
+
+// EstimatedCost returns the estimated cost of the call in USD
+func (c *Completion) EstimatedCost() float64 {
+	return EstimateCost(c.Model, c.PromptTokens, c.CompletionTokens)
+}
+
+// GenerateCompletion sends a prompt to a model, or to the client's model when none is given, and returns
+// the JSON object in its reply.
+// The returned Completion is non-nil even when the call fails, so callers can account for it.
+func (c *Client) GenerateCompletion(ctx context.Context, model, prompt string) (*Completion, error) {
+	if model == "" {
+		model = c.config.Model
+	}
+
+	ctx, span := tracing.Start(ctx, "openai.chat_completion", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("llm.request.model", model)))
+	completion, err := c.generateCompletion(ctx, model, prompt)
+	span.SetAttributes(
+		attribute.String("llm.response.model", completion.Model),
+		attribute.Int("llm.usage.prompt_tokens", completion.PromptTokens),
+		attribute.Int("llm.usage.completion_tokens", completion.CompletionTokens),
+	)
+	tracing.End(span, err)
+	return completion, err
 }
-`, input.CurrentLevel, input.Goal, input.Objectives, input.CurrentDate, input.TargetDate)
 
+func (c *Client) generateCompletion(ctx context.Context, model, prompt string) (*Completion, error) {
+	completion := &Completion{Model: model}
+
+	if c.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+
+	started := time.Now()
 	resp, err := c.client.CreateChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
-			Model: openai.GPT3Dot5Turbo,
+			Model: model,
 			Messages: []openai.ChatCompletionMessage{
 				{
 					Role:    openai.ChatMessageRoleSystem,
@@ -72,59 +107,27 @@ Format your response as a JSON object with the following structure:
 					Content: prompt,
 				},
 			},
-			Temperature: 0.7,
+			Temperature: c.config.Temperature,
 		},
 	)
+	completion.Latency = time.Since(started)
 
 	if err != nil {
-		return nil, fmt.Errorf("OpenAI API error: %v", err)
-	}
-
-	content := resp.Choices[0].Message.Content
-
-	// Extract JSON from the response
-	content = extractJSON(content)
-
-	var timelineData struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		StartDate   string `json:"startDate"`
-		EndDate     string `json:"endDate"`
-		Tasks       []struct {
-			Title       string `json:"title"`
-			Description string `json:"description"`
-			StartDate   string `json:"startDate"`
-			EndDate     string `json:"endDate"`
-			Duration    string `json:"duration"`
-			Priority    int    `json:"priority"`
-		} `json:"tasks"`
-	}
-
-	if err := json.Unmarshal([]byte(content), &timelineData); err != nil {
-		return nil, fmt.Errorf("error parsing timeline JSON: %v", err)
+		return completion, fmt.Errorf("OpenAI API error: %w", err)
 	}
 
-	// Convert to our model
-	timeline := &model.Timeline{
-		Title:       timelineData.Title,
-		Description: timelineData.Description,
-		StartDate:   timelineData.StartDate,
-		EndDate:     timelineData.EndDate,
-		Tasks:       make([]*model.TimelineTask, len(timelineData.Tasks)),
+	if resp.Model != "" {
+		completion.Model = resp.Model
 	}
+	completion.PromptTokens = resp.Usage.PromptTokens
+	completion.CompletionTokens = resp.Usage.CompletionTokens
 
-	for i, task := range timelineData.Tasks {
-		timeline.Tasks[i] = &model.TimelineTask{
-			Title:       task.Title,
-			Description: task.Description,
-			StartDate:   task.StartDate,
-			EndDate:     task.EndDate,
-			Duration:    task.Duration,
-			Priority:    task.Priority,
-		}
+	if len(resp.Choices) == 0 {
+		return completion, fmt.Errorf("OpenAI API returned no choices")
 	}
 
-	return timeline, nil
+	completion.Content = extractJSON(resp.Choices[0].Message.Content)
+	return completion, nil
 }
 
 // Helper function to extract JSON from the response
@@ -0,0 +1,44 @@
+package openai
+
+import "strings"
+
+// Price is the cost of a model in USD per million tokens
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// prices lists known models by name prefix. Longer prefixes are matched first,
+// so dated snapshots such as "gpt-4o-mini-2024-07-18" resolve to their family.
+var prices = map[string]Price{
+	"gpt-3.5-turbo": {Prompt: 0.50, Completion: 1.50},
+	"gpt-4":         {Prompt: 30.00, Completion: 60.00},
+	"gpt-4-turbo":   {Prompt: 10.00, Completion: 30.00},
+	"gpt-4o":        {Prompt: 2.50, Completion: 10.00},
+	"gpt-4o-mini":   {Prompt: 0.15, Completion: 0.60},
+	"gpt-4.1":       {Prompt: 2.00, Completion: 8.00},
+	"gpt-4.1-mini":  {Prompt: 0.40, Completion: 1.60},
+}
+
+// PriceFor returns the price of a model and whether it is known
+func PriceFor(model string) (Price, bool) {
+	best := ""
+	for prefix := range prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return prices[best], true
+}
+
+// EstimateCost returns the estimated cost in USD of a call, or zero for unknown models
+func EstimateCost(model string, promptTokens, completionTokens int) float64 {
+	price, ok := PriceFor(model)
+	if !ok {
+		return 0
+	}
+	return (float64(promptTokens)*price.Prompt + float64(completionTokens)*price.Completion) / 1_000_000
+}
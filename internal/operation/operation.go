@@ -0,0 +1,21 @@
+// Package operation names GraphQL operations for metrics and traces
+package operation
+
+import (
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Name names an operation by its first root field, such as "generateTimeline". The operation name
+// a client sends is left out, since clients can choose any and each would become its own time series;
+// root fields are bounded by the schema, as the operation has been validated against it.
+func Name(opCtx *graphql.OperationContext) string {
+	if opCtx.Operation != nil {
+		for _, selection := range opCtx.Operation.SelectionSet {
+			if field, ok := selection.(*ast.Field); ok {
+				return field.Name
+			}
+		}
+	}
+	return "other"
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Error codes returned to API clients
+const (
+	CodeRateLimited   = "RATE_LIMITED"
+	CodeQuotaExceeded = "QUOTA_EXCEEDED"
+)
+
+// idleTTL is how long an unused bucket is kept before it is dropped
+const idleTTL = 10 * time.Minute
+
+// Error is returned when a caller has exceeded a limit
+type Error struct {
+	Code       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (retry after %s)", e.Message, e.RetryAfter.Round(time.Second))
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter is a set of token buckets keyed by caller
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	limit     rate.Limit
+	burst     int
+	lastSweep time.Time
+}
+
+// NewLimiter creates a Limiter that refills perMinute tokens a minute and holds at most burst tokens per key
+func NewLimiter(perMinute float64, burst int) *Limiter {
+	return &Limiter{
+		buckets:   make(map[string]*bucket),
+		limit:     rate.Limit(perMinute / 60),
+		burst:     burst,
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from the key's bucket.
+// When the bucket is empty it returns false and how long until a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+	l.sweep(now)
+	l.mu.Unlock()
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, time.Minute
+	}
+
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+
+	return true, 0
+}
+
+// sweep drops buckets that have not been used recently. The caller must hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTTL {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
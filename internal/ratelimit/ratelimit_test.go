@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	tests := []struct {
+		name           string
+		perMinute      float64
+		burst          int
+		calls          int
+		wantAllowed    int
+		wantRetryAfter time.Duration
+	}{
+		{name: "within the burst", perMinute: 60, burst: 3, calls: 3, wantAllowed: 3},
+		{name: "past the burst", perMinute: 60, burst: 2, calls: 3, wantAllowed: 2, wantRetryAfter: time.Second},
+		{name: "slow refill", perMinute: 1, burst: 1, calls: 2, wantAllowed: 1, wantRetryAfter: time.Minute},
+		{name: "no burst", perMinute: 60, burst: 0, calls: 1, wantAllowed: 0, wantRetryAfter: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(tt.perMinute, tt.burst)
+
+			allowed := 0
+			var retryAfter time.Duration
+			for i := 0; i < tt.calls; i++ {
+				ok, wait := l.Allow("user-1")
+				if ok {
+					allowed++
+				} else {
+					retryAfter = wait
+				}
+			}
+
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed %d of %d calls, want %d", allowed, tt.calls, tt.wantAllowed)
+			}
+			// The wait is until the next token, less the moments spent making the calls
+			if retryAfter > tt.wantRetryAfter || retryAfter < tt.wantRetryAfter-100*time.Millisecond {
+				t.Errorf("retry after %v, want about %v", retryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestAllowKeysSeparately(t *testing.T) {
+	l := NewLimiter(1, 1)
+	if ok, _ := l.Allow("user-1"); !ok {
+		t.Fatal("first call of user-1 refused")
+	}
+	if ok, _ := l.Allow("user-2"); !ok {
+		t.Error("first call of user-2 refused after user-1 used up their bucket")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l := NewLimiter(1, 1)
+	l.Allow("idle")
+	l.Allow("active")
+
+	// Pretend the idle bucket was last used long ago and the last sweep was due
+	now := time.Now()
+	l.buckets["idle"].lastSeen = now.Add(-2 * idleTTL)
+	l.lastSweep = now.Add(-idleTTL)
+
+	// The active bucket keeps its empty state, so it is not refilled by the sweep
+	if ok, _ := l.Allow("active"); ok {
+		t.Error("active bucket was refilled, want it kept")
+	}
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("idle bucket was kept, want it dropped")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("active bucket was dropped, want it kept")
+	}
+
+	// Between sweeps nothing is dropped, however idle
+	l.buckets["active"].lastSeen = now.Add(-2 * idleTTL)
+	l.Allow("other")
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("bucket dropped before the next sweep was due")
+	}
+}
@@ -0,0 +1,155 @@
+package recurrence
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/teambition/rrule-go"
+)
+
+// Normalize validates an RRULE such as "FREQ=WEEKLY;BYDAY=SA" and returns it in canonical form.
+// A leading "RRULE:" is accepted. The rule must not carry its own DTSTART, since occurrences start
+// on the task's start date, and it must repeat at most daily, since tasks are scheduled on dates.
+func Normalize(rule string) (string, error) {
+	option, err := parse(rule)
+	if err != nil {
+		return "", err
+	}
+	return option.RRuleString(), nil
+}
+
+// Unbounded returns a rule without its UNTIL or COUNT, so it repeats for as long as whatever task it is
+// given to, such as one instantiated from a template
+func Unbounded(rule string) (string, error) {
+	option, err := parse(rule)
+	if err != nil {
+		return "", err
+	}
+	option.Count = 0
+	option.Until = time.Time{}
+	return option.RRuleString(), nil
+}
+
+// Dates returns the dates a recurring task occurs on between from and to, counting both ends.
+// Occurrences start on the task's start date and never fall after its end date.
+func Dates(task *models.TimelineTask, from, to time.Time) ([]time.Time, error) {
+	r, err := newRule(task)
+	if err != nil {
+		return nil, err
+	}
+	return r.Between(truncate(from), truncate(to), true), nil
+}
+
+// All returns every date a recurring task occurs on
+func All(task *models.TimelineTask) ([]time.Time, error) {
+	r, err := newRule(task)
+	if err != nil {
+		return nil, err
+	}
+	return r.All(), nil
+}
+
+// Occurs reports whether a recurring task has an occurrence on the date
+func Occurs(task *models.TimelineTask, date time.Time) (bool, error) {
+	dates, err := Dates(task, date, date)
+	if err != nil {
+		return false, err
+	}
+	return len(dates) > 0, nil
+}
+
+// Export returns a task's rule as it goes in an iCalendar event starting on the task's start date.
+// The rule is bounded by an UNTIL date on its last occurrence, so calendars stop where the task ends.
+func Export(task *models.TimelineTask) (string, error) {
+	r, err := newRule(task)
+	if err != nil {
+		return "", err
+	}
+
+	dates := r.All()
+	option := r.OrigOptions
+	option.Dtstart = time.Time{}
+	option.Count = 0
+	option.Until = time.Time{}
+
+	rule := option.RRuleString()
+	if len(dates) > 0 {
+		rule += ";UNTIL=" + dates[len(dates)-1].Format("20060102")
+	}
+	return rule, nil
+}
+
+// Streak works out a habit's streaks from the dates it occurs on, in order, and the dates it was done on,
+// as of the civil date today. An occurrence today that is not done yet does not break the current streak.
+func Streak(dates []time.Time, done map[time.Time]bool, today time.Time) models.Streak {
+	streak := models.Streak{TotalOccurrences: len(dates)}
+
+	run := 0
+	for _, date := range dates {
+		if date.After(today) {
+			break
+		}
+		streak.DueOccurrences++
+
+		if done[date] {
+			streak.CompletedOccurrences++
+			run++
+			streak.Longest = max(streak.Longest, run)
+			streak.Current = run
+			continue
+		}
+
+		run = 0
+		if !date.Equal(today) {
+			streak.Current = 0
+		}
+	}
+
+	return streak
+}
+
+// newRule builds the rule for a task's occurrences
+func newRule(task *models.TimelineTask) (*rrule.RRule, error) {
+	option, err := parse(task.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	option.Dtstart = truncate(task.StartDate)
+	end := truncate(task.EndDate)
+	if option.Until.IsZero() || option.Until.After(end) {
+		option.Until = end
+	}
+
+	return rrule.NewRRule(*option)
+}
+
+func parse(rule string) (*rrule.ROption, error) {
+	rule = strings.TrimSpace(rule)
+	if rule == "" {
+		return nil, fmt.Errorf("recurrence rule is empty")
+	}
+	if strings.Contains(rule, "\n") || strings.Contains(strings.ToUpper(rule), "DTSTART") {
+		return nil, fmt.Errorf("recurrence rule must not set DTSTART; occurrences start on the task's start date")
+	}
+
+	option, err := rrule.StrToROption(strings.ToUpper(rule))
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule: %w", err)
+	}
+	if option.Freq > rrule.DAILY || len(option.Byhour) > 0 || len(option.Byminute) > 0 || len(option.Bysecond) > 0 {
+		return nil, fmt.Errorf("invalid recurrence rule: tasks can repeat at most daily")
+	}
+	if option.Interval < 0 || option.Count < 0 {
+		return nil, fmt.Errorf("invalid recurrence rule: INTERVAL and COUNT must be positive")
+	}
+
+	return option, nil
+}
+
+// truncate drops the time of day, keeping the calendar date
+func truncate(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
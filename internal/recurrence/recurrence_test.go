@@ -0,0 +1,139 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{rule: "FREQ=WEEKLY;BYDAY=SA", want: "FREQ=WEEKLY;BYDAY=SA"},
+		{rule: "freq=daily;interval=2", want: "FREQ=DAILY;INTERVAL=2"},
+		{rule: "RRULE:FREQ=MONTHLY;COUNT=3", want: "FREQ=MONTHLY;COUNT=3"},
+		{rule: "  FREQ=YEARLY  ", want: "FREQ=YEARLY"},
+		{rule: "", wantErr: true},
+		{rule: "FREQ=HOURLY", wantErr: true},
+		{rule: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+		{rule: "DTSTART:20240101T000000Z\nRRULE:FREQ=DAILY", wantErr: true},
+		{rule: "FREQ=DAILY;DTSTART=20240101T000000Z", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=-1", wantErr: true},
+		{rule: "FREQ=SOMETIMES", wantErr: true},
+		{rule: "not a rule", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := Normalize(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Normalize(%q) = %q, want an error", tt.rule, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize(%q): %v", tt.rule, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnbounded(t *testing.T) {
+	got, err := Unbounded("FREQ=WEEKLY;COUNT=4;BYDAY=MO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "FREQ=WEEKLY;BYDAY=MO"; got != want {
+		t.Errorf("Unbounded = %q, want %q", got, want)
+	}
+}
+
+func TestDates(t *testing.T) {
+	task := &models.TimelineTask{
+		StartDate:  date(2024, time.January, 1),
+		EndDate:    date(2024, time.January, 31),
+		Recurrence: "FREQ=WEEKLY;BYDAY=SA",
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		from, to time.Time
+		want     []time.Time
+	}{
+		{
+			name: "within the task",
+			rule: "FREQ=WEEKLY;BYDAY=SA",
+			from: date(2024, time.January, 1),
+			to:   date(2024, time.January, 14),
+			want: []time.Time{date(2024, time.January, 6), date(2024, time.January, 13)},
+		},
+		{
+			name: "stops at the task's end date",
+			rule: "FREQ=WEEKLY;BYDAY=SA",
+			from: date(2024, time.January, 20),
+			to:   date(2024, time.February, 29),
+			want: []time.Time{date(2024, time.January, 20), date(2024, time.January, 27)},
+		},
+		{
+			name: "count bounds the occurrences",
+			rule: "FREQ=DAILY;COUNT=2",
+			from: date(2024, time.January, 1),
+			to:   date(2024, time.January, 31),
+			want: []time.Time{date(2024, time.January, 1), date(2024, time.January, 2)},
+		},
+		{
+			name: "ignores the time of day",
+			rule: "FREQ=DAILY;INTERVAL=10",
+			from: time.Date(2024, time.January, 11, 18, 30, 0, 0, time.UTC),
+			to:   time.Date(2024, time.January, 21, 6, 0, 0, 0, time.UTC),
+			want: []time.Time{date(2024, time.January, 11), date(2024, time.January, 21)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task.Recurrence = tt.rule
+			got, err := Dates(task, tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Dates = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Fatalf("Dates = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	task := &models.TimelineTask{
+		StartDate:  date(2024, time.January, 1),
+		EndDate:    date(2024, time.January, 31),
+		Recurrence: "FREQ=WEEKLY;BYDAY=SA",
+	}
+
+	got, err := Export(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "FREQ=WEEKLY;BYDAY=SA;UNTIL=20240127"; got != want {
+		t.Errorf("Export = %q, want %q", got, want)
+	}
+}
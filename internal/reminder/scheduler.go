@@ -0,0 +1,217 @@
+package reminder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+const (
+	// maxLeadDays bounds how far ahead reminders can be configured
+	maxLeadDays = 30
+	// overdueWindow is how many days after its end date an overdue task is still reminded about
+	overdueWindow = 7
+)
+
+// Scheduler scans timeline tasks and publishes task.reminder events.
+// Every reminder is recorded before it is published, so each one fires once even across restarts.
+type Scheduler struct {
+	taskRepo     *repository.TaskRepository
+	reminderRepo *repository.ReminderRepository
+	events       *events.Bus
+	clock        clock.Clock
+	interval     time.Duration
+}
+
+// NewScheduler creates a new Scheduler that scans at the given interval
+func NewScheduler(bus *events.Bus, clk clock.Clock, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		taskRepo:     repository.NewTaskRepository(),
+		reminderRepo: repository.NewReminderRepository(),
+		events:       bus,
+		clock:        clk,
+		interval:     interval,
+	}
+}
+
+// Run scans for due reminders until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Scan(ctx, s.clock.Now()); err != nil {
+			log.Printf("failed to scan for task reminders: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan publishes every reminder that is due at now and has not been sent yet.
+// Reminders are due by the date it is in each task owner's time zone.
+func (s *Scheduler) Scan(ctx context.Context, now time.Time) error {
+	// Widen the range by a day either side to cover every time zone
+	today := clock.Date(now, time.UTC)
+	tasks, err := s.taskRepo.GetIncompleteBetween(ctx, today.AddDate(0, 0, -overdueWindow-1), today.AddDate(0, 0, maxLeadDays+1))
+	if err != nil {
+		return err
+	}
+
+	settingsByUser := map[string]*models.ReminderSettings{}
+	for i := range tasks {
+		task := &tasks[i]
+
+		settings, ok := settingsByUser[task.UserID]
+		if !ok {
+			settings, err = s.Settings(ctx, task.UserID)
+			if err != nil {
+				return err
+			}
+			settingsByUser[task.UserID] = settings
+		}
+
+		for _, reminder := range Due(settings, &task.TimelineTask, clock.Date(now, clock.Location(task.TimeZone))) {
+			reminder.UserID = task.UserID
+			s.send(ctx, reminder, &task.TimelineTask)
+		}
+	}
+
+	return nil
+}
+
+// Settings gets a user's reminder settings, falling back to the defaults
+func (s *Scheduler) Settings(ctx context.Context, userID string) (*models.ReminderSettings, error) {
+	settings, err := s.reminderRepo.GetSettings(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.DefaultReminderSettings(userID), nil
+	}
+	return settings, err
+}
+
+// send records a reminder and publishes it if it had not been sent before
+func (s *Scheduler) send(ctx context.Context, reminder *models.TaskReminder, task *models.TimelineTask) {
+	recorded, err := s.reminderRepo.Record(ctx, reminder)
+	if err != nil {
+		log.Printf("failed to record %s reminder for task %s: %v", reminder.Kind, reminder.TaskID, err)
+		return
+	}
+	if !recorded {
+		return
+	}
+
+	s.events.Publish(ctx, events.TaskReminder, reminder.UserID, events.ReminderData{
+		TaskData: events.NewTaskData(task),
+		Kind:     reminder.Kind,
+		LeadDays: reminder.LeadDays,
+		Message:  reminder.Message,
+	})
+}
+
+// Due returns the reminders for a task that are due today under the given settings.
+// When the scheduler was not running on the exact day a lead time was reached,
+// the closest lead time that still applies is used so the reminder is not lost.
+func Due(settings *models.ReminderSettings, task *models.TimelineTask, today time.Time) []*models.TaskReminder {
+	if !settings.Enabled || task.Completed {
+		return nil
+	}
+
+	reminders := []*models.TaskReminder{}
+
+	startsIn := daysBetween(today, task.StartDate)
+	if lead, ok := closestLead(settings.StartLeadDays, startsIn); ok {
+		reminders = append(reminders, &models.TaskReminder{
+			TaskID:   task.ID,
+			Kind:     models.ReminderStarts,
+			LeadDays: lead,
+			DueOn:    task.StartDate,
+			Message:  fmt.Sprintf("%q %s", task.Title, describe("starts", startsIn)),
+		})
+	}
+
+	dueIn := daysBetween(today, task.EndDate)
+	if lead, ok := closestLead(settings.DueLeadDays, dueIn); ok {
+		reminders = append(reminders, &models.TaskReminder{
+			TaskID:   task.ID,
+			Kind:     models.ReminderDue,
+			LeadDays: lead,
+			DueOn:    task.EndDate,
+			Message:  fmt.Sprintf("%q is %s", task.Title, describe("due", dueIn)),
+		})
+	}
+
+	if settings.Overdue && dueIn < 0 {
+		reminders = append(reminders, &models.TaskReminder{
+			TaskID:  task.ID,
+			Kind:    models.ReminderOverdue,
+			DueOn:   task.EndDate,
+			Message: fmt.Sprintf("%q is overdue since %s", task.Title, task.EndDate.Format("2006-01-02")),
+		})
+	}
+
+	return reminders
+}
+
+// ValidateLeadDays checks lead times are within range and returns them sorted without duplicates
+func ValidateLeadDays(leadDays []int) ([]int, error) {
+	seen := map[int]bool{}
+	result := []int{}
+	for _, lead := range leadDays {
+		if lead < 0 || lead > maxLeadDays {
+			return nil, fmt.Errorf("lead days must be between 0 and %d", maxLeadDays)
+		}
+		if !seen[lead] {
+			seen[lead] = true
+			result = append(result, lead)
+		}
+	}
+	sort.Ints(result)
+	return result, nil
+}
+
+// closestLead returns the smallest lead time that has been reached days before the date
+func closestLead(leadDays []int, days int) (int, bool) {
+	if days < 0 {
+		return 0, false
+	}
+
+	best, found := 0, false
+	for _, lead := range leadDays {
+		if days <= lead && (!found || lead < best) {
+			best, found = lead, true
+		}
+	}
+	return best, found
+}
+
+// daysBetween returns the number of calendar days from one date to another
+func daysBetween(from, to time.Time) int {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// describe phrases how far away a date is, such as "starts tomorrow" or "due in 2 days"
+func describe(verb string, days int) string {
+	switch days {
+	case 0:
+		return verb + " today"
+	case 1:
+		return verb + " tomorrow"
+	default:
+		return fmt.Sprintf("%s in %d days", verb, days)
+	}
+}
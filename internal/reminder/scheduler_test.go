@@ -0,0 +1,198 @@
+package reminder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/database/databasetest"
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestClosestLead(t *testing.T) {
+	tests := []struct {
+		name     string
+		leadDays []int
+		days     int
+		want     int
+		wantOK   bool
+	}{
+		{name: "today with a same day lead", leadDays: []int{0, 1, 7}, days: 0, want: 0, wantOK: true},
+		{name: "today with only a later lead", leadDays: []int{1}, days: 0, want: 1, wantOK: true},
+		{name: "within one lead", leadDays: []int{1, 7}, days: 1, want: 1, wantOK: true},
+		{name: "between leads", leadDays: []int{1, 7}, days: 3, want: 7, wantOK: true},
+		{name: "unsorted leads", leadDays: []int{7, 1}, days: 3, want: 7, wantOK: true},
+		{name: "on the furthest lead", leadDays: []int{1, 7}, days: 7, want: 7, wantOK: true},
+		{name: "beyond every lead", leadDays: []int{1, 7}, days: 8},
+		{name: "in the past", leadDays: []int{1, 7}, days: -1},
+		{name: "no leads", leadDays: nil, days: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := closestLead(tt.leadDays, tt.days)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("closestLead(%v, %d) = %d, %v, want %d, %v", tt.leadDays, tt.days, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDue(t *testing.T) {
+	today := date(2024, time.March, 10)
+	settings := &models.ReminderSettings{Enabled: true, StartLeadDays: []int{1, 7}, DueLeadDays: []int{2}, Overdue: true}
+
+	type reminder struct {
+		kind     string
+		leadDays int
+		message  string
+	}
+	tests := []struct {
+		name     string
+		settings *models.ReminderSettings
+		start    time.Time
+		end      time.Time
+		done     bool
+		want     []reminder
+	}{
+		{
+			name:  "starts today",
+			start: today,
+			end:   today.AddDate(0, 0, 5),
+			want:  []reminder{{models.ReminderStarts, 1, `"Task" starts today`}},
+		},
+		{
+			name:  "starts in 3 days",
+			start: today.AddDate(0, 0, 3),
+			end:   today.AddDate(0, 0, 10),
+			want:  []reminder{{models.ReminderStarts, 7, `"Task" starts in 3 days`}},
+		},
+		{
+			name:  "due tomorrow",
+			start: today.AddDate(0, 0, -3),
+			end:   today.AddDate(0, 0, 1),
+			want:  []reminder{{models.ReminderDue, 2, `"Task" is due tomorrow`}},
+		},
+		{
+			name:  "overdue",
+			start: today.AddDate(0, 0, -5),
+			end:   today.AddDate(0, 0, -2),
+			want:  []reminder{{models.ReminderOverdue, 0, `"Task" is overdue since 2024-03-08`}},
+		},
+		{
+			name:     "overdue reminders off",
+			settings: &models.ReminderSettings{Enabled: true, StartLeadDays: []int{1}, DueLeadDays: []int{2}},
+			start:    today.AddDate(0, 0, -5),
+			end:      today.AddDate(0, 0, -2),
+		},
+		{
+			name:  "completed",
+			start: today.AddDate(0, 0, -5),
+			end:   today.AddDate(0, 0, -2),
+			done:  true,
+		},
+		{
+			name:     "disabled",
+			settings: &models.ReminderSettings{StartLeadDays: []int{1}, DueLeadDays: []int{2}, Overdue: true},
+			start:    today,
+			end:      today.AddDate(0, 0, 1),
+		},
+		{
+			name:  "nothing due yet",
+			start: today.AddDate(0, 0, 8),
+			end:   today.AddDate(0, 0, 9),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := settings
+			if tt.settings != nil {
+				s = tt.settings
+			}
+			task := &models.TimelineTask{ID: "task-1", Title: "Task", StartDate: tt.start, EndDate: tt.end, Completed: tt.done}
+
+			got := Due(s, task, today)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Due = %d reminders, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got[i].Kind != want.kind || got[i].LeadDays != want.leadDays || got[i].Message != want.message {
+					t.Errorf("reminder %d = %s %d %q, want %s %d %q",
+						i, got[i].Kind, got[i].LeadDays, got[i].Message, want.kind, want.leadDays, want.message)
+				}
+			}
+		})
+	}
+}
+
+// key is what a reminder is recorded under, so that it is sent once
+type key struct {
+	kind     string
+	leadDays int
+	dueOn    time.Time
+}
+
+func TestDueKeys(t *testing.T) {
+	settings := &models.ReminderSettings{Enabled: true, StartLeadDays: []int{1, 7}, Overdue: true}
+	task := &models.TimelineTask{ID: "task-1", StartDate: date(2024, time.March, 13), EndDate: date(2024, time.March, 14)}
+
+	// Scanning every day from 3 days before the start sends the 7 day reminder once, the 1 day reminder
+	// once, and a single overdue reminder however long the task stays overdue
+	sent := map[key]int{}
+	for day := date(2024, time.March, 10); !day.After(date(2024, time.March, 20)); day = day.AddDate(0, 0, 1) {
+		for _, reminder := range Due(settings, task, day) {
+			sent[key{reminder.Kind, reminder.LeadDays, reminder.DueOn}]++
+		}
+	}
+
+	want := map[key]int{
+		{models.ReminderStarts, 7, task.StartDate}: 2,
+		{models.ReminderStarts, 1, task.StartDate}: 2,
+		{models.ReminderOverdue, 0, task.EndDate}:  6,
+	}
+	if len(sent) != len(want) {
+		t.Fatalf("reminders due under %d keys, want %d: %v", len(sent), len(want), sent)
+	}
+	for k, n := range want {
+		if sent[k] != n {
+			t.Errorf("reminder %+v due on %d days, want %d", k, sent[k], n)
+		}
+	}
+}
+
+func TestSendOnce(t *testing.T) {
+	tests := []struct {
+		name        string
+		recorded    int64
+		wantPublish bool
+	}{
+		{name: "first time", recorded: 1, wantPublish: true},
+		{name: "already sent", recorded: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := databasetest.Open(t)
+			db.Exec("INSERT IGNORE INTO task_reminders", tt.recorded)
+
+			bus := events.NewBus()
+			published := 0
+			bus.Subscribe(func(ctx context.Context, event events.Event) { published++ })
+			s := NewScheduler(bus, clock.System, time.Hour)
+
+			task := &models.TimelineTask{ID: "task-1", StartDate: date(2024, time.March, 10), EndDate: date(2024, time.March, 12)}
+			s.send(context.Background(), &models.TaskReminder{TaskID: task.ID, UserID: "user-1", Kind: models.ReminderStarts}, task)
+
+			if got := published == 1; got != tt.wantPublish {
+				t.Errorf("published %d events, want published = %v", published, tt.wantPublish)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// ActivityRepository handles database operations for the things that happen on timelines
+type ActivityRepository struct {
+	db *timeoutDB
+}
+
+// NewActivityRepository creates a new ActivityRepository
+func NewActivityRepository() *ActivityRepository {
+	return &ActivityRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// Create records an activity item
+func (r *ActivityRepository) Create(ctx context.Context, item *models.ActivityItem) error {
+	item.ID = uuid.New().String()
+	if item.OccurredAt.IsZero() {
+		item.OccurredAt = time.Now()
+	}
+
+	query := "INSERT INTO timeline_activity (id, timeline_id, task_id, user_id, kind, created_at) VALUES (?, ?, ?, ?, ?, ?)"
+	_, err := r.db.ExecContext(ctx, query, item.ID, item.TimelineID, nullString(item.TaskID), nullString(item.UserID), item.Kind, item.OccurredAt)
+	return err
+}
+
+// GetByTimelineID gets the most recent activity on a timeline before a time, newest first
+func (r *ActivityRepository) GetByTimelineID(ctx context.Context, timelineID string, before time.Time, limit int) ([]*models.ActivityItem, error) {
+	query := `SELECT id, timeline_id, task_id, user_id, kind, created_at FROM timeline_activity
+	WHERE timeline_id = ? AND created_at < ? ORDER BY created_at DESC LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, timelineID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*models.ActivityItem{}
+	for rows.Next() {
+		item := &models.ActivityItem{}
+		var taskID, userID sql.NullString
+		if err := rows.Scan(&item.ID, &item.TimelineID, &taskID, &userID, &item.Kind, &item.OccurredAt); err != nil {
+			return nil, err
+		}
+		item.TaskID = taskID.String
+		item.UserID = userID.String
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
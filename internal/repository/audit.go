@@ -0,0 +1,398 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jukemori/timeline-generator/internal/audit"
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// redactedColumns are credentials that are never copied into the audit log
+var redactedColumns = map[string]bool{
+	"secret":            true,
+	"unsubscribe_token": true,
+}
+
+// auditTarget identifies the single row an audited write changes
+type auditTarget struct {
+	entityType string
+	// entityID defaults to the row's id column
+	entityID string
+	table    string
+	where    string
+	args     []interface{}
+}
+
+// rowTarget targets the row of a table with the given ID
+func rowTarget(entityType, table, id string) auditTarget {
+	return auditTarget{entityType: entityType, entityID: id, table: table, where: "id = ?", args: []interface{}{id}}
+}
+
+// userTarget targets the row of a per-user table, such as a user's settings
+func userTarget(entityType, table, userID string) auditTarget {
+	return auditTarget{entityType: entityType, entityID: userID, table: table, where: "user_id = ?", args: []interface{}{userID}}
+}
+
+// memberTarget targets a user's membership of a goal
+func memberTarget(goalID, userID string) auditTarget {
+	return auditTarget{
+		entityType: "goal_member",
+		entityID:   goalID + "/" + userID,
+		table:      "goal_members",
+		where:      "goal_id = ? AND user_id = ?",
+		args:       []interface{}{goalID, userID},
+	}
+}
+
+// auditedExec runs a single statement as an audited write and reports whether it changed the row
+func auditedExec(ctx context.Context, db *timeoutDB, target auditTarget, query string, args ...interface{}) (bool, error) {
+	return audited(ctx, db, target, func(tx *sql.Tx) (bool, error) {
+		return execAffected(ctx, tx, query, args...)
+	})
+}
+
+// audited runs a write in a transaction together with an audit entry holding the target row's
+// values before and after it, attributed to the actor and request in ctx.
+// Writes that report no change are not recorded. Records the system keeps about its own work, such as
+// job claims, webhook deliveries, sent reminders and usage counters, are written without auditing.
+func audited(ctx context.Context, db *timeoutDB, target auditTarget, write func(tx *sql.Tx) (bool, error)) (bool, error) {
+	var changed bool
+	err := db.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		changed, err = auditedTx(ctx, tx, target, write)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return changed, nil
+}
+
+// auditedTx runs a write within a transaction that changes several rows, adding the audit entry for
+// the target row
+func auditedTx(ctx context.Context, tx *sql.Tx, target auditTarget, write func(tx *sql.Tx) (bool, error)) (bool, error) {
+	before, err := snapshot(ctx, tx, target)
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := write(tx)
+	if err != nil {
+		return false, err
+	}
+	if !changed {
+		return false, nil
+	}
+
+	after, err := snapshot(ctx, tx, target)
+	if err != nil {
+		return false, err
+	}
+	if err := recordChange(ctx, tx, target, before, after); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// snapshot reads the target row's columns, or nil if there is no such row
+func snapshot(ctx context.Context, tx *sql.Tx, target auditTarget) (map[string]interface{}, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT * FROM "+target.table+" WHERE "+target.where, target.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		switch value := values[i].(type) {
+		case []byte:
+			row[column] = string(value)
+		default:
+			row[column] = value
+		}
+		if redactedColumns[column] && row[column] != nil {
+			row[column] = "[redacted]"
+		}
+	}
+
+	return row, rows.Err()
+}
+
+// recordChange appends the audit entry for a change to the target row
+func recordChange(ctx context.Context, tx *sql.Tx, target auditTarget, before, after map[string]interface{}) error {
+	action := models.AuditUpdate
+	row := after
+	switch {
+	case before == nil:
+		action = models.AuditCreate
+	case after == nil:
+		action = models.AuditDelete
+		row = before
+	}
+
+	beforeValues, err := nullJSON(before)
+	if err != nil {
+		return err
+	}
+	afterValues, err := nullJSON(after)
+	if err != nil {
+		return err
+	}
+
+	organizationID, err := organizationOf(ctx, tx, target.table, row)
+	if err != nil {
+		return err
+	}
+
+	entityID := target.entityID
+	if entityID == "" {
+		entityID, _ = row["id"].(string)
+	}
+
+	metadata := audit.FromContext(ctx)
+	operation := metadata.Operation
+	if operation == "" {
+		operation = "unknown"
+	}
+
+	query := `INSERT INTO audit_log (id, organization_id, actor_id, operation, action, entity_type, entity_id,
+	before_values, after_values, request_id, ip_address, user_agent, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = tx.ExecContext(ctx,
+		query,
+		uuid.New().String(),
+		organizationID,
+		nullString(metadata.ActorID),
+		truncate(operation, 255),
+		action,
+		target.entityType,
+		entityID,
+		beforeValues,
+		afterValues,
+		nullString(metadata.RequestID),
+		nullString(metadata.IPAddress),
+		nullString(truncate(metadata.UserAgent, 255)),
+		time.Now(),
+	)
+	return err
+}
+
+// organizationOf finds the organization a row belongs to through the column linking it to one
+func organizationOf(ctx context.Context, tx *sql.Tx, table string, row map[string]interface{}) (sql.NullString, error) {
+	var query string
+	var arg interface{}
+	switch {
+	case table == "organizations":
+		return sql.NullString{String: row["id"].(string), Valid: true}, nil
+	case row["organization_id"] != nil:
+		return sql.NullString{String: row["organization_id"].(string), Valid: true}, nil
+	case row["user_id"] != nil:
+		query, arg = "SELECT organization_id FROM users WHERE id = ?", row["user_id"]
+	case row["goal_id"] != nil:
+		query, arg = "SELECT organization_id FROM goals WHERE id = ?", row["goal_id"]
+	case row["timeline_id"] != nil:
+		query = "SELECT g.organization_id FROM timelines tl JOIN goals g ON g.id = tl.goal_id WHERE tl.id = ?"
+		arg = row["timeline_id"]
+	case row["task_id"] != nil:
+		query = `SELECT g.organization_id FROM timeline_tasks t
+		JOIN timelines tl ON tl.id = t.timeline_id
+		JOIN goals g ON g.id = tl.goal_id
+		WHERE t.id = ?`
+		arg = row["task_id"]
+	default:
+		return sql.NullString{}, nil
+	}
+
+	var organizationID sql.NullString
+	err := tx.QueryRowContext(ctx, query, arg).Scan(&organizationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.NullString{}, nil
+	}
+	return organizationID, err
+}
+
+// nullJSON encodes a row snapshot, storing a missing row as NULL
+func nullJSON(row map[string]interface{}) (interface{}, error) {
+	if row == nil {
+		return nil, nil
+	}
+	return json.Marshal(row)
+}
+
+// truncate shortens s to at most n characters, the length of the VARCHAR column it is stored in,
+// replacing bytes that are not valid UTF-8
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// AuditFilter restricts which entries are included in audit log queries.
+// Only entries of the organization are ever included.
+type AuditFilter struct {
+	OrganizationID string
+	ActorID        string
+	EntityType     string
+	EntityID       string
+	Action         string
+	Operation      string
+	From           *time.Time
+	To             *time.Time
+}
+
+// where builds the WHERE clause and arguments for the filter
+func (f AuditFilter) where() (string, []interface{}) {
+	conditions := []string{"organization_id = ?"}
+	args := []interface{}{f.OrganizationID}
+
+	for _, condition := range []struct{ column, value string }{
+		{"actor_id", f.ActorID},
+		{"entity_type", f.EntityType},
+		{"entity_id", f.EntityID},
+		{"action", f.Action},
+		{"operation", f.Operation},
+	} {
+		if condition.value != "" {
+			conditions = append(conditions, condition.column+" = ?")
+			args = append(args, condition.value)
+		}
+	}
+	if f.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *f.From)
+	}
+	if f.To != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *f.To)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// auditColumns are the columns scanned by scanAuditEntry
+const auditColumns = `id, organization_id, actor_id, operation, action, entity_type, entity_id,
+	before_values, after_values, request_id, ip_address, user_agent, created_at`
+
+// AuditRepository reads the audit log. Entries are only ever written alongside the changes they record.
+type AuditRepository struct {
+	db *timeoutDB
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// GetEntries gets the most recent entries matching the filter made before a time, newest first
+func (r *AuditRepository) GetEntries(ctx context.Context, filter AuditFilter, before time.Time, limit int) ([]*models.AuditEntry, error) {
+	where, args := filter.where()
+	query := "SELECT " + auditColumns + " FROM audit_log WHERE " + where + " AND created_at < ? ORDER BY created_at DESC LIMIT ?"
+	args = append(args, before, limit)
+
+	entries := []*models.AuditEntry{}
+	err := r.each(ctx, query, args, func(entry *models.AuditEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Export streams every entry matching the filter to fn, oldest first
+func (r *AuditRepository) Export(ctx context.Context, filter AuditFilter, fn func(entry *models.AuditEntry) error) error {
+	where, args := filter.where()
+	query := "SELECT " + auditColumns + " FROM audit_log WHERE " + where + " ORDER BY created_at ASC"
+	return r.each(ctx, query, args, fn)
+}
+
+func (r *AuditRepository) each(ctx context.Context, query string, args []interface{}, fn func(entry *models.AuditEntry) error) error {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func scanAuditEntry(row rowScanner) (*models.AuditEntry, error) {
+	entry := &models.AuditEntry{}
+	var organizationID, actorID, requestID, ipAddress, userAgent sql.NullString
+	var before, after []byte
+
+	err := row.Scan(
+		&entry.ID,
+		&organizationID,
+		&actorID,
+		&entry.Operation,
+		&entry.Action,
+		&entry.EntityType,
+		&entry.EntityID,
+		&before,
+		&after,
+		&requestID,
+		&ipAddress,
+		&userAgent,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.OrganizationID = organizationID.String
+	entry.ActorID = actorID.String
+	entry.RequestID = requestID.String
+	entry.IPAddress = ipAddress.String
+	entry.UserAgent = userAgent.String
+	if before != nil {
+		entry.Before = json.RawMessage(before)
+	}
+	if after != nil {
+		entry.After = json.RawMessage(after)
+	}
+
+	return entry, nil
+}
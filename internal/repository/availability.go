@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// AvailabilityRepository handles database operations for users' availability
+type AvailabilityRepository struct {
+	db *timeoutDB
+}
+
+// NewAvailabilityRepository creates a new AvailabilityRepository
+func NewAvailabilityRepository() *AvailabilityRepository {
+	return &AvailabilityRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// GetByUserID gets a user's availability.
+// It returns sql.ErrNoRows when the user has not configured their availability.
+func (r *AvailabilityRepository) GetByUserID(ctx context.Context, userID string) (*models.Availability, error) {
+	query := `SELECT user_id, working_days, hours_per_day, weekly_capacity, holiday_set, blackout_dates, created_at, updated_at
+	FROM availability WHERE user_id = ?`
+
+	availability := &models.Availability{}
+	var workingDays, blackoutDates []byte
+	var createdAt, updatedAt time.Time
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&availability.UserID,
+		&workingDays,
+		&availability.HoursPerDay,
+		&availability.WeeklyCapacity,
+		&availability.HolidaySet,
+		&blackoutDates,
+		&createdAt,
+		&updatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	availability.CreatedAt = &createdAt
+	availability.UpdatedAt = &updatedAt
+
+	if err := json.Unmarshal(workingDays, &availability.WorkingDays); err != nil {
+		return nil, err
+	}
+
+	var dates []string
+	if err := json.Unmarshal(blackoutDates, &dates); err != nil {
+		return nil, err
+	}
+	availability.BlackoutDates = make([]time.Time, len(dates))
+	for i, date := range dates {
+		if availability.BlackoutDates[i], err = time.Parse("2006-01-02", date); err != nil {
+			return nil, err
+		}
+	}
+
+	return availability, nil
+}
+
+// Save creates or replaces a user's availability
+func (r *AvailabilityRepository) Save(ctx context.Context, availability *models.Availability) error {
+	workingDays, err := json.Marshal(availability.WorkingDays)
+	if err != nil {
+		return err
+	}
+
+	dates := make([]string, len(availability.BlackoutDates))
+	for i, date := range availability.BlackoutDates {
+		dates[i] = date.Format("2006-01-02")
+	}
+	blackoutDates, err := json.Marshal(dates)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO availability
+	(user_id, working_days, hours_per_day, weekly_capacity, holiday_set, blackout_dates, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE working_days = VALUES(working_days), hours_per_day = VALUES(hours_per_day),
+	weekly_capacity = VALUES(weekly_capacity),
+	holiday_set = VALUES(holiday_set), blackout_dates = VALUES(blackout_dates), updated_at = VALUES(updated_at)`
+
+	now := time.Now()
+	_, err = auditedExec(
+		ctx,
+		r.db,
+		userTarget("availability", "availability", availability.UserID),
+		query,
+		availability.UserID,
+		workingDays,
+		availability.HoursPerDay,
+		availability.WeeklyCapacity,
+		availability.HolidaySet,
+		blackoutDates,
+		now,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+
+	if availability.CreatedAt == nil {
+		availability.CreatedAt = &now
+	}
+	availability.UpdatedAt = &now
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// commentQuery selects the comments on goals of the organization given as its first argument along with
+// their author's email, leaving out comments on goals and tasks in the trash
+const commentQuery = `SELECT c.id, c.goal_id, c.task_id, c.parent_id, c.user_id, u.email, c.body, c.mentions,
+	c.edited_at, c.deleted_at, c.created_at, c.updated_at
+	FROM comments c
+	JOIN users u ON u.id = c.user_id
+	JOIN goals g ON g.id = c.goal_id AND g.deleted_at IS NULL
+	LEFT JOIN timeline_tasks t ON t.id = c.task_id
+	WHERE g.organization_id = ? AND t.deleted_at IS NULL`
+
+// commentInOrganization limits comments to those on goals of the organization given as its argument
+const commentInOrganization = "goal_id IN (SELECT g.id FROM goals g WHERE g.organization_id = ?)"
+
+// CommentRepository handles database operations for comments on goals and tasks
+type CommentRepository struct {
+	db *timeoutDB
+}
+
+// NewCommentRepository creates a new CommentRepository
+func NewCommentRepository() *CommentRepository {
+	return &CommentRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// Create saves a new comment
+func (r *CommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	mentions, err := json.Marshal(comment.Mentions)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	comment.ID = uuid.New().String()
+	comment.CreatedAt = now
+	comment.UpdatedAt = now
+
+	query := `INSERT INTO comments (id, goal_id, task_id, parent_id, user_id, body, mentions, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = auditedExec(
+		ctx,
+		r.db,
+		rowTarget("comment", "comments", comment.ID),
+		query,
+		comment.ID,
+		comment.GoalID,
+		nullString(comment.TaskID),
+		nullString(comment.ParentID),
+		comment.UserID,
+		comment.Body,
+		mentions,
+		now,
+		now,
+	)
+	return err
+}
+
+// GetByID gets a comment on a goal of an organization by ID
+func (r *CommentRepository) GetByID(ctx context.Context, organizationID, id string) (*models.Comment, error) {
+	return scanComment(r.db.QueryRowContext(ctx, commentQuery+" AND c.id = ?", organizationID, id))
+}
+
+// GetByGoalID gets the comments on a goal of an organization itself, oldest first
+func (r *CommentRepository) GetByGoalID(ctx context.Context, organizationID, goalID string) ([]*models.Comment, error) {
+	return r.query(ctx, commentQuery+" AND c.goal_id = ? AND c.task_id IS NULL ORDER BY c.created_at ASC", organizationID, goalID)
+}
+
+// GetByTaskID gets the comments on a task on a goal of an organization, oldest first
+func (r *CommentRepository) GetByTaskID(ctx context.Context, organizationID, taskID string) ([]*models.Comment, error) {
+	return r.query(ctx, commentQuery+" AND c.task_id = ? ORDER BY c.created_at ASC", organizationID, taskID)
+}
+
+// GetByTimelineID gets the most recent comments made before a time on the tasks of a timeline on a goal
+// of an organization and on its goal that have not been deleted, newest first
+func (r *CommentRepository) GetByTimelineID(ctx context.Context, organizationID, timelineID string, before time.Time, limit int) ([]*models.Comment, error) {
+	query := commentQuery + `
+	AND c.goal_id = (SELECT tl.goal_id FROM timelines tl WHERE tl.id = ?) AND c.created_at < ?
+	AND (c.task_id IS NULL OR t.timeline_id = ?) AND c.deleted_at IS NULL
+	ORDER BY c.created_at DESC LIMIT ?`
+	return r.query(ctx, query, organizationID, timelineID, before, timelineID, limit)
+}
+
+// Update replaces the body and mentions of a comment on a goal of an organization and marks it edited
+func (r *CommentRepository) Update(ctx context.Context, organizationID string, comment *models.Comment) error {
+	mentions, err := json.Marshal(comment.Mentions)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	comment.EditedAt = &now
+	comment.UpdatedAt = now
+
+	query := "UPDATE comments SET body = ?, mentions = ?, edited_at = ?, updated_at = ? WHERE id = ? AND " + commentInOrganization
+	_, err = auditedExec(ctx, r.db, rowTarget("comment", "comments", comment.ID), query, comment.Body, mentions, now, now, comment.ID, organizationID)
+	return err
+}
+
+// Delete deletes a comment on a goal of an organization by clearing its body and mentions and marking it
+// deleted, keeping its replies in their thread
+func (r *CommentRepository) Delete(ctx context.Context, organizationID, id string) error {
+	query := `UPDATE comments SET body = '', mentions = JSON_ARRAY(), deleted_at = ?, updated_at = ?
+	WHERE id = ? AND deleted_at IS NULL AND ` + commentInOrganization
+	now := time.Now()
+	_, err := auditedExec(ctx, r.db, rowTarget("comment", "comments", id), query, now, now, id, organizationID)
+	return err
+}
+
+func (r *CommentRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.Comment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+func scanComment(row rowScanner) (*models.Comment, error) {
+	comment := &models.Comment{}
+	var taskID, parentID sql.NullString
+	var mentions []byte
+	var editedAt, deletedAt sql.NullTime
+
+	err := row.Scan(
+		&comment.ID,
+		&comment.GoalID,
+		&taskID,
+		&parentID,
+		&comment.UserID,
+		&comment.AuthorEmail,
+		&comment.Body,
+		&mentions,
+		&editedAt,
+		&deletedAt,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	comment.TaskID = taskID.String
+	comment.ParentID = parentID.String
+	if editedAt.Valid {
+		comment.EditedAt = &editedAt.Time
+	}
+	if deletedAt.Valid {
+		comment.DeletedAt = &deletedAt.Time
+	}
+	if err := json.Unmarshal(mentions, &comment.Mentions); err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
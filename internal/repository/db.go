@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jukemori/timeline-generator/internal/database"
+)
+
+// timeoutDB is the database as repositories use it. Each statement, and each transaction as a whole,
+// is bounded by database.QueryTimeout, so it stops at the timeout or as soon as the request that made
+// it is cancelled.
+type timeoutDB struct {
+	db *sql.DB
+}
+
+// withQueryTimeout wraps a database so that its statements are bounded by database.QueryTimeout
+func withQueryTimeout(db *sql.DB) *timeoutDB {
+	return &timeoutDB{db: db}
+}
+
+// withTimeout bounds ctx by database.QueryTimeout
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if database.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, database.QueryTimeout)
+}
+
+// ExecContext runs a statement that returns no rows
+func (d *timeoutDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return d.db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs a query. The timeout applies until the rows are closed.
+func (d *timeoutDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*timeoutRows, error) {
+	ctx, cancel := withTimeout(ctx)
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &timeoutRows{Rows: rows, cancel: cancel}, nil
+}
+
+// QueryRowContext runs a query expected to return at most one row. The timeout applies until the row is scanned.
+func (d *timeoutDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *timeoutRow {
+	ctx, cancel := withTimeout(ctx)
+	return &timeoutRow{row: d.db.QueryRowContext(ctx, query, args...), cancel: cancel}
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds and rolling it back otherwise.
+// The timeout applies to the transaction as a whole.
+func (d *timeoutDB) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// timeoutRows are the rows of a query, releasing its timeout once closed
+type timeoutRows struct {
+	*sql.Rows
+	cancel context.CancelFunc
+}
+
+// Close closes the rows and releases the query's timeout
+func (r *timeoutRows) Close() error {
+	defer r.cancel()
+	return r.Rows.Close()
+}
+
+// timeoutRow is the row of a query, releasing its timeout once scanned
+type timeoutRow struct {
+	row    *sql.Row
+	cancel context.CancelFunc
+}
+
+// Scan copies the row's columns into dest and releases the query's timeout
+func (r *timeoutRow) Scan(dest ...interface{}) error {
+	defer r.cancel()
+	return r.row.Scan(dest...)
+}
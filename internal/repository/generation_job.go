@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+const generationJobColumns = `id, user_id, status, input, attempts, max_attempts, last_error, timeline_id,
+	run_at, started_at, finished_at, created_at, updated_at`
+
+// GenerationJobRepository handles database operations for generation jobs
+type GenerationJobRepository struct {
+	db *timeoutDB
+}
+
+// NewGenerationJobRepository creates a new GenerationJobRepository
+func NewGenerationJobRepository() *GenerationJobRepository {
+	return &GenerationJobRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// Create queues a new generation job
+func (r *GenerationJobRepository) Create(ctx context.Context, userID string, input models.TimelineInput, maxAttempts int) (*models.GenerationJob, error) {
+	job := &models.GenerationJob{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Status:      models.JobStatusQueued,
+		Input:       input,
+		MaxAttempts: maxAttempts,
+		RunAt:       time.Now(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	encoded, err := json.Marshal(job.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO generation_jobs
+	(id, user_id, status, input, attempts, max_attempts, run_at, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = auditedExec(
+		ctx,
+		r.db,
+		rowTarget("generation_job", "generation_jobs", job.ID),
+		query,
+		job.ID,
+		job.UserID,
+		job.Status,
+		encoded,
+		job.Attempts,
+		job.MaxAttempts,
+		job.RunAt,
+		job.CreatedAt,
+		job.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetByID gets a generation job by ID
+func (r *GenerationJobRepository) GetByID(ctx context.Context, id string) (*models.GenerationJob, error) {
+	query := "SELECT " + generationJobColumns + " FROM generation_jobs WHERE id = ?"
+	return scanGenerationJob(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetInOrganization gets a generation job of a user of an organization by ID.
+// It returns sql.ErrNoRows for jobs of other organizations.
+func (r *GenerationJobRepository) GetInOrganization(ctx context.Context, organizationID, id string) (*models.GenerationJob, error) {
+	query := "SELECT " + generationJobColumns + ` FROM generation_jobs
+	WHERE id = ? AND user_id IN (SELECT u.id FROM users u WHERE u.organization_id = ?)`
+	return scanGenerationJob(r.db.QueryRowContext(ctx, query, id, organizationID))
+}
+
+// GetByUserID gets a user's generation jobs, optionally only those with the given status
+func (r *GenerationJobRepository) GetByUserID(ctx context.Context, userID, status string) ([]*models.GenerationJob, error) {
+	query := "SELECT " + generationJobColumns + " FROM generation_jobs WHERE user_id = ?"
+	args := []interface{}{userID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*models.GenerationJob{}
+	for rows.Next() {
+		job, err := scanGenerationJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// ClaimNext marks the next due queued job as running and returns it.
+// It returns sql.ErrNoRows when no job is due.
+func (r *GenerationJobRepository) ClaimNext(ctx context.Context) (*models.GenerationJob, error) {
+	now := time.Now()
+	var id string
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`SELECT id FROM generation_jobs WHERE status = ? AND run_at <= ?
+			ORDER BY run_at ASC LIMIT 1 FOR UPDATE SKIP LOCKED`,
+			models.JobStatusQueued, now,
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE generation_jobs SET status = ?, attempts = attempts + 1, locked_at = ?,
+			started_at = COALESCE(started_at, ?), updated_at = ? WHERE id = ?`,
+			models.JobStatusRunning, now, now, now, id,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// MarkSucceeded finishes a running job and links it to the timeline it produced
+func (r *GenerationJobRepository) MarkSucceeded(ctx context.Context, id, timelineID string) (bool, error) {
+	now := time.Now()
+	query := `UPDATE generation_jobs SET status = ?, timeline_id = ?, last_error = NULL, locked_at = NULL,
+	finished_at = ?, updated_at = ? WHERE id = ? AND status = ?`
+	return execAffected(ctx, r.db, query, models.JobStatusSucceeded, timelineID, now, now, id, models.JobStatusRunning)
+}
+
+// Retry puts a running job back in the queue to run again at runAt
+func (r *GenerationJobRepository) Retry(ctx context.Context, id, lastError string, runAt time.Time) (bool, error) {
+	query := `UPDATE generation_jobs SET status = ?, last_error = ?, run_at = ?, locked_at = NULL,
+	updated_at = ? WHERE id = ? AND status = ?`
+	return execAffected(ctx, r.db, query, models.JobStatusQueued, lastError, runAt, time.Now(), id, models.JobStatusRunning)
+}
+
+// MarkFailed finishes a running job that will not be retried
+func (r *GenerationJobRepository) MarkFailed(ctx context.Context, id, lastError string) (bool, error) {
+	now := time.Now()
+	query := `UPDATE generation_jobs SET status = ?, last_error = ?, locked_at = NULL,
+	finished_at = ?, updated_at = ? WHERE id = ? AND status = ?`
+	return execAffected(ctx, r.db, query, models.JobStatusFailed, lastError, now, now, id, models.JobStatusRunning)
+}
+
+// Cancel cancels a job that has not finished yet. It returns the status the job was cancelled in,
+// queued or running, or an empty status if the job had already finished.
+func (r *GenerationJobRepository) Cancel(ctx context.Context, id string) (string, error) {
+	var status string
+	_, err := audited(ctx, r.db, rowTarget("generation_job", "generation_jobs", id), func(tx *sql.Tx) (bool, error) {
+		err := tx.QueryRowContext(ctx, "SELECT status FROM generation_jobs WHERE id = ? FOR UPDATE", id).Scan(&status)
+		if err != nil {
+			return false, err
+		}
+		if status != models.JobStatusQueued && status != models.JobStatusRunning {
+			status = ""
+			return false, nil
+		}
+
+		now := time.Now()
+		query := "UPDATE generation_jobs SET status = ?, locked_at = NULL, finished_at = ?, updated_at = ? WHERE id = ?"
+		return execAffected(ctx, tx, query, models.JobStatusCancelled, now, now, id)
+	})
+	if err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+// Heartbeat renews the lock on a running job so that it is not requeued as stale.
+// It returns false once the job is no longer running, because it was cancelled or requeued.
+func (r *GenerationJobRepository) Heartbeat(ctx context.Context, id string) (bool, error) {
+	query := "UPDATE generation_jobs SET locked_at = ? WHERE id = ? AND status = ?"
+	return execAffected(ctx, r.db, query, time.Now(), id, models.JobStatusRunning)
+}
+
+// CountQueued counts the jobs waiting to run, including those waiting to be retried
+func (r *GenerationJobRepository) CountQueued(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM generation_jobs WHERE status = ?", models.JobStatusQueued).Scan(&count)
+	return count, err
+}
+
+// RequeueStale puts back running jobs whose worker has not renewed their lock since lockedBefore,
+// presumably because it died. Jobs that have used up their attempts fail instead, so that a job that
+// kills its worker is not run forever. It returns the number of jobs requeued and the users of the
+// jobs that failed, one per job.
+func (r *GenerationJobRepository) RequeueStale(ctx context.Context, lockedBefore time.Time) (int64, []string, error) {
+	var requeued int64
+	var failedUserIDs []string
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx,
+			`SELECT user_id FROM generation_jobs WHERE status = ? AND locked_at < ? AND attempts >= max_attempts
+			FOR UPDATE`,
+			models.JobStatusRunning, lockedBefore,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		failedUserIDs = nil
+		for rows.Next() {
+			var userID string
+			if err := rows.Scan(&userID); err != nil {
+				return err
+			}
+			failedUserIDs = append(failedUserIDs, userID)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		_, err = tx.ExecContext(ctx,
+			`UPDATE generation_jobs SET status = ?, last_error = ?, locked_at = NULL, finished_at = ?, updated_at = ?
+			WHERE status = ? AND locked_at < ? AND attempts >= max_attempts`,
+			models.JobStatusFailed, "the worker running the job stopped responding", now, now,
+			models.JobStatusRunning, lockedBefore,
+		)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx,
+			`UPDATE generation_jobs SET status = ?, run_at = ?, locked_at = NULL, updated_at = ?
+			WHERE status = ? AND locked_at < ?`,
+			models.JobStatusQueued, now, now, models.JobStatusRunning, lockedBefore,
+		)
+		if err != nil {
+			return err
+		}
+		requeued, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return requeued, failedUserIDs, nil
+}
+
+func scanGenerationJob(row rowScanner) (*models.GenerationJob, error) {
+	job := &models.GenerationJob{}
+	var (
+		input      []byte
+		lastError  sql.NullString
+		timelineID sql.NullString
+		startedAt  sql.NullTime
+		finishedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Status,
+		&input,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&lastError,
+		&timelineID,
+		&job.RunAt,
+		&startedAt,
+		&finishedAt,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(input, &job.Input); err != nil {
+		return nil, err
+	}
+	job.LastError = lastError.String
+	job.TimelineID = timelineID.String
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+
+	return job, nil
+}
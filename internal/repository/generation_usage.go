@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// GenerationUsageRepository handles database operations for generation quotas and usage
+type GenerationUsageRepository struct {
+	db *timeoutDB
+}
+
+// NewGenerationUsageRepository creates a new GenerationUsageRepository
+func NewGenerationUsageRepository() *GenerationUsageRepository {
+	return &GenerationUsageRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// GetMonthlyLimit gets the monthly generation limit configured for a user, or else for their organization.
+// It returns sql.ErrNoRows when neither has an explicit limit.
+func (r *GenerationUsageRepository) GetMonthlyLimit(ctx context.Context, userID string) (int, error) {
+	query := `SELECT COALESCE(q.monthly_limit, o.monthly_quota)
+	FROM users u
+	JOIN organizations o ON o.id = u.organization_id
+	LEFT JOIN generation_quotas q ON q.user_id = u.id
+	WHERE u.id = ?`
+
+	var limit sql.NullInt64
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&limit); err != nil {
+		return 0, err
+	}
+	if !limit.Valid {
+		return 0, sql.ErrNoRows
+	}
+
+	return int(limit.Int64), nil
+}
+
+// SetMonthlyLimit sets the monthly generation limit for a user of an organization.
+// It returns sql.ErrNoRows for users of other organizations.
+func (r *GenerationUsageRepository) SetMonthlyLimit(ctx context.Context, organizationID, userID string, limit int) error {
+	query := `INSERT INTO generation_quotas (user_id, monthly_limit, created_at, updated_at)
+	SELECT u.id, ?, ?, ? FROM users u WHERE u.id = ? AND u.organization_id = ?
+	ON DUPLICATE KEY UPDATE generation_quotas.monthly_limit = VALUES(monthly_limit), generation_quotas.updated_at = VALUES(updated_at)`
+
+	now := time.Now()
+	target := userTarget("generation_quota", "generation_quotas", userID)
+	saved, err := auditedExec(ctx, r.db, target, query, limit, now, now, userID, organizationID)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Reserve counts one generation against the user's usage for the period.
+// It returns false without counting anything when the limit has already been reached.
+func (r *GenerationUsageRepository) Reserve(ctx context.Context, userID, period string, limit int) (bool, error) {
+	now := time.Now()
+	_, err := r.db.ExecContext(ctx,
+		"INSERT IGNORE INTO generation_usage (user_id, period, generations, created_at, updated_at) VALUES (?, ?, 0, ?, ?)",
+		userID, period, now, now,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE generation_usage SET generations = generations + 1, updated_at = ? WHERE user_id = ? AND period = ? AND generations < ?",
+		now, userID, period, limit,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// Release gives back a generation previously counted by Reserve
+func (r *GenerationUsageRepository) Release(ctx context.Context, userID, period string) error {
+	query := "UPDATE generation_usage SET generations = generations - 1, updated_at = ? WHERE user_id = ? AND period = ? AND generations > 0"
+	_, err := r.db.ExecContext(ctx, query, time.Now(), userID, period)
+	return err
+}
+
+// Reset clears the usage for the period of a user of an organization
+func (r *GenerationUsageRepository) Reset(ctx context.Context, organizationID, userID, period string) error {
+	query := `UPDATE generation_usage SET generations = 0, updated_at = ?
+	WHERE user_id = ? AND period = ? AND user_id IN (SELECT u.id FROM users u WHERE u.organization_id = ?)`
+	target := auditTarget{
+		entityType: "generation_usage",
+		entityID:   userID + "/" + period,
+		table:      "generation_usage",
+		where:      "user_id = ? AND period = ?",
+		args:       []interface{}{userID, period},
+	}
+	_, err := auditedExec(ctx, r.db, target, query, time.Now(), userID, period, organizationID)
+	return err
+}
+
+// GetByUserID gets the usage for the period of a user of an organization.
+// Users without an explicit limit are reported with their organization's, or else with defaultLimit.
+// It returns sql.ErrNoRows for users of other organizations.
+func (r *GenerationUsageRepository) GetByUserID(ctx context.Context, organizationID, userID, period string, defaultLimit int) (*models.GenerationUsage, error) {
+	query := `SELECT
+	u.id, ?, COALESCE(g.generations, 0), COALESCE(q.monthly_limit, o.monthly_quota, ?), COALESCE(g.updated_at, u.updated_at)
+	FROM users u
+	JOIN organizations o ON o.id = u.organization_id
+	LEFT JOIN generation_usage g ON g.user_id = u.id AND g.period = ?
+	LEFT JOIN generation_quotas q ON q.user_id = u.id
+	WHERE u.id = ? AND u.organization_id = ?`
+
+	row := r.db.QueryRowContext(ctx, query, period, defaultLimit, period, userID, organizationID)
+
+	usage := &models.GenerationUsage{}
+	err := row.Scan(
+		&usage.UserID,
+		&usage.Period,
+		&usage.Generations,
+		&usage.MonthlyQuota,
+		&usage.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+// GetByPeriod gets the usage of every user of an organization who generated timelines in the period
+func (r *GenerationUsageRepository) GetByPeriod(ctx context.Context, organizationID, period string, defaultLimit int) ([]*models.GenerationUsage, error) {
+	query := `SELECT
+	g.user_id, g.period, g.generations, COALESCE(q.monthly_limit, o.monthly_quota, ?), g.updated_at
+	FROM generation_usage g
+	JOIN users u ON u.id = g.user_id
+	JOIN organizations o ON o.id = u.organization_id
+	LEFT JOIN generation_quotas q ON q.user_id = g.user_id
+	WHERE u.organization_id = ? AND g.period = ? ORDER BY g.generations DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, defaultLimit, organizationID, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usages := []*models.GenerationUsage{}
+	for rows.Next() {
+		usage := &models.GenerationUsage{}
+		err := rows.Scan(
+			&usage.UserID,
+			&usage.Period,
+			&usage.Generations,
+			&usage.MonthlyQuota,
+			&usage.UpdatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+
+	return usages, rows.Err()
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
@@ -11,20 +12,19 @@ import (
 
 // GoalRepository handles database operations for goals
 type GoalRepository struct {
-	db *sql.DB
+	db *timeoutDB
 }
 
 // NewGoalRepository creates a new GoalRepository
 func NewGoalRepository() *GoalRepository {
 	return &GoalRepository{
-		db: database.DB,
+		db: withQueryTimeout(database.DB),
 	}
 }
 
 // Create creates a new goal
-func (r *GoalRepository) Create(userID, title, description, currentLevel, targetLevel string, startDate, targetDate time.Time) (*models.Goal, error) {
+func (r *GoalRepository) Create(ctx context.Context, userID, title, description, currentLevel, targetLevel string, startDate, targetDate time.Time) (*models.Goal, error) {
 	goal := &models.Goal{
-		ID:           uuid.New().String(),
 		UserID:       userID,
 		Title:        title,
 		Description:  description,
@@ -32,28 +32,11 @@ func (r *GoalRepository) Create(userID, title, description, currentLevel, target
 		TargetLevel:  targetLevel,
 		StartDate:    startDate,
 		TargetDate:   targetDate,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
 	}
 
-	query := `INSERT INTO goals 
-	(id, user_id, title, description, current_level, target_level, start_date, target_date, created_at, updated_at) 
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	
-	_, err := r.db.Exec(
-		query, 
-		goal.ID, 
-		goal.UserID, 
-		goal.Title, 
-		goal.Description, 
-		goal.CurrentLevel, 
-		goal.TargetLevel, 
-		goal.StartDate, 
-		goal.TargetDate, 
-		goal.CreatedAt, 
-		goal.UpdatedAt,
-	)
-	
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		return insertGoal(ctx, tx, goal)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -61,17 +44,53 @@ func (r *GoalRepository) Create(userID, title, description, currentLevel, target
 	return goal, nil
 }
 
-// GetByID gets a goal by ID
-func (r *GoalRepository) GetByID(id string) (*models.Goal, error) {
+// insertGoal stores a new goal as an audited write within a transaction, filling in its ID,
+// organization and timestamps
+func insertGoal(ctx context.Context, tx *sql.Tx, goal *models.Goal) error {
+	goal.ID = uuid.New().String()
+	goal.CreatedAt = time.Now()
+	goal.UpdatedAt = goal.CreatedAt
+
+	// Goals belong to their owner's organization
+	err := tx.QueryRowContext(ctx, "SELECT organization_id FROM users WHERE id = ?", goal.UserID).Scan(&goal.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO goals
+	(id, organization_id, user_id, title, description, current_level, target_level, start_date, target_date, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = auditedTx(ctx, tx, rowTarget("goal", "goals", goal.ID), func(tx *sql.Tx) (bool, error) {
+		return execAffected(ctx, tx, query,
+			goal.ID,
+			goal.OrganizationID,
+			goal.UserID,
+			goal.Title,
+			goal.Description,
+			goal.CurrentLevel,
+			goal.TargetLevel,
+			goal.StartDate,
+			goal.TargetDate,
+			goal.CreatedAt,
+			goal.UpdatedAt,
+		)
+	})
+	return err
+}
+
+// GetByID gets a goal of an organization by ID
+func (r *GoalRepository) GetByID(ctx context.Context, organizationID, id string) (*models.Goal, error) {
 	query := `SELECT 
-	id, user_id, title, description, current_level, target_level, start_date, target_date, created_at, updated_at 
-	FROM goals WHERE id = ?`
+	id, organization_id, user_id, title, description, current_level, target_level, start_date, target_date, created_at, updated_at 
+	FROM goals WHERE id = ? AND organization_id = ? AND deleted_at IS NULL`
 	
-	row := r.db.QueryRow(query, id)
+	row := r.db.QueryRowContext(ctx, query, id, organizationID)
 
 	goal := &models.Goal{}
 	err := row.Scan(
 		&goal.ID, 
+		&goal.OrganizationID,
 		&goal.UserID, 
 		&goal.Title, 
 		&goal.Description, 
@@ -90,13 +109,29 @@ func (r *GoalRepository) GetByID(id string) (*models.Goal, error) {
 	return goal, nil
 }
 
-// GetByUserID gets all goals for a user
-func (r *GoalRepository) GetByUserID(userID string) ([]*models.Goal, error) {
+// GetVisible gets a goal of an organization by ID, provided it is the viewer's or shared with them.
+// Goals the viewer cannot see are not found, like those that do not exist.
+func (r *GoalRepository) GetVisible(ctx context.Context, organizationID, id, viewerID string) (*models.Goal, error) {
+	query := `SELECT
+	g.id, g.organization_id, g.user_id, g.title, g.description, g.current_level, g.target_level, g.start_date, g.target_date, g.created_at, g.updated_at
+	FROM goals g WHERE g.id = ? AND g.organization_id = ? AND g.deleted_at IS NULL AND (` + goalRoleColumn + `) IS NOT NULL`
+	goals, err := r.query(ctx, query, id, organizationID, viewerID, viewerID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(goals) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return goals[0], nil
+}
+
+// GetByUserID gets all goals for a user of an organization
+func (r *GoalRepository) GetByUserID(ctx context.Context, organizationID, userID string) ([]*models.Goal, error) {
 	query := `SELECT 
-	id, user_id, title, description, current_level, target_level, start_date, target_date, created_at, updated_at 
-	FROM goals WHERE user_id = ?`
+	id, organization_id, user_id, title, description, current_level, target_level, start_date, target_date, created_at, updated_at 
+	FROM goals WHERE user_id = ? AND organization_id = ? AND deleted_at IS NULL`
 	
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.QueryContext(ctx, query, userID, organizationID)
 	if err != nil {
 		return nil, err
 	}
@@ -107,6 +142,7 @@ func (r *GoalRepository) GetByUserID(userID string) ([]*models.Goal, error) {
 		goal := &models.Goal{}
 		err := rows.Scan(
 			&goal.ID, 
+			&goal.OrganizationID,
 			&goal.UserID, 
 			&goal.Title, 
 			&goal.Description, 
@@ -125,4 +161,102 @@ func (r *GoalRepository) GetByUserID(userID string) ([]*models.Goal, error) {
 	}
 
 	return goals, nil
-}
\ No newline at end of file
+}
+
+// GetVisibleByUserID gets the goals of a user of an organization that a viewer can see: all of them
+// when the viewer is the user, otherwise those shared with the viewer
+func (r *GoalRepository) GetVisibleByUserID(ctx context.Context, organizationID, userID, viewerID string) ([]*models.Goal, error) {
+	query := `SELECT
+	g.id, g.organization_id, g.user_id, g.title, g.description, g.current_level, g.target_level, g.start_date, g.target_date, g.created_at, g.updated_at
+	FROM goals g WHERE g.user_id = ? AND g.organization_id = ? AND g.deleted_at IS NULL AND (` + goalRoleColumn + `) IS NOT NULL`
+	return r.query(ctx, query, userID, organizationID, viewerID, viewerID, viewerID)
+}
+
+// GetSharedWithUserID gets the goals other users of their organization have shared with a user,
+// most recently shared first
+func (r *GoalRepository) GetSharedWithUserID(ctx context.Context, organizationID, userID string) ([]*models.Goal, error) {
+	query := `SELECT
+	g.id, g.organization_id, g.user_id, g.title, g.description, g.current_level, g.target_level, g.start_date, g.target_date, g.created_at, g.updated_at
+	FROM goals g JOIN goal_members m ON m.goal_id = g.id
+	JOIN users u ON u.id = m.user_id AND u.organization_id = g.organization_id
+	WHERE m.user_id = ? AND g.organization_id = ? AND g.deleted_at IS NULL ORDER BY m.created_at DESC`
+	return r.query(ctx, query, userID, organizationID)
+}
+
+func (r *GoalRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.Goal, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []*models.Goal{}
+	for rows.Next() {
+		goal := &models.Goal{}
+		err := rows.Scan(
+			&goal.ID,
+			&goal.OrganizationID,
+			&goal.UserID,
+			&goal.Title,
+			&goal.Description,
+			&goal.CurrentLevel,
+			&goal.TargetLevel,
+			&goal.StartDate,
+			&goal.TargetDate,
+			&goal.CreatedAt,
+			&goal.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+
+	return goals, rows.Err()
+}
+
+// GetProgressByUserID gets every goal of a user of an organization with its task completion counts
+func (r *GoalRepository) GetProgressByUserID(ctx context.Context, organizationID, userID string) ([]*models.GoalProgress, error) {
+	query := `SELECT
+	g.id, g.organization_id, g.user_id, g.title, g.description, g.current_level, g.target_level, g.start_date, g.target_date, g.created_at, g.updated_at,
+	COUNT(t.id), COALESCE(SUM(CASE WHEN t.completed THEN 1 ELSE 0 END), 0)
+	FROM goals g
+	LEFT JOIN timelines tl ON tl.goal_id = g.id AND tl.deleted_at IS NULL
+	LEFT JOIN timeline_tasks t ON t.timeline_id = tl.id AND t.deleted_at IS NULL
+	WHERE g.user_id = ? AND g.organization_id = ? AND g.deleted_at IS NULL
+	GROUP BY g.id
+	ORDER BY g.target_date ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := []*models.GoalProgress{}
+	for rows.Next() {
+		p := &models.GoalProgress{}
+		err := rows.Scan(
+			&p.Goal.ID,
+			&p.Goal.OrganizationID,
+			&p.Goal.UserID,
+			&p.Goal.Title,
+			&p.Goal.Description,
+			&p.Goal.CurrentLevel,
+			&p.Goal.TargetLevel,
+			&p.Goal.StartDate,
+			&p.Goal.TargetDate,
+			&p.Goal.CreatedAt,
+			&p.Goal.UpdatedAt,
+			&p.TotalTasks,
+			&p.CompletedTasks,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+		progress = append(progress, p)
+	}
+
+	return progress, rows.Err()
+}
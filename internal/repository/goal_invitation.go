@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// goalInvitationQuery selects invitations to goals that are not in the trash along with the goal's title
+// and the inviter's email
+const goalInvitationQuery = `SELECT i.id, i.goal_id, g.title, i.email, i.role, u.email, i.created_at
+	FROM goal_invitations i
+	JOIN goals g ON g.id = i.goal_id AND g.deleted_at IS NULL
+	JOIN users u ON u.id = i.invited_by`
+
+// GoalInvitationRepository handles database operations for pending invitations to goals
+type GoalInvitationRepository struct {
+	db *timeoutDB
+}
+
+// NewGoalInvitationRepository creates a new GoalInvitationRepository
+func NewGoalInvitationRepository() *GoalInvitationRepository {
+	return &GoalInvitationRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// Save invites an email address to a goal under a role.
+// Inviting an address that already has a pending invitation to the goal replaces it.
+// It returns sql.ErrNoRows when the goal does not exist. Addresses are not checked against other
+// organizations' users, whose addresses are none of the goal's organization's business.
+func (r *GoalInvitationRepository) Save(ctx context.Context, goalID, email, role, invitedBy string) (*models.GoalInvitation, error) {
+	query := `INSERT INTO goal_invitations (id, goal_id, email, role, invited_by, created_at)
+	SELECT ?, g.id, ?, ?, ?, ? FROM goals g
+	WHERE g.id = ? AND g.deleted_at IS NULL
+	ON DUPLICATE KEY UPDATE goal_invitations.role = VALUES(role), goal_invitations.invited_by = VALUES(invited_by),
+	goal_invitations.created_at = VALUES(created_at)`
+
+	target := auditTarget{
+		entityType: "goal_invitation",
+		table:      "goal_invitations",
+		where:      "goal_id = ? AND email = ?",
+		args:       []interface{}{goalID, email},
+	}
+	saved, err := auditedExec(ctx, r.db, target, query, uuid.New().String(), email, role, invitedBy, time.Now(), goalID)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, sql.ErrNoRows
+	}
+
+	return scanGoalInvitation(r.db.QueryRowContext(ctx, goalInvitationQuery+" WHERE i.goal_id = ? AND i.email = ?", goalID, email))
+}
+
+// GetByID gets an invitation to a goal of an organization by ID
+func (r *GoalInvitationRepository) GetByID(ctx context.Context, organizationID, id string) (*models.GoalInvitation, error) {
+	return scanGoalInvitation(r.db.QueryRowContext(ctx, goalInvitationQuery+" WHERE g.organization_id = ? AND i.id = ?", organizationID, id))
+}
+
+// GetByEmail gets the pending invitations sent to an email address to goals of an organization
+func (r *GoalInvitationRepository) GetByEmail(ctx context.Context, organizationID, email string) ([]*models.GoalInvitation, error) {
+	return r.query(ctx, goalInvitationQuery+" WHERE g.organization_id = ? AND i.email = ? ORDER BY i.created_at DESC", organizationID, email)
+}
+
+// GetByGoalID gets the pending invitations to a goal
+func (r *GoalInvitationRepository) GetByGoalID(ctx context.Context, goalID string) ([]*models.GoalInvitation, error) {
+	return r.query(ctx, goalInvitationQuery+" WHERE i.goal_id = ? ORDER BY i.created_at DESC", goalID)
+}
+
+// Accept shares the invitation's goal with the user under the invited role and removes the invitation
+func (r *GoalInvitationRepository) Accept(ctx context.Context, invitation *models.GoalInvitation, userID string) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := auditedTx(ctx, tx, memberTarget(invitation.GoalID, userID), func(tx *sql.Tx) (bool, error) {
+			return true, saveGoalMember(ctx, tx, invitation.GoalID, userID, invitation.Role)
+		})
+		if err != nil {
+			return err
+		}
+		_, err = auditedTx(ctx, tx, rowTarget("goal_invitation", "goal_invitations", invitation.ID), func(tx *sql.Tx) (bool, error) {
+			return execAffected(ctx, tx, "DELETE FROM goal_invitations WHERE id = ?", invitation.ID)
+		})
+		return err
+	})
+}
+
+// Delete deletes an invitation
+func (r *GoalInvitationRepository) Delete(ctx context.Context, id string) error {
+	_, err := auditedExec(ctx, r.db, rowTarget("goal_invitation", "goal_invitations", id), "DELETE FROM goal_invitations WHERE id = ?", id)
+	return err
+}
+
+func (r *GoalInvitationRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.GoalInvitation, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*models.GoalInvitation{}
+	for rows.Next() {
+		invitation, err := scanGoalInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+func scanGoalInvitation(row rowScanner) (*models.GoalInvitation, error) {
+	invitation := &models.GoalInvitation{}
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.GoalID,
+		&invitation.GoalTitle,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// goalRoleColumn selects the role a user has on the goal g: owner, the role the goal is shared with them
+// under, or NULL. It is always NULL for users of another organization. It takes the user's ID three times.
+const goalRoleColumn = `CASE
+	WHEN NOT EXISTS (SELECT 1 FROM users ru WHERE ru.id = ? AND ru.organization_id = g.organization_id) THEN NULL
+	WHEN g.user_id = ? THEN 'owner'
+	ELSE (SELECT m.role FROM goal_members m WHERE m.goal_id = g.id AND m.user_id = ?) END`
+
+// GoalMemberRepository handles database operations for the users goals are shared with
+type GoalMemberRepository struct {
+	db *timeoutDB
+}
+
+// NewGoalMemberRepository creates a new GoalMemberRepository
+func NewGoalMemberRepository() *GoalMemberRepository {
+	return &GoalMemberRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// GetRole gets the role a user has on a goal, or an empty role if the goal is not shared with them.
+// It returns sql.ErrNoRows when the goal does not exist.
+func (r *GoalMemberRepository) GetRole(ctx context.Context, goalID, userID string) (string, error) {
+	query := "SELECT " + goalRoleColumn + " FROM goals g WHERE g.id = ? AND g.deleted_at IS NULL"
+	return scanRole(r.db.QueryRowContext(ctx, query, userID, userID, userID, goalID))
+}
+
+// GetByGoalID gets everyone with access to a goal, its owner first
+func (r *GoalMemberRepository) GetByGoalID(ctx context.Context, goalID string) ([]*models.GoalMember, error) {
+	query := `SELECT g.id AS goal_id, u.id AS user_id, u.email, 'owner' AS role, g.created_at FROM goals g
+	JOIN users u ON u.id = g.user_id WHERE g.id = ? AND g.deleted_at IS NULL
+	UNION ALL
+	SELECT m.goal_id, u.id, u.email, m.role, m.created_at FROM goal_members m
+	JOIN goals g ON g.id = m.goal_id AND g.deleted_at IS NULL
+	JOIN users u ON u.id = m.user_id AND u.organization_id = g.organization_id WHERE m.goal_id = ?
+	ORDER BY role = 'owner' DESC, created_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, goalID, goalID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*models.GoalMember{}
+	for rows.Next() {
+		member := &models.GoalMember{}
+		if err := rows.Scan(&member.GoalID, &member.UserID, &member.Email, &member.Role, &member.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// Save shares a goal with a user under a role, replacing any role they had.
+// It returns ErrOtherOrganization when the user belongs to a different organization than the goal.
+func (r *GoalMemberRepository) Save(ctx context.Context, goalID, userID, role string) error {
+	_, err := audited(ctx, r.db, memberTarget(goalID, userID), func(tx *sql.Tx) (bool, error) {
+		return true, saveGoalMember(ctx, tx, goalID, userID, role)
+	})
+	return err
+}
+
+// Delete stops sharing a goal with a user
+func (r *GoalMemberRepository) Delete(ctx context.Context, goalID, userID string) error {
+	query := "DELETE FROM goal_members WHERE goal_id = ? AND user_id = ?"
+	_, err := auditedExec(ctx, r.db, memberTarget(goalID, userID), query, goalID, userID)
+	return err
+}
+
+func saveGoalMember(ctx context.Context, db execer, goalID, userID, role string) error {
+	query := `INSERT INTO goal_members (goal_id, user_id, role, created_at, updated_at)
+	SELECT g.id, u.id, ?, ?, ? FROM goals g JOIN users u ON u.organization_id = g.organization_id
+	WHERE g.id = ? AND g.deleted_at IS NULL AND u.id = ?
+	ON DUPLICATE KEY UPDATE goal_members.role = VALUES(role), goal_members.updated_at = VALUES(updated_at)`
+
+	now := time.Now()
+	result, err := db.ExecContext(ctx, query, role, now, now, goalID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrOtherOrganization
+	}
+	return nil
+}
+
+// scanRole scans a goalRoleColumn, which is NULL for users a goal is not shared with
+func scanRole(row rowScanner) (string, error) {
+	var role sql.NullString
+	if err := row.Scan(&role); err != nil {
+		return "", err
+	}
+	return role.String, nil
+}
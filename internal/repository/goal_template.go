@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+const goalTemplateColumns = "id, user_id, title, description, goal, current_level, target_level, span_days, tasks, created_at, updated_at"
+
+// GoalTemplateRepository handles database operations for users' saved goal templates
+type GoalTemplateRepository struct {
+	db *timeoutDB
+}
+
+// NewGoalTemplateRepository creates a new GoalTemplateRepository
+func NewGoalTemplateRepository() *GoalTemplateRepository {
+	return &GoalTemplateRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// Create saves a template for its user
+func (r *GoalTemplateRepository) Create(ctx context.Context, template *models.GoalTemplate) error {
+	tasks, err := json.Marshal(template.Tasks)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	template.ID = uuid.New().String()
+	template.CreatedAt = &now
+	template.UpdatedAt = &now
+
+	query := `INSERT INTO goal_templates
+	(id, user_id, title, description, goal, current_level, target_level, span_days, tasks, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = auditedExec(
+		ctx,
+		r.db,
+		rowTarget("goal_template", "goal_templates", template.ID),
+		query,
+		template.ID,
+		template.UserID,
+		template.Title,
+		template.Description,
+		template.Goal,
+		template.CurrentLevel,
+		template.TargetLevel,
+		template.SpanDays,
+		tasks,
+		now,
+		now,
+	)
+	return err
+}
+
+// templateOwnerInOrganization limits goal_templates to those saved by users of the organization given as its argument
+const templateOwnerInOrganization = "user_id IN (SELECT u.id FROM users u WHERE u.organization_id = ?)"
+
+// GetByID gets a template saved by a user of an organization by ID
+func (r *GoalTemplateRepository) GetByID(ctx context.Context, organizationID, id string) (*models.GoalTemplate, error) {
+	query := "SELECT " + goalTemplateColumns + " FROM goal_templates WHERE id = ? AND " + templateOwnerInOrganization
+	return scanGoalTemplate(r.db.QueryRowContext(ctx, query, id, organizationID))
+}
+
+// GetByUserID gets all templates a user of an organization has saved
+func (r *GoalTemplateRepository) GetByUserID(ctx context.Context, organizationID, userID string) ([]*models.GoalTemplate, error) {
+	query := "SELECT " + goalTemplateColumns + " FROM goal_templates WHERE user_id = ? AND " + templateOwnerInOrganization +
+		" ORDER BY created_at ASC"
+
+	rows, err := r.db.QueryContext(ctx, query, userID, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []*models.GoalTemplate{}
+	for rows.Next() {
+		template, err := scanGoalTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+// Delete deletes a template saved by a user of an organization
+func (r *GoalTemplateRepository) Delete(ctx context.Context, organizationID, id string) error {
+	query := "DELETE FROM goal_templates WHERE id = ? AND " + templateOwnerInOrganization
+	_, err := auditedExec(ctx, r.db, rowTarget("goal_template", "goal_templates", id), query, id, organizationID)
+	return err
+}
+
+func scanGoalTemplate(row rowScanner) (*models.GoalTemplate, error) {
+	template := &models.GoalTemplate{}
+	var description sql.NullString
+	var tasks []byte
+	var createdAt, updatedAt time.Time
+
+	err := row.Scan(
+		&template.ID,
+		&template.UserID,
+		&template.Title,
+		&description,
+		&template.Goal,
+		&template.CurrentLevel,
+		&template.TargetLevel,
+		&template.SpanDays,
+		&tasks,
+		&createdAt,
+		&updatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	template.Description = description.String
+	template.CreatedAt = &createdAt
+	template.UpdatedAt = &updatedAt
+
+	if err := json.Unmarshal(tasks, &template.Tasks); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// Groupings supported when summarizing LLM usage
+const (
+	UsageByUser  = "user"
+	UsageByDay   = "day"
+	UsageByModel = "model"
+)
+
+// usageGroupings maps each grouping to the column expression it aggregates on
+var usageGroupings = map[string]string{
+	UsageByUser:  "COALESCE(user_id, '')",
+	UsageByDay:   "DATE_FORMAT(created_at, '%Y-%m-%d')",
+	UsageByModel: "model",
+}
+
+// UsageFilter restricts which LLM calls are included in usage queries.
+// Only calls made by users of the organization are ever included.
+type UsageFilter struct {
+	OrganizationID string
+	UserID         string
+	From           *time.Time
+	To             *time.Time
+}
+
+// where builds the WHERE clause and arguments for the filter
+func (f UsageFilter) where() (string, []interface{}) {
+	conditions := []string{"user_id IN (SELECT u.id FROM users u WHERE u.organization_id = ?)"}
+	args := []interface{}{f.OrganizationID}
+
+	if f.UserID != "" {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *f.From)
+	}
+	if f.To != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *f.To)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// LLMUsageRepository handles database operations for LLM usage records
+type LLMUsageRepository struct {
+	db *timeoutDB
+}
+
+// NewLLMUsageRepository creates a new LLMUsageRepository
+func NewLLMUsageRepository() *LLMUsageRepository {
+	return &LLMUsageRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// Create records an LLM call
+func (r *LLMUsageRepository) Create(ctx context.Context, usage *models.LLMUsage) error {
+	if usage.ID == "" {
+		usage.ID = uuid.New().String()
+	}
+	if usage.CreatedAt.IsZero() {
+		usage.CreatedAt = time.Now()
+	}
+
+	query := `INSERT INTO llm_usage
+	(id, user_id, goal_id, timeline_id, operation, model, prompt_tokens, completion_tokens, latency_ms, estimated_cost, success, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.ExecContext(ctx,
+		query,
+		usage.ID,
+		nullString(usage.UserID),
+		nullString(usage.GoalID),
+		nullString(usage.TimelineID),
+		usage.Operation,
+		usage.Model,
+		usage.PromptTokens,
+		usage.CompletionTokens,
+		usage.LatencyMs,
+		usage.EstimatedCost,
+		usage.Success,
+		usage.CreatedAt,
+	)
+
+	return err
+}
+
+// AttachTimeline links a recorded LLM call to the goal and timeline it produced
+func (r *LLMUsageRepository) AttachTimeline(ctx context.Context, id, goalID, timelineID string) error {
+	query := "UPDATE llm_usage SET goal_id = ?, timeline_id = ? WHERE id = ?"
+	_, err := r.db.ExecContext(ctx, query, goalID, timelineID, id)
+	return err
+}
+
+// Summarize aggregates LLM calls matching the filter by user, day or model
+func (r *LLMUsageRepository) Summarize(ctx context.Context, groupBy string, filter UsageFilter) ([]*models.LLMUsageSummary, error) {
+	key, ok := usageGroupings[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown usage grouping %q", groupBy)
+	}
+
+	where, args := filter.where()
+	query := fmt.Sprintf(`SELECT
+	%s AS grouping_key,
+	COUNT(*),
+	COALESCE(SUM(CASE WHEN success THEN 0 ELSE 1 END), 0),
+	COALESCE(SUM(prompt_tokens), 0),
+	COALESCE(SUM(completion_tokens), 0),
+	COALESCE(SUM(estimated_cost), 0),
+	COALESCE(AVG(latency_ms), 0)
+	FROM llm_usage WHERE %s GROUP BY grouping_key ORDER BY grouping_key ASC`, key, where)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []*models.LLMUsageSummary{}
+	for rows.Next() {
+		summary := &models.LLMUsageSummary{}
+		err := rows.Scan(
+			&summary.Key,
+			&summary.Calls,
+			&summary.Failures,
+			&summary.PromptTokens,
+			&summary.CompletionTokens,
+			&summary.EstimatedCost,
+			&summary.AverageLatencyMs,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, rows.Err()
+}
+
+// GetMostExpensive gets the costliest LLM calls matching the filter
+func (r *LLMUsageRepository) GetMostExpensive(ctx context.Context, filter UsageFilter, limit int) ([]*models.LLMUsage, error) {
+	where, args := filter.where()
+	query := fmt.Sprintf(`SELECT
+	id, COALESCE(user_id, ''), COALESCE(goal_id, ''), COALESCE(timeline_id, ''), operation, model,
+	prompt_tokens, completion_tokens, latency_ms, estimated_cost, success, created_at
+	FROM llm_usage WHERE %s ORDER BY estimated_cost DESC, created_at DESC LIMIT ?`, where)
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usages := []*models.LLMUsage{}
+	for rows.Next() {
+		usage := &models.LLMUsage{}
+		err := rows.Scan(
+			&usage.ID,
+			&usage.UserID,
+			&usage.GoalID,
+			&usage.TimelineID,
+			&usage.Operation,
+			&usage.Model,
+			&usage.PromptTokens,
+			&usage.CompletionTokens,
+			&usage.LatencyMs,
+			&usage.EstimatedCost,
+			&usage.Success,
+			&usage.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+
+	return usages, rows.Err()
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// Defaults for users without saved notification preferences
+const (
+	defaultEmailReminders = true
+	defaultWeeklyDigest   = true
+	defaultDigestDay      = int(time.Monday)
+)
+
+const notificationPreferencesQuery = `SELECT
+	u.id, u.organization_id, u.email, u.time_zone,
+	COALESCE(p.email_reminders, ?), COALESCE(p.weekly_digest, ?), COALESCE(p.digest_day, ?),
+	COALESCE(p.unsubscribe_token, ''), p.last_digest_at,
+	COALESCE(p.created_at, u.created_at), COALESCE(p.updated_at, u.updated_at)
+	FROM users u
+	LEFT JOIN notification_preferences p ON p.user_id = u.id`
+
+// NotificationRepository handles database operations for notification preferences
+type NotificationRepository struct {
+	db *timeoutDB
+}
+
+// NewNotificationRepository creates a new NotificationRepository
+func NewNotificationRepository() *NotificationRepository {
+	return &NotificationRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// GetPreferences gets a user's notification preferences, with defaults for anything not saved.
+// UnsubscribeToken is empty until preferences have been saved.
+func (r *NotificationRepository) GetPreferences(ctx context.Context, userID string) (*models.NotificationPreferences, error) {
+	query := notificationPreferencesQuery + " WHERE u.id = ?"
+	return scanNotificationPreferences(r.db.QueryRowContext(ctx, query, defaultEmailReminders, defaultWeeklyDigest, defaultDigestDay, userID))
+}
+
+// GetByUnsubscribeToken gets the preferences an unsubscribe token belongs to
+func (r *NotificationRepository) GetByUnsubscribeToken(ctx context.Context, token string) (*models.NotificationPreferences, error) {
+	query := notificationPreferencesQuery + " WHERE p.unsubscribe_token = ?"
+	return scanNotificationPreferences(r.db.QueryRowContext(ctx, query, defaultEmailReminders, defaultWeeklyDigest, defaultDigestDay, token))
+}
+
+// SavePreferences creates or updates a user's notification preferences
+func (r *NotificationRepository) SavePreferences(ctx context.Context, preferences *models.NotificationPreferences) error {
+	query := `INSERT INTO notification_preferences
+	(user_id, email_reminders, weekly_digest, digest_day, unsubscribe_token, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE email_reminders = VALUES(email_reminders), weekly_digest = VALUES(weekly_digest),
+	digest_day = VALUES(digest_day), updated_at = VALUES(updated_at)`
+
+	now := time.Now()
+	_, err := auditedExec(
+		ctx,
+		r.db,
+		userTarget("notification_preferences", "notification_preferences", preferences.UserID),
+		query,
+		preferences.UserID,
+		preferences.EmailReminders,
+		preferences.WeeklyDigest,
+		preferences.DigestDay,
+		preferences.UnsubscribeToken,
+		now,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+
+	preferences.UpdatedAt = now
+
+	return nil
+}
+
+// GetDigestTimeZones gets the time zones of users who want a weekly digest and have not had one since sentBefore
+func (r *NotificationRepository) GetDigestTimeZones(ctx context.Context, sentBefore time.Time) ([]string, error) {
+	query := `SELECT DISTINCT u.time_zone FROM users u
+	LEFT JOIN notification_preferences p ON p.user_id = u.id
+	WHERE COALESCE(p.weekly_digest, ?) = TRUE AND (p.last_digest_at IS NULL OR p.last_digest_at < ?)`
+
+	rows, err := r.db.QueryContext(ctx, query, defaultWeeklyDigest, sentBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	timeZones := []string{}
+	for rows.Next() {
+		var timeZone string
+		if err := rows.Scan(&timeZone); err != nil {
+			return nil, err
+		}
+		timeZones = append(timeZones, timeZone)
+	}
+
+	return timeZones, rows.Err()
+}
+
+// GetDigestRecipients gets users who want a weekly digest, have not had one since sentBefore and
+// whose digest day it is. weekdays maps each time zone to the weekday it currently is there.
+func (r *NotificationRepository) GetDigestRecipients(ctx context.Context, sentBefore time.Time, weekdays map[string]int) ([]*models.NotificationPreferences, error) {
+	recipients := []*models.NotificationPreferences{}
+	if len(weekdays) == 0 {
+		return recipients, nil
+	}
+
+	args := []interface{}{defaultEmailReminders, defaultWeeklyDigest, defaultDigestDay, defaultWeeklyDigest, sentBefore, defaultDigestDay}
+	due := make([]string, 0, len(weekdays))
+	for timeZone, weekday := range weekdays {
+		due = append(due, "(?, ?)")
+		args = append(args, timeZone, weekday)
+	}
+
+	query := notificationPreferencesQuery + ` WHERE COALESCE(p.weekly_digest, ?) = TRUE
+	AND (p.last_digest_at IS NULL OR p.last_digest_at < ?)
+	AND (u.time_zone, COALESCE(p.digest_day, ?)) IN (` + strings.Join(due, ", ") + ")"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		preferences, err := scanNotificationPreferences(rows)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, preferences)
+	}
+
+	return recipients, rows.Err()
+}
+
+// MarkDigestSent claims this week's digest for a user by recording that it was sent at sentAt, unless
+// one was already sent since sentBefore. It returns false when another run already claimed it.
+// sentAt is stored to the second, so pass it truncated to find the claim again with UnmarkDigestSent.
+func (r *NotificationRepository) MarkDigestSent(ctx context.Context, userID string, sentAt, sentBefore time.Time) (bool, error) {
+	query := `UPDATE notification_preferences SET last_digest_at = ?
+	WHERE user_id = ? AND (last_digest_at IS NULL OR last_digest_at < ?)`
+	return execAffected(ctx, r.db, query, sentAt, userID, sentBefore)
+}
+
+// UnmarkDigestSent gives back the claim made at sentAt on a digest that failed to go out, restoring
+// when the one before it was sent, so that a later run sends it
+func (r *NotificationRepository) UnmarkDigestSent(ctx context.Context, userID string, sentAt time.Time, previous *time.Time) error {
+	var lastDigestAt sql.NullTime
+	if previous != nil {
+		lastDigestAt = sql.NullTime{Time: *previous, Valid: true}
+	}
+
+	query := "UPDATE notification_preferences SET last_digest_at = ? WHERE user_id = ? AND last_digest_at = ?"
+	_, err := r.db.ExecContext(ctx, query, lastDigestAt, userID, sentAt)
+	return err
+}
+
+func scanNotificationPreferences(row rowScanner) (*models.NotificationPreferences, error) {
+	preferences := &models.NotificationPreferences{}
+	var lastDigestAt sql.NullTime
+
+	err := row.Scan(
+		&preferences.UserID,
+		&preferences.OrganizationID,
+		&preferences.Email,
+		&preferences.TimeZone,
+		&preferences.EmailReminders,
+		&preferences.WeeklyDigest,
+		&preferences.DigestDay,
+		&preferences.UnsubscribeToken,
+		&lastDigestAt,
+		&preferences.CreatedAt,
+		&preferences.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if lastDigestAt.Valid {
+		preferences.LastDigestAt = &lastDigestAt.Time
+	}
+
+	return preferences, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/database"
+)
+
+// OccurrenceRepository handles database operations for the completed occurrences of recurring tasks
+type OccurrenceRepository struct {
+	db *timeoutDB
+}
+
+// NewOccurrenceRepository creates a new OccurrenceRepository
+func NewOccurrenceRepository() *OccurrenceRepository {
+	return &OccurrenceRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// occurrenceTarget targets the completed occurrence of a task on a date
+func occurrenceTarget(taskID string, occursOn time.Time) auditTarget {
+	return auditTarget{
+		entityType: "task_occurrence",
+		entityID:   taskID + "/" + occursOn.Format("2006-01-02"),
+		table:      "task_occurrences",
+		where:      "task_id = ? AND occurs_on = ?",
+		args:       []interface{}{taskID, occursOn},
+	}
+}
+
+// occurrenceInOrganization limits task_occurrences to those of tasks on goals of the organization given as its argument
+const occurrenceInOrganization = `task_id IN (SELECT t.id FROM timeline_tasks t JOIN timelines tl ON tl.id = t.timeline_id
+	JOIN goals g ON g.id = tl.goal_id WHERE g.organization_id = ?)`
+
+// GetCompleted gets when each completed occurrence of a task on a goal of an organization was
+// completed, by occurrence date
+func (r *OccurrenceRepository) GetCompleted(ctx context.Context, organizationID, taskID string) (map[time.Time]time.Time, error) {
+	query := "SELECT occurs_on, completed_at FROM task_occurrences WHERE task_id = ? AND " + occurrenceInOrganization
+
+	rows, err := r.db.QueryContext(ctx, query, taskID, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	completed := map[time.Time]time.Time{}
+	for rows.Next() {
+		var occursOn, completedAt time.Time
+		if err := rows.Scan(&occursOn, &completedAt); err != nil {
+			return nil, err
+		}
+		completed[occursOn] = completedAt
+	}
+
+	return completed, rows.Err()
+}
+
+// SetCompleted marks an occurrence of a task on a goal of an organization as completed or not.
+// Completing an occurrence that is already completed keeps its original completion time.
+func (r *OccurrenceRepository) SetCompleted(ctx context.Context, organizationID, taskID string, occursOn time.Time, completed bool) error {
+	target := occurrenceTarget(taskID, occursOn)
+
+	if !completed {
+		query := "DELETE FROM task_occurrences WHERE task_id = ? AND occurs_on = ? AND " + occurrenceInOrganization
+		_, err := auditedExec(ctx, r.db, target, query, taskID, occursOn, organizationID)
+		return err
+	}
+
+	query := `INSERT IGNORE INTO task_occurrences (task_id, occurs_on, completed_at)
+	SELECT t.id, ?, ? FROM timeline_tasks t JOIN timelines tl ON tl.id = t.timeline_id JOIN goals g ON g.id = tl.goal_id
+	WHERE t.id = ? AND g.organization_id = ?`
+	_, err := auditedExec(ctx, r.db, target, query, occursOn, time.Now(), taskID, organizationID)
+	return err
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// ErrOtherOrganization is returned when an operation would reach from one organization into another
+var ErrOtherOrganization = errors.New("not part of this organization")
+
+const organizationColumns = "o.id, o.name, o.default_model, o.prompt_template, o.monthly_quota, o.created_at, o.updated_at"
+
+// OrganizationRepository handles database operations for organizations
+type OrganizationRepository struct {
+	db *timeoutDB
+}
+
+// NewOrganizationRepository creates a new OrganizationRepository
+func NewOrganizationRepository() *OrganizationRepository {
+	return &OrganizationRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// Create creates a new organization with the server's default settings
+func (r *OrganizationRepository) Create(ctx context.Context, name string) (*models.Organization, error) {
+	organization := &models.Organization{
+		ID:        uuid.New().String(),
+		Name:      name,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	query := "INSERT INTO organizations (id, name, created_at, updated_at) VALUES (?, ?, ?, ?)"
+	_, err := auditedExec(ctx, r.db, rowTarget("organization", "organizations", organization.ID), query, organization.ID, organization.Name, organization.CreatedAt, organization.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return organization, nil
+}
+
+// GetByID gets an organization by ID
+func (r *OrganizationRepository) GetByID(ctx context.Context, id string) (*models.Organization, error) {
+	query := "SELECT " + organizationColumns + " FROM organizations o WHERE o.id = ?"
+	return scanOrganization(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetByUserID gets the organization a user belongs to
+func (r *OrganizationRepository) GetByUserID(ctx context.Context, userID string) (*models.Organization, error) {
+	query := "SELECT " + organizationColumns + " FROM organizations o JOIN users u ON u.organization_id = o.id WHERE u.id = ?"
+	return scanOrganization(r.db.QueryRowContext(ctx, query, userID))
+}
+
+// UpdateSettings replaces an organization's settings
+func (r *OrganizationRepository) UpdateSettings(ctx context.Context, id string, settings models.OrganizationSettings) error {
+	query := "UPDATE organizations SET default_model = ?, prompt_template = ?, monthly_quota = ?, updated_at = ? WHERE id = ?"
+
+	var quota sql.NullInt64
+	if settings.MonthlyQuota != nil {
+		quota = sql.NullInt64{Int64: int64(*settings.MonthlyQuota), Valid: true}
+	}
+
+	_, err := auditedExec(ctx, r.db, rowTarget("organization", "organizations", id), query, nullString(settings.DefaultModel), nullString(settings.PromptTemplate), quota, time.Now(), id)
+	return err
+}
+
+func scanOrganization(row rowScanner) (*models.Organization, error) {
+	organization := &models.Organization{}
+	var defaultModel, promptTemplate sql.NullString
+	var quota sql.NullInt64
+
+	err := row.Scan(
+		&organization.ID,
+		&organization.Name,
+		&defaultModel,
+		&promptTemplate,
+		&quota,
+		&organization.CreatedAt,
+		&organization.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	organization.Settings.DefaultModel = defaultModel.String
+	organization.Settings.PromptTemplate = promptTemplate.String
+	if quota.Valid {
+		monthlyQuota := int(quota.Int64)
+		organization.Settings.MonthlyQuota = &monthlyQuota
+	}
+
+	return organization, nil
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// ReminderRepository handles database operations for reminder settings and sent reminders
+type ReminderRepository struct {
+	db *timeoutDB
+}
+
+// NewReminderRepository creates a new ReminderRepository
+func NewReminderRepository() *ReminderRepository {
+	return &ReminderRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// GetSettings gets a user's reminder settings.
+// It returns sql.ErrNoRows when the user has not configured reminders.
+func (r *ReminderRepository) GetSettings(ctx context.Context, userID string) (*models.ReminderSettings, error) {
+	query := `SELECT user_id, enabled, start_lead_days, due_lead_days, overdue, created_at, updated_at
+	FROM reminder_settings WHERE user_id = ?`
+
+	settings := &models.ReminderSettings{}
+	var startLeadDays, dueLeadDays []byte
+	var createdAt, updatedAt time.Time
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&settings.UserID,
+		&settings.Enabled,
+		&startLeadDays,
+		&dueLeadDays,
+		&settings.Overdue,
+		&createdAt,
+		&updatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	settings.CreatedAt = &createdAt
+	settings.UpdatedAt = &updatedAt
+
+	if err := json.Unmarshal(startLeadDays, &settings.StartLeadDays); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(dueLeadDays, &settings.DueLeadDays); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// SaveSettings creates or replaces a user's reminder settings
+func (r *ReminderRepository) SaveSettings(ctx context.Context, settings *models.ReminderSettings) error {
+	startLeadDays, err := json.Marshal(settings.StartLeadDays)
+	if err != nil {
+		return err
+	}
+	dueLeadDays, err := json.Marshal(settings.DueLeadDays)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO reminder_settings
+	(user_id, enabled, start_lead_days, due_lead_days, overdue, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE enabled = VALUES(enabled), start_lead_days = VALUES(start_lead_days),
+	due_lead_days = VALUES(due_lead_days), overdue = VALUES(overdue), updated_at = VALUES(updated_at)`
+
+	now := time.Now()
+	_, err = auditedExec(
+		ctx,
+		r.db,
+		userTarget("reminder_settings", "reminder_settings", settings.UserID),
+		query,
+		settings.UserID,
+		settings.Enabled,
+		startLeadDays,
+		dueLeadDays,
+		settings.Overdue,
+		now,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+
+	if settings.CreatedAt == nil {
+		settings.CreatedAt = &now
+	}
+	settings.UpdatedAt = &now
+
+	return nil
+}
+
+// Record stores a reminder unless the same reminder was already sent.
+// It returns true only the first time, so each reminder fires once.
+func (r *ReminderRepository) Record(ctx context.Context, reminder *models.TaskReminder) (bool, error) {
+	if reminder.SentAt.IsZero() {
+		reminder.SentAt = time.Now()
+	}
+
+	query := `INSERT IGNORE INTO task_reminders
+	(task_id, kind, lead_days, due_on, user_id, message, sent_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	return execAffected(ctx,
+		r.db,
+		query,
+		reminder.TaskID,
+		reminder.Kind,
+		reminder.LeadDays,
+		reminder.DueOn,
+		reminder.UserID,
+		reminder.Message,
+		reminder.SentAt,
+	)
+}
+
+// GetByUserID gets the reminders most recently sent to a user
+func (r *ReminderRepository) GetByUserID(ctx context.Context, userID string, limit int) ([]*models.TaskReminder, error) {
+	query := `SELECT task_id, user_id, kind, lead_days, due_on, message, sent_at
+	FROM task_reminders WHERE user_id = ? ORDER BY sent_at DESC LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []*models.TaskReminder{}
+	for rows.Next() {
+		reminder := &models.TaskReminder{}
+		err := rows.Scan(
+			&reminder.TaskID,
+			&reminder.UserID,
+			&reminder.Kind,
+			&reminder.LeadDays,
+			&reminder.DueOn,
+			&reminder.Message,
+			&reminder.SentAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
@@ -1,61 +1,45 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/duration"
 	"github.com/jukemori/timeline-generator/internal/models"
 )
 
 // TaskRepository handles database operations for timeline tasks
 type TaskRepository struct {
-	db *sql.DB
+	db *timeoutDB
 }
 
 // NewTaskRepository creates a new TaskRepository
 func NewTaskRepository() *TaskRepository {
 	return &TaskRepository{
-		db: database.DB,
+		db: withQueryTimeout(database.DB),
 	}
 }
 
 // Create creates a new timeline task
-func (r *TaskRepository) Create(timelineID, title, description, duration string, startDate, endDate time.Time, priority int) (*models.TimelineTask, error) {
+func (r *TaskRepository) Create(ctx context.Context, timelineID, title, description string, taskDuration models.Duration, effortHours float64, recurrence string, startDate, endDate time.Time, priority int) (*models.TimelineTask, error) {
 	task := &models.TimelineTask{
-		ID:          uuid.New().String(),
 		TimelineID:  timelineID,
 		Title:       title,
 		Description: description,
 		StartDate:   startDate,
 		EndDate:     endDate,
-		Duration:    duration,
+		Duration:    taskDuration,
+		EffortHours: effortHours,
+		Recurrence:  recurrence,
 		Priority:    priority,
-		Completed:   false,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
 	}
 
-	query := `INSERT INTO timeline_tasks 
-	(id, timeline_id, title, description, start_date, end_date, duration, priority, completed, created_at, updated_at) 
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	
-	_, err := r.db.Exec(
-		query, 
-		task.ID, 
-		task.TimelineID, 
-		task.Title, 
-		task.Description, 
-		task.StartDate, 
-		task.EndDate, 
-		task.Duration, 
-		task.Priority, 
-		task.Completed, 
-		task.CreatedAt, 
-		task.UpdatedAt,
-	)
-	
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		return insertTask(ctx, tx, task)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -63,28 +47,48 @@ func (r *TaskRepository) Create(timelineID, title, description, duration string,
 	return task, nil
 }
 
-// GetByID gets a task by ID
-func (r *TaskRepository) GetByID(id string) (*models.TimelineTask, error) {
-	query := `SELECT 
-	id, timeline_id, title, description, start_date, end_date, duration, priority, completed, created_at, updated_at 
-	FROM timeline_tasks WHERE id = ?`
+// insertTask stores a new, uncompleted task as an audited write within a transaction, filling in its
+// ID and timestamps
+func insertTask(ctx context.Context, tx *sql.Tx, task *models.TimelineTask) error {
+	task.ID = uuid.New().String()
+	task.Completed = false
+	task.CreatedAt = time.Now()
+	task.UpdatedAt = task.CreatedAt
+
+	query := `INSERT INTO timeline_tasks
+	(id, timeline_id, title, description, start_date, end_date, duration, duration_value, duration_unit, effort_hours, recurrence, priority, completed, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := auditedTx(ctx, tx, rowTarget("task", "timeline_tasks", task.ID), func(tx *sql.Tx) (bool, error) {
+		return execAffected(ctx, tx, query,
+			task.ID,
+			task.TimelineID,
+			task.Title,
+			task.Description,
+			task.StartDate,
+			task.EndDate,
+			task.Duration.Label,
+			task.Duration.Value,
+			task.Duration.Unit,
+			task.EffortHours,
+			nullString(task.Recurrence),
+			task.Priority,
+			task.Completed,
+			task.CreatedAt,
+			task.UpdatedAt,
+		)
+	})
+	return err
+}
+
+// GetByID gets a task on a goal of an organization by ID
+func (r *TaskRepository) GetByID(ctx context.Context, organizationID, id string) (*models.TimelineTask, error) {
+	query := "SELECT " + taskColumns + " FROM timeline_tasks WHERE id = ? AND deleted_at IS NULL AND " + inOrganization
 	
-	row := r.db.QueryRow(query, id)
+	row := r.db.QueryRowContext(ctx, query, id, organizationID)
 
 	task := &models.TimelineTask{}
-	err := row.Scan(
-		&task.ID, 
-		&task.TimelineID, 
-		&task.Title, 
-		&task.Description, 
-		&task.StartDate, 
-		&task.EndDate, 
-		&task.Duration, 
-		&task.Priority, 
-		&task.Completed, 
-		&task.CreatedAt, 
-		&task.UpdatedAt,
-	)
+	err := scanTask(row, task)
 	
 	if err != nil {
 		return nil, err
@@ -93,13 +97,26 @@ func (r *TaskRepository) GetByID(id string) (*models.TimelineTask, error) {
 	return task, nil
 }
 
-// GetByTimelineID gets all tasks for a timeline
-func (r *TaskRepository) GetByTimelineID(timelineID string) ([]models.TimelineTask, error) {
-	query := `SELECT 
-	id, timeline_id, title, description, start_date, end_date, duration, priority, completed, created_at, updated_at 
-	FROM timeline_tasks WHERE timeline_id = ? ORDER BY start_date ASC, priority DESC`
+// GetVisible gets a task on a goal of an organization by ID, provided the goal is the viewer's or
+// shared with them. Tasks the viewer cannot see are not found, like those that do not exist.
+func (r *TaskRepository) GetVisible(ctx context.Context, organizationID, id, viewerID string) (*models.TimelineTask, error) {
+	query := "SELECT " + taskColumns + ` FROM timeline_tasks WHERE id = ? AND deleted_at IS NULL AND timeline_id IN (
+	SELECT tl.id FROM timelines tl JOIN goals g ON g.id = tl.goal_id WHERE g.organization_id = ? AND (` + goalRoleColumn + `) IS NOT NULL)`
+
+	task := &models.TimelineTask{}
+	if err := scanTask(r.db.QueryRowContext(ctx, query, id, organizationID, viewerID, viewerID, viewerID), task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// GetByTimelineID gets all tasks for a timeline on a goal of an organization. It is how timelines load
+// their tasks, so tasks are as visible as the timeline they were loaded with.
+func (r *TaskRepository) GetByTimelineID(ctx context.Context, organizationID, timelineID string) ([]models.TimelineTask, error) {
+	query := "SELECT " + taskColumns + " FROM timeline_tasks WHERE timeline_id = ? AND deleted_at IS NULL AND " + inOrganization +
+		" ORDER BY start_date ASC, priority DESC"
 	
-	rows, err := r.db.Query(query, timelineID)
+	rows, err := r.db.QueryContext(ctx, query, timelineID, organizationID)
 	if err != nil {
 		return nil, err
 	}
@@ -108,19 +125,7 @@ func (r *TaskRepository) GetByTimelineID(timelineID string) ([]models.TimelineTa
 	tasks := []models.TimelineTask{}
 	for rows.Next() {
 		task := models.TimelineTask{}
-		err := rows.Scan(
-			&task.ID, 
-			&task.TimelineID, 
-			&task.Title, 
-			&task.Description, 
-			&task.StartDate, 
-			&task.EndDate, 
-			&task.Duration, 
-			&task.Priority, 
-			&task.Completed, 
-			&task.CreatedAt, 
-			&task.UpdatedAt,
-		)
+		err := scanTask(rows, &task)
 		
 		if err != nil {
 			return nil, err
@@ -131,9 +136,214 @@ func (r *TaskRepository) GetByTimelineID(timelineID string) ([]models.TimelineTa
 	return tasks, nil
 }
 
-// UpdateCompletionStatus updates a task's completion status
-func (r *TaskRepository) UpdateCompletionStatus(id string, completed bool) error {
-	query := "UPDATE timeline_tasks SET completed = ?, updated_at = ? WHERE id = ?"
-	_, err := r.db.Exec(query, completed, time.Now(), id)
+// UpdateCompletionStatus updates the completion status of a task on a goal of an organization
+func (r *TaskRepository) UpdateCompletionStatus(ctx context.Context, organizationID, id string, completed bool) error {
+	query := `UPDATE timeline_tasks SET completed = ?,
+	completed_at = CASE WHEN ? THEN COALESCE(completed_at, ?) ELSE NULL END, updated_at = ? WHERE id = ? AND ` + inOrganization
+	now := time.Now()
+	_, err := auditedExec(ctx, r.db, rowTarget("task", "timeline_tasks", id), query, completed, completed, now, now, id, organizationID)
 	return err
-}
\ No newline at end of file
+}
+
+// OwnedTask is a task along with the goal it belongs to and the ID and time zone of the user who owns that goal
+type OwnedTask struct {
+	models.TimelineTask
+	UserID   string
+	GoalID   string
+	TimeZone string
+}
+
+// GetGoalID gets the ID of the goal of an organization the task's timeline belongs to
+func (r *TaskRepository) GetGoalID(ctx context.Context, organizationID, id string) (string, error) {
+	query := `SELECT tl.goal_id FROM timeline_tasks t JOIN timelines tl ON tl.id = t.timeline_id JOIN goals g ON g.id = tl.goal_id
+	WHERE t.id = ? AND g.organization_id = ? AND t.deleted_at IS NULL`
+
+	var goalID string
+	err := r.db.QueryRowContext(ctx, query, id, organizationID).Scan(&goalID)
+	return goalID, err
+}
+
+// GetRole gets the role a user has on the goal the task belongs to, or an empty role if the goal
+// is not shared with them. It returns sql.ErrNoRows when the task does not exist.
+func (r *TaskRepository) GetRole(ctx context.Context, id, userID string) (string, error) {
+	query := "SELECT " + goalRoleColumn + ` FROM timeline_tasks t
+	JOIN timelines tl ON tl.id = t.timeline_id
+	JOIN goals g ON g.id = tl.goal_id
+	WHERE t.id = ? AND t.deleted_at IS NULL`
+	return scanRole(r.db.QueryRowContext(ctx, query, userID, userID, userID, id))
+}
+
+// ownedTaskQuery selects tasks that are not in the trash along with the user who owns them
+const ownedTaskQuery = `SELECT
+	t.id, t.timeline_id, t.title, t.description, t.start_date, t.end_date,
+	t.duration, t.duration_value, t.duration_unit, t.effort_hours, t.recurrence, t.priority, t.completed, t.created_at, t.updated_at,
+	g.user_id, g.id, u.time_zone
+	FROM timeline_tasks t
+	JOIN timelines tl ON tl.id = t.timeline_id
+	JOIN goals g ON g.id = tl.goal_id
+	JOIN users u ON u.id = g.user_id
+	WHERE t.deleted_at IS NULL`
+
+// GetMissedDeadlines and GetIncompleteBetween serve the background jobs that report on every
+// organization's tasks, so they are not limited to one organization.
+
+// GetMissedDeadlines gets incomplete tasks that ended before the given date and have not been reported yet
+func (r *TaskRepository) GetMissedDeadlines(ctx context.Context, before time.Time) ([]OwnedTask, error) {
+	query := ownedTaskQuery + " AND t.completed = FALSE AND t.end_date < ? AND t.deadline_missed_at IS NULL"
+	return r.queryOwned(ctx, query, before)
+}
+
+// GetIncompleteBetween gets incomplete tasks that start or end within the date range
+func (r *TaskRepository) GetIncompleteBetween(ctx context.Context, from, to time.Time) ([]OwnedTask, error) {
+	query := ownedTaskQuery + ` AND t.completed = FALSE
+	AND (t.start_date BETWEEN ? AND ? OR t.end_date BETWEEN ? AND ?)
+	ORDER BY g.user_id, t.end_date ASC`
+	return r.queryOwned(ctx, query, from, to, from, to)
+}
+
+func (r *TaskRepository) queryOwned(ctx context.Context, query string, args ...interface{}) ([]OwnedTask, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []OwnedTask{}
+	for rows.Next() {
+		task := OwnedTask{}
+		err := scanTask(rows, &task.TimelineTask, &task.UserID, &task.GoalID, &task.TimeZone)
+
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// MarkDeadlineMissed records that a task's missed deadline has been reported.
+// It returns false if it had already been reported. Like GetMissedDeadlines, it serves every organization.
+func (r *TaskRepository) MarkDeadlineMissed(ctx context.Context, id string) (bool, error) {
+	query := "UPDATE timeline_tasks SET deadline_missed_at = ? WHERE id = ? AND deadline_missed_at IS NULL"
+	return auditedExec(ctx, r.db, rowTarget("task", "timeline_tasks", id), query, time.Now(), id)
+}
+
+// GetCompletedByUserID gets tasks a user of an organization completed within the time range
+func (r *TaskRepository) GetCompletedByUserID(ctx context.Context, organizationID, userID string, from, to time.Time) ([]OwnedTask, error) {
+	query := ownedTaskQuery + ` AND g.organization_id = ? AND g.user_id = ? AND t.completed = TRUE
+	AND t.completed_at >= ? AND t.completed_at < ? ORDER BY t.completed_at ASC`
+	return r.queryOwned(ctx, query, organizationID, userID, from, to)
+}
+
+// GetUpcomingByUserID gets the incomplete tasks of a user of an organization starting or ending within the date range
+func (r *TaskRepository) GetUpcomingByUserID(ctx context.Context, organizationID, userID string, from, to time.Time) ([]OwnedTask, error) {
+	query := ownedTaskQuery + ` AND g.organization_id = ? AND g.user_id = ? AND t.completed = FALSE
+	AND (t.start_date BETWEEN ? AND ? OR t.end_date BETWEEN ? AND ?) ORDER BY t.end_date ASC, t.priority DESC`
+	return r.queryOwned(ctx, query, organizationID, userID, from, to, from, to)
+}
+
+// inOrganization limits timeline_tasks to those on goals of the organization given as its argument
+const inOrganization = `timeline_id IN (SELECT tl.id FROM timelines tl JOIN goals g ON g.id = tl.goal_id WHERE g.organization_id = ?)`
+
+// taskColumns lists the timeline_tasks columns scanTask reads, in order
+const taskColumns = `id, timeline_id, title, description, start_date, end_date,
+	duration, duration_value, duration_unit, effort_hours, recurrence, priority, completed, created_at, updated_at`
+
+// scanTask scans the task columns followed by any extra columns.
+// Tasks stored before durations were normalized are parsed from their label.
+func scanTask(row rowScanner, task *models.TimelineTask, extra ...interface{}) error {
+	var durationValue sql.NullFloat64
+	var durationUnit sql.NullString
+	var effortHours sql.NullFloat64
+	var recurrence sql.NullString
+
+	dest := []interface{}{
+		&task.ID,
+		&task.TimelineID,
+		&task.Title,
+		&task.Description,
+		&task.StartDate,
+		&task.EndDate,
+		&task.Duration.Label,
+		&durationValue,
+		&durationUnit,
+		&effortHours,
+		&recurrence,
+		&task.Priority,
+		&task.Completed,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+	}
+
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	task.EffortHours = effortHours.Float64
+	task.Recurrence = recurrence.String
+
+	if durationValue.Valid && durationUnit.Valid {
+		task.Duration.Value = durationValue.Float64
+		task.Duration.Unit = durationUnit.String
+	} else {
+		task.Duration = duration.Reconcile(task.Duration.Label, task.StartDate, task.EndDate)
+	}
+
+	return nil
+}
+
+// TaskSchedule is the dates a task moves to
+type TaskSchedule struct {
+	TaskID    string
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+// updateScheduleQuery moves a task to new dates. MySQL assigns left to right, so deadline_missed_at
+// is compared against the old end date.
+const updateScheduleQuery = `UPDATE timeline_tasks SET
+	deadline_missed_at = CASE WHEN end_date = ? THEN deadline_missed_at ELSE NULL END,
+	start_date = ?, end_date = ?, updated_at = ? WHERE id = ? AND ` + inOrganization
+
+// UpdateSchedule moves a task on a goal of an organization to new dates. A task whose end date moves
+// can miss its deadline again.
+func (r *TaskRepository) UpdateSchedule(ctx context.Context, organizationID, id string, startDate, endDate time.Time) error {
+	_, err := auditedExec(ctx, r.db, rowTarget("task", "timeline_tasks", id), updateScheduleQuery, endDate, startDate, endDate, time.Now(), id, organizationID)
+	return err
+}
+
+// UpdateRecurrence sets the RRULE a task on a goal of an organization repeats by, or makes it a
+// one-off task when the rule is empty. In the same transaction it deletes the completed occurrences on
+// the removed dates, which the new rule no longer has, and sets whether the task is completed.
+func (r *TaskRepository) UpdateRecurrence(ctx context.Context, organizationID, id, recurrence string, removed []time.Time, completed bool) error {
+	query := `UPDATE timeline_tasks SET recurrence = ?, completed = ?,
+	completed_at = CASE WHEN ? THEN COALESCE(completed_at, ?) ELSE NULL END, updated_at = ? WHERE id = ? AND ` + inOrganization
+	now := time.Now()
+
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		changed, err := auditedTx(ctx, tx, rowTarget("task", "timeline_tasks", id), func(tx *sql.Tx) (bool, error) {
+			return execAffected(ctx, tx, query, nullString(recurrence), completed, completed, now, now, id, organizationID)
+		})
+		if err != nil || !changed {
+			return err
+		}
+
+		for _, occursOn := range removed {
+			_, err := auditedTx(ctx, tx, occurrenceTarget(id, occursOn), func(tx *sql.Tx) (bool, error) {
+				return execAffected(ctx, tx, "DELETE FROM task_occurrences WHERE task_id = ? AND occurs_on = ?", id, occursOn)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetIncompleteByUserID gets all the incomplete tasks of a user of an organization in start date order
+func (r *TaskRepository) GetIncompleteByUserID(ctx context.Context, organizationID, userID string) ([]OwnedTask, error) {
+	query := ownedTaskQuery + ` AND g.organization_id = ? AND g.user_id = ? AND t.completed = FALSE
+	ORDER BY t.start_date ASC, t.priority DESC`
+	return r.queryOwned(ctx, query, organizationID, userID)
+}
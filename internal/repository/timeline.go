@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,45 +13,29 @@ import (
 
 // TimelineRepository handles database operations for timelines
 type TimelineRepository struct {
-	db *sql.DB
+	db *timeoutDB
 }
 
 // NewTimelineRepository creates a new TimelineRepository
 func NewTimelineRepository() *TimelineRepository {
 	return &TimelineRepository{
-		db: database.DB,
+		db: withQueryTimeout(database.DB),
 	}
 }
 
 // Create creates a new timeline
-func (r *TimelineRepository) Create(goalID, title, description string, startDate, endDate time.Time) (*models.Timeline, error) {
+func (r *TimelineRepository) Create(ctx context.Context, goalID, title, description string, startDate, endDate time.Time) (*models.Timeline, error) {
 	timeline := &models.Timeline{
-		ID:          uuid.New().String(),
 		GoalID:      goalID,
 		Title:       title,
 		Description: description,
 		StartDate:   startDate,
 		EndDate:     endDate,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
 	}
 
-	query := `INSERT INTO timelines 
-	(id, goal_id, title, description, start_date, end_date, created_at, updated_at) 
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-	
-	_, err := r.db.Exec(
-		query, 
-		timeline.ID, 
-		timeline.GoalID, 
-		timeline.Title, 
-		timeline.Description, 
-		timeline.StartDate, 
-		timeline.EndDate, 
-		timeline.CreatedAt, 
-		timeline.UpdatedAt,
-	)
-	
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		return insertTimeline(ctx, tx, timeline)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -57,48 +43,106 @@ func (r *TimelineRepository) Create(goalID, title, description string, startDate
 	return timeline, nil
 }
 
-// GetByID gets a timeline by ID with its tasks
-func (r *TimelineRepository) GetByID(id string) (*models.Timeline, error) {
-	query := `SELECT 
-	id, goal_id, title, description, start_date, end_date, created_at, updated_at 
-	FROM timelines WHERE id = ?`
-	
-	row := r.db.QueryRow(query, id)
+// CreateWithGoal stores a new goal, a timeline for it and the timeline's tasks in one transaction, so
+// that a failure part way leaves none of them behind. It fills in their IDs, and the goal's organization.
+func (r *TimelineRepository) CreateWithGoal(ctx context.Context, goal *models.Goal, timeline *models.Timeline, tasks []models.TimelineTask) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		if err := insertGoal(ctx, tx, goal); err != nil {
+			return fmt.Errorf("failed to create goal: %w", err)
+		}
 
-	timeline := &models.Timeline{}
-	err := row.Scan(
-		&timeline.ID, 
-		&timeline.GoalID, 
-		&timeline.Title, 
-		&timeline.Description, 
-		&timeline.StartDate, 
-		&timeline.EndDate, 
-		&timeline.CreatedAt, 
-		&timeline.UpdatedAt,
-	)
-	
+		timeline.GoalID = goal.ID
+		if err := insertTimeline(ctx, tx, timeline); err != nil {
+			return fmt.Errorf("failed to create timeline: %w", err)
+		}
+
+		for i := range tasks {
+			tasks[i].TimelineID = timeline.ID
+			if err := insertTask(ctx, tx, &tasks[i]); err != nil {
+				return fmt.Errorf("failed to create task: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// insertTimeline stores a new timeline as an audited write within a transaction, filling in its ID
+// and timestamps
+func insertTimeline(ctx context.Context, tx *sql.Tx, timeline *models.Timeline) error {
+	timeline.ID = uuid.New().String()
+	timeline.CreatedAt = time.Now()
+	timeline.UpdatedAt = timeline.CreatedAt
+
+	query := `INSERT INTO timelines
+	(id, goal_id, title, description, start_date, end_date, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := auditedTx(ctx, tx, rowTarget("timeline", "timelines", timeline.ID), func(tx *sql.Tx) (bool, error) {
+		return execAffected(ctx, tx, query,
+			timeline.ID,
+			timeline.GoalID,
+			timeline.Title,
+			timeline.Description,
+			timeline.StartDate,
+			timeline.EndDate,
+			timeline.CreatedAt,
+			timeline.UpdatedAt,
+		)
+	})
+	return err
+}
+
+// timelineQuery selects timelines that are not in the trash along with the goal g they belong to
+const timelineQuery = `SELECT
+	tl.id, tl.goal_id, tl.title, tl.description, tl.start_date, tl.end_date, tl.created_at, tl.updated_at
+	FROM timelines tl JOIN goals g ON g.id = tl.goal_id
+	WHERE tl.deleted_at IS NULL`
+
+// GetByID gets a timeline on a goal of an organization by ID with its tasks
+func (r *TimelineRepository) GetByID(ctx context.Context, organizationID, id string) (*models.Timeline, error) {
+	query := timelineQuery + " AND tl.id = ? AND g.organization_id = ?"
+	return r.get(ctx, organizationID, query, id, organizationID)
+}
+
+// GetVisible gets a timeline on a goal of an organization by ID with its tasks, provided the goal is
+// the viewer's or shared with them. Timelines the viewer cannot see are not found, like those that do not exist.
+func (r *TimelineRepository) GetVisible(ctx context.Context, organizationID, id, viewerID string) (*models.Timeline, error) {
+	query := timelineQuery + " AND tl.id = ? AND g.organization_id = ? AND (" + goalRoleColumn + ") IS NOT NULL"
+	return r.get(ctx, organizationID, query, id, organizationID, viewerID, viewerID, viewerID)
+}
+
+// GetByGoalID gets all timelines for a goal of an organization
+func (r *TimelineRepository) GetByGoalID(ctx context.Context, organizationID, goalID string) ([]*models.Timeline, error) {
+	query := timelineQuery + " AND tl.goal_id = ? AND g.organization_id = ?"
+	return r.query(ctx, query, goalID, organizationID)
+}
+
+// GetVisibleByGoalID gets all timelines for a goal of an organization, provided the goal is the
+// viewer's or shared with them. A goal the viewer cannot see has no timelines.
+func (r *TimelineRepository) GetVisibleByGoalID(ctx context.Context, organizationID, goalID, viewerID string) ([]*models.Timeline, error) {
+	query := timelineQuery + " AND tl.goal_id = ? AND g.organization_id = ? AND (" + goalRoleColumn + ") IS NOT NULL"
+	return r.query(ctx, query, goalID, organizationID, viewerID, viewerID, viewerID)
+}
+
+// get gets the timeline a query selects along with its tasks
+func (r *TimelineRepository) get(ctx context.Context, organizationID, query string, args ...interface{}) (*models.Timeline, error) {
+	timeline, err := scanTimeline(r.db.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		return nil, err
 	}
 
 	// Get tasks for this timeline
-	taskRepo := NewTaskRepository()
-	tasks, err := taskRepo.GetByTimelineID(timeline.ID)
+	tasks, err := NewTaskRepository().GetByTimelineID(ctx, organizationID, timeline.ID)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	timeline.Tasks = tasks
 	return timeline, nil
 }
 
-// GetByGoalID gets all timelines for a goal
-func (r *TimelineRepository) GetByGoalID(goalID string) ([]*models.Timeline, error) {
-	query := `SELECT 
-	id, goal_id, title, description, start_date, end_date, created_at, updated_at 
-	FROM timelines WHERE goal_id = ?`
-	
-	rows, err := r.db.Query(query, goalID)
+func (r *TimelineRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.Timeline, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -106,23 +150,101 @@ func (r *TimelineRepository) GetByGoalID(goalID string) ([]*models.Timeline, err
 
 	timelines := []*models.Timeline{}
 	for rows.Next() {
-		timeline := &models.Timeline{}
-		err := rows.Scan(
-			&timeline.ID, 
-			&timeline.GoalID, 
-			&timeline.Title, 
-			&timeline.Description, 
-			&timeline.StartDate, 
-			&timeline.EndDate, 
-			&timeline.CreatedAt, 
-			&timeline.UpdatedAt,
-		)
-		
+		timeline, err := scanTimeline(rows)
 		if err != nil {
 			return nil, err
 		}
 		timelines = append(timelines, timeline)
 	}
 
-	return timelines, nil
-}
\ No newline at end of file
+	return timelines, rows.Err()
+}
+
+func scanTimeline(row rowScanner) (*models.Timeline, error) {
+	timeline := &models.Timeline{}
+	err := row.Scan(
+		&timeline.ID,
+		&timeline.GoalID,
+		&timeline.Title,
+		&timeline.Description,
+		&timeline.StartDate,
+		&timeline.EndDate,
+		&timeline.CreatedAt,
+		&timeline.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return timeline, nil
+}
+
+// updateDatesQuery sets the date range a timeline on a goal of an organization covers
+const updateDatesQuery = `UPDATE timelines SET start_date = ?, end_date = ?, updated_at = ?
+	WHERE id = ? AND goal_id IN (SELECT g.id FROM goals g WHERE g.organization_id = ?)`
+
+// UpdateDates updates the date range a timeline on a goal of an organization covers
+func (r *TimelineRepository) UpdateDates(ctx context.Context, organizationID, id string, startDate, endDate time.Time) error {
+	_, err := auditedExec(ctx, r.db, rowTarget("timeline", "timelines", id), updateDatesQuery, startDate, endDate, time.Now(), id, organizationID)
+	return err
+}
+
+// Reschedule moves tasks of a timeline on a goal of an organization to new dates and sets the date
+// range the timeline covers, in one transaction
+func (r *TimelineRepository) Reschedule(ctx context.Context, organizationID, id string, startDate, endDate time.Time, schedules []TaskSchedule) error {
+	return r.db.inTx(ctx, func(tx *sql.Tx) error {
+		now := time.Now()
+		for _, schedule := range schedules {
+			_, err := auditedTx(ctx, tx, rowTarget("task", "timeline_tasks", schedule.TaskID), func(tx *sql.Tx) (bool, error) {
+				return execAffected(ctx, tx, updateScheduleQuery+" AND timeline_id = ?",
+					schedule.EndDate, schedule.StartDate, schedule.EndDate, now, schedule.TaskID, organizationID, id)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := auditedTx(ctx, tx, rowTarget("timeline", "timelines", id), func(tx *sql.Tx) (bool, error) {
+			return execAffected(ctx, tx, updateDatesQuery, startDate, endDate, now, id, organizationID)
+		})
+		return err
+	})
+}
+
+// GetOwnerID gets the ID of the user whose goal of an organization the timeline belongs to
+func (r *TimelineRepository) GetOwnerID(ctx context.Context, organizationID, id string) (string, error) {
+	query := `SELECT g.user_id FROM timelines tl JOIN goals g ON g.id = tl.goal_id
+	WHERE tl.id = ? AND g.organization_id = ? AND tl.deleted_at IS NULL`
+
+	var userID string
+	err := r.db.QueryRowContext(ctx, query, id, organizationID).Scan(&userID)
+	return userID, err
+}
+
+// GetRole gets the role a user has on the goal the timeline belongs to, or an empty role if the goal
+// is not shared with them. It returns sql.ErrNoRows when the timeline does not exist.
+func (r *TimelineRepository) GetRole(ctx context.Context, id, userID string) (string, error) {
+	query := "SELECT " + goalRoleColumn + " FROM timelines tl JOIN goals g ON g.id = tl.goal_id WHERE tl.id = ? AND tl.deleted_at IS NULL"
+	return scanRole(r.db.QueryRowContext(ctx, query, userID, userID, userID, id))
+}
+
+// GetIDsByGoalID gets the IDs of the timelines of a goal of an organization
+func (r *TimelineRepository) GetIDsByGoalID(ctx context.Context, organizationID, goalID string) ([]string, error) {
+	query := `SELECT tl.id FROM timelines tl JOIN goals g ON g.id = tl.goal_id
+	WHERE tl.goal_id = ? AND g.organization_id = ? AND tl.deleted_at IS NULL`
+	rows, err := r.db.QueryContext(ctx, query, goalID, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+// ErrParentInTrash is returned when restoring an item whose goal or timeline is still in the trash
+var ErrParentInTrash = errors.New("the goal or timeline it belongs to is in the trash")
+
+// trashKind describes how items of a kind move in and out of the trash
+type trashKind struct {
+	table string
+	// goalJoin joins the item, as x, to the goal g it belongs to
+	goalJoin string
+	// parent selects the deleted_at of the item's goal or timeline, given the item's ID
+	parent string
+	// children set deleted_at on the item's children, given the new value, the item's ID and the value
+	// it replaces
+	children []string
+	// cascaded select the timelines and tasks that purging the item deletes through ON DELETE CASCADE,
+	// given the item's ID, so that their deletion is audited too
+	cascaded []cascadedRows
+}
+
+// cascadedRows selects the IDs of rows of an audited kind deleted along with another row
+type cascadedRows struct {
+	entityType string
+	table      string
+	query      string
+}
+
+var trashKinds = map[string]trashKind{
+	models.TrashGoal: {
+		table:    "goals",
+		goalJoin: "goals x JOIN goals g ON g.id = x.id",
+		children: []string{
+			`UPDATE timeline_tasks t JOIN timelines tl ON tl.id = t.timeline_id SET t.deleted_at = ?
+			WHERE tl.goal_id = ? AND t.deleted_at <=> ?`,
+			"UPDATE timelines SET deleted_at = ? WHERE goal_id = ? AND deleted_at <=> ?",
+		},
+		cascaded: []cascadedRows{
+			{"timeline", "timelines", "SELECT id FROM timelines WHERE goal_id = ?"},
+			{"task", "timeline_tasks", "SELECT t.id FROM timeline_tasks t JOIN timelines tl ON tl.id = t.timeline_id WHERE tl.goal_id = ?"},
+		},
+	},
+	models.TrashTimeline: {
+		table:    "timelines",
+		goalJoin: "timelines x JOIN goals g ON g.id = x.goal_id",
+		parent:   "SELECT g.deleted_at FROM timelines x JOIN goals g ON g.id = x.goal_id WHERE x.id = ?",
+		children: []string{"UPDATE timeline_tasks SET deleted_at = ? WHERE timeline_id = ? AND deleted_at <=> ?"},
+		cascaded: []cascadedRows{{"task", "timeline_tasks", "SELECT id FROM timeline_tasks WHERE timeline_id = ?"}},
+	},
+	models.TrashTask: {
+		table:    "timeline_tasks",
+		goalJoin: "timeline_tasks x JOIN timelines tl ON tl.id = x.timeline_id JOIN goals g ON g.id = tl.goal_id",
+		parent:   "SELECT tl.deleted_at FROM timeline_tasks x JOIN timelines tl ON tl.id = x.timeline_id WHERE x.id = ?",
+	},
+}
+
+func lookupTrashKind(kind string) (trashKind, error) {
+	k, ok := trashKinds[kind]
+	if !ok {
+		return trashKind{}, fmt.Errorf("unknown kind of item %q", kind)
+	}
+	return k, nil
+}
+
+// trashQuery selects the items in the trash, leaving out those deleted along with their goal or
+// timeline. Goals must match goalCondition and timelines and tasks condition, both on the item x and
+// its goal g.
+func trashQuery(goalCondition, condition string) string {
+	return `SELECT 'goal', x.id, x.title, g.id, NULL, x.deleted_at FROM ` + trashKinds[models.TrashGoal].goalJoin + `
+	WHERE x.deleted_at IS NOT NULL AND ` + goalCondition + `
+	UNION ALL
+	SELECT 'timeline', x.id, x.title, g.id, x.id, x.deleted_at FROM ` + trashKinds[models.TrashTimeline].goalJoin + `
+	WHERE x.deleted_at IS NOT NULL AND NOT (g.deleted_at <=> x.deleted_at) AND ` + condition + `
+	UNION ALL
+	SELECT 'task', x.id, x.title, g.id, tl.id, x.deleted_at FROM ` + trashKinds[models.TrashTask].goalJoin + `
+	WHERE x.deleted_at IS NOT NULL AND NOT (tl.deleted_at <=> x.deleted_at) AND ` + condition + `
+	ORDER BY 6 DESC`
+}
+
+// TrashRepository handles moving goals, timelines and tasks in and out of the trash. Every other
+// repository leaves out what is in the trash.
+type TrashRepository struct {
+	db *timeoutDB
+}
+
+// NewTrashRepository creates a new TrashRepository
+func NewTrashRepository() *TrashRepository {
+	return &TrashRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// Delete moves an item to the trash along with its timelines and tasks that are not already there.
+// It returns false if there is no such item outside the trash.
+func (r *TrashRepository) Delete(ctx context.Context, kind, id string) (bool, error) {
+	k, err := lookupTrashKind(kind)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	return audited(ctx, r.db, rowTarget(kind, k.table, id), func(tx *sql.Tx) (bool, error) {
+		deleted, err := execAffected(ctx, tx, "UPDATE "+k.table+" SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", now, id)
+		if err != nil || !deleted {
+			return false, err
+		}
+		for _, query := range k.children {
+			if _, err := tx.ExecContext(ctx, query, now, id, nil); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+// Restore takes an item out of the trash along with the timelines and tasks deleted with it.
+// It returns false if the item is not in the trash, and ErrParentInTrash if the goal or timeline it
+// belongs to is.
+func (r *TrashRepository) Restore(ctx context.Context, kind, id string) (bool, error) {
+	k, err := lookupTrashKind(kind)
+	if err != nil {
+		return false, err
+	}
+
+	return audited(ctx, r.db, rowTarget(kind, k.table, id), func(tx *sql.Tx) (bool, error) {
+		var deletedAt sql.NullTime
+		err := tx.QueryRowContext(ctx, "SELECT deleted_at FROM "+k.table+" WHERE id = ? FOR UPDATE", id).Scan(&deletedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil || !deletedAt.Valid {
+			return false, err
+		}
+
+		if k.parent != "" {
+			var parentDeletedAt sql.NullTime
+			if err := tx.QueryRowContext(ctx, k.parent, id).Scan(&parentDeletedAt); err != nil {
+				return false, err
+			}
+			if parentDeletedAt.Valid {
+				return false, ErrParentInTrash
+			}
+		}
+
+		for _, query := range k.children {
+			if _, err := tx.ExecContext(ctx, query, nil, id, deletedAt.Time); err != nil {
+				return false, err
+			}
+		}
+		return execAffected(ctx, tx, "UPDATE "+k.table+" SET deleted_at = NULL WHERE id = ?", id)
+	})
+}
+
+// GetRole gets the role a user has on the goal an item in the trash belongs to, or an empty role if the
+// goal is not shared with them. It returns sql.ErrNoRows when the item is not in the trash.
+func (r *TrashRepository) GetRole(ctx context.Context, kind, id, userID string) (string, error) {
+	k, err := lookupTrashKind(kind)
+	if err != nil {
+		return "", err
+	}
+
+	query := "SELECT " + goalRoleColumn + " FROM " + k.goalJoin + " WHERE x.id = ? AND x.deleted_at IS NOT NULL"
+	return scanRole(r.db.QueryRowContext(ctx, query, userID, userID, userID, id))
+}
+
+// GetByUserID gets the items in the trash a user can restore, most recently deleted first: the goals
+// they own, and the timelines and tasks of goals they own or can edit
+func (r *TrashRepository) GetByUserID(ctx context.Context, userID string) ([]*models.TrashItem, error) {
+	query := trashQuery("g.user_id = ?", "("+goalRoleColumn+") IN ('owner', 'editor')")
+	return r.query(ctx, query, userID, userID, userID, userID, userID, userID, userID)
+}
+
+// GetDeletedBefore gets the items that were moved to the trash before a time
+func (r *TrashRepository) GetDeletedBefore(ctx context.Context, before time.Time) ([]*models.TrashItem, error) {
+	query := trashQuery("x.deleted_at < ?", "x.deleted_at < ?")
+	return r.query(ctx, query, before, before, before)
+}
+
+// Purge permanently deletes an item in the trash along with everything that belongs to it.
+// The deletion of its timelines and tasks is audited along with the item's own.
+// It returns false if the item is not in the trash.
+func (r *TrashRepository) Purge(ctx context.Context, kind, id string) (bool, error) {
+	k, err := lookupTrashKind(kind)
+	if err != nil {
+		return false, err
+	}
+
+	var purged bool
+	err = r.db.inTx(ctx, func(tx *sql.Tx) error {
+		var inTrash bool
+		err := tx.QueryRowContext(ctx, "SELECT 1 FROM "+k.table+" WHERE id = ? AND deleted_at IS NOT NULL FOR UPDATE", id).Scan(&inTrash)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Cascaded rows are recorded first, while the rows linking them to their organization remain
+		for _, cascaded := range k.cascaded {
+			if err := auditCascadedDeletes(ctx, tx, cascaded, id); err != nil {
+				return err
+			}
+		}
+
+		query := "DELETE FROM " + k.table + " WHERE id = ?"
+		purged, err = auditedTx(ctx, tx, rowTarget(kind, k.table, id), func(tx *sql.Tx) (bool, error) {
+			return execAffected(ctx, tx, query, id)
+		})
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return purged, nil
+}
+
+// auditCascadedDeletes records the deletion of the rows that deleting a parent row is about to cascade to
+func auditCascadedDeletes(ctx context.Context, tx *sql.Tx, cascaded cascadedRows, parentID string) error {
+	rows, err := tx.QueryContext(ctx, cascaded.query, parentID)
+	if err != nil {
+		return err
+	}
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		target := rowTarget(cascaded.entityType, cascaded.table, id)
+		before, err := snapshot(ctx, tx, target)
+		if err != nil {
+			return err
+		}
+		if err := recordChange(ctx, tx, target, before, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *TrashRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.TrashItem, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*models.TrashItem{}
+	for rows.Next() {
+		item := &models.TrashItem{}
+		var timelineID sql.NullString
+		if err := rows.Scan(&item.Kind, &item.ID, &item.Title, &item.GoalID, &timelineID, &item.DeletedAt); err != nil {
+			return nil, err
+		}
+		item.TimelineID = timelineID.String
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
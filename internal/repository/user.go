@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
@@ -9,29 +10,34 @@ import (
 	"github.com/jukemori/timeline-generator/internal/models"
 )
 
+const userColumns = "id, organization_id, email, role, time_zone, created_at, updated_at"
+
 // UserRepository handles database operations for users
 type UserRepository struct {
-	db *sql.DB
+	db *timeoutDB
 }
 
 // NewUserRepository creates a new UserRepository
 func NewUserRepository() *UserRepository {
 	return &UserRepository{
-		db: database.DB,
+		db: withQueryTimeout(database.DB),
 	}
 }
 
-// Create creates a new user
-func (r *UserRepository) Create(email string) (*models.User, error) {
+// Create creates a new user in an organization
+func (r *UserRepository) Create(ctx context.Context, organizationID, email, role string) (*models.User, error) {
 	user := &models.User{
-		ID:        uuid.New().String(),
-		Email:     email,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:             uuid.New().String(),
+		OrganizationID: organizationID,
+		Email:          email,
+		Role:           role,
+		TimeZone:       models.DefaultTimeZone,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 
-	query := "INSERT INTO users (id, email, created_at, updated_at) VALUES (?, ?, ?, ?)"
-	_, err := r.db.Exec(query, user.ID, user.Email, user.CreatedAt, user.UpdatedAt)
+	query := "INSERT INTO users (id, organization_id, email, role, time_zone, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	_, err := auditedExec(ctx, r.db, rowTarget("user", "users", user.ID), query, user.ID, user.OrganizationID, user.Email, user.Role, user.TimeZone, user.CreatedAt, user.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -39,30 +45,83 @@ func (r *UserRepository) Create(email string) (*models.User, error) {
 	return user, nil
 }
 
-// GetByID gets a user by ID
-func (r *UserRepository) GetByID(id string) (*models.User, error) {
-	query := "SELECT id, email, created_at, updated_at FROM users WHERE id = ?"
-	row := r.db.QueryRow(query, id)
+// GetByID gets a user by ID.
+// Only use it for the user making a request; other users are looked up within an organization.
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = ?"
+	return scanUser(r.db.QueryRowContext(ctx, query, id))
+}
 
-	user := &models.User{}
-	err := row.Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+// GetInOrganization gets a user of an organization by ID.
+// It returns sql.ErrNoRows for users of other organizations.
+func (r *UserRepository) GetInOrganization(ctx context.Context, organizationID, id string) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = ? AND organization_id = ?"
+	return scanUser(r.db.QueryRowContext(ctx, query, id, organizationID))
+}
+
+// GetByEmailInOrganization gets a user of an organization by email.
+// It returns sql.ErrNoRows for users of other organizations.
+func (r *UserRepository) GetByEmailInOrganization(ctx context.Context, organizationID, email string) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE email = ? AND organization_id = ?"
+	return scanUser(r.db.QueryRowContext(ctx, query, email, organizationID))
+}
+
+// GetByOrganizationID gets every user of an organization
+func (r *UserRepository) GetByOrganizationID(ctx context.Context, organizationID string) ([]*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE organization_id = ? ORDER BY email ASC"
+
+	rows, err := r.db.QueryContext(ctx, query, organizationID)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return user, nil
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
 }
 
-// GetByEmail gets a user by email
-func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
-	query := "SELECT id, email, created_at, updated_at FROM users WHERE email = ?"
-	row := r.db.QueryRow(query, email)
+// UpdateTimeZone sets the time zone a user's dates are evaluated in
+func (r *UserRepository) UpdateTimeZone(ctx context.Context, id, timeZone string) error {
+	query := "UPDATE users SET time_zone = ?, updated_at = ? WHERE id = ?"
+	_, err := auditedExec(ctx, r.db, rowTarget("user", "users", id), query, timeZone, time.Now(), id)
+	return err
+}
 
+// UpdateRole sets the role of a user of an organization.
+// It returns sql.ErrNoRows for users of other organizations.
+func (r *UserRepository) UpdateRole(ctx context.Context, organizationID, id, role string) error {
+	query := "UPDATE users SET role = ?, updated_at = ? WHERE id = ? AND organization_id = ?"
+	updated, err := auditedExec(ctx, r.db, rowTarget("user", "users", id), query, role, time.Now(), id, organizationID)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
 	user := &models.User{}
-	err := row.Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	err := row.Scan(
+		&user.ID,
+		&user.OrganizationID,
+		&user.Email,
+		&user.Role,
+		&user.TimeZone,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
 	if err != nil {
 		return nil, err
 	}
-
 	return user, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+	response_status, last_error, next_attempt_at, delivered_at, created_at, updated_at`
+
+// WebhookDeliveryRepository handles database operations for webhook deliveries
+type WebhookDeliveryRepository struct {
+	db *timeoutDB
+}
+
+// NewWebhookDeliveryRepository creates a new WebhookDeliveryRepository
+func NewWebhookDeliveryRepository() *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// Create queues a delivery of an event payload to a subscription
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, subscriptionID, eventID, eventType, payload string) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{
+		ID:             uuid.New().String(),
+		SubscriptionID: subscriptionID,
+		EventID:        eventID,
+		EventType:      eventType,
+		Payload:        payload,
+		Status:         models.DeliveryStatusPending,
+		NextAttemptAt:  time.Now(),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	query := `INSERT INTO webhook_deliveries
+	(id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.ExecContext(ctx,
+		query,
+		delivery.ID,
+		delivery.SubscriptionID,
+		delivery.EventID,
+		delivery.EventType,
+		delivery.Payload,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.CreatedAt,
+		delivery.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// GetByID gets a webhook delivery by ID
+func (r *WebhookDeliveryRepository) GetByID(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	query := "SELECT " + webhookDeliveryColumns + " FROM webhook_deliveries WHERE id = ?"
+	return scanWebhookDelivery(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetBySubscriptionID gets the most recent deliveries for a subscription
+func (r *WebhookDeliveryRepository) GetBySubscriptionID(ctx context.Context, subscriptionID string, limit int) ([]*models.WebhookDelivery, error) {
+	query := "SELECT " + webhookDeliveryColumns + ` FROM webhook_deliveries
+	WHERE subscription_id = ? ORDER BY created_at DESC LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// ClaimNext marks the next due pending delivery as delivering and returns it.
+// It returns sql.ErrNoRows when no delivery is due.
+func (r *WebhookDeliveryRepository) ClaimNext(ctx context.Context) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	var id string
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`SELECT id FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at ASC LIMIT 1 FOR UPDATE SKIP LOCKED`,
+			models.DeliveryStatusPending, now,
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, locked_at = ?, updated_at = ? WHERE id = ?",
+			models.DeliveryStatusDelivering, now, now, id,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// MarkSucceeded records a successful delivery
+func (r *WebhookDeliveryRepository) MarkSucceeded(ctx context.Context, id string, responseStatus int) error {
+	now := time.Now()
+	query := `UPDATE webhook_deliveries SET status = ?, response_status = ?, last_error = NULL,
+	locked_at = NULL, delivered_at = ?, updated_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, models.DeliveryStatusSucceeded, responseStatus, now, now, id)
+	return err
+}
+
+// MarkRetry records a failed attempt and schedules the next one
+func (r *WebhookDeliveryRepository) MarkRetry(ctx context.Context, id string, responseStatus int, lastError string, nextAttemptAt time.Time) error {
+	query := `UPDATE webhook_deliveries SET status = ?, response_status = ?, last_error = ?,
+	next_attempt_at = ?, locked_at = NULL, updated_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx,
+		query,
+		models.DeliveryStatusPending,
+		nullInt(responseStatus),
+		lastError,
+		nextAttemptAt,
+		time.Now(),
+		id,
+	)
+	return err
+}
+
+// MarkFailed records a failed attempt after which the delivery is given up
+func (r *WebhookDeliveryRepository) MarkFailed(ctx context.Context, id string, responseStatus int, lastError string) error {
+	query := `UPDATE webhook_deliveries SET status = ?, response_status = ?, last_error = ?,
+	locked_at = NULL, updated_at = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, models.DeliveryStatusFailed, nullInt(responseStatus), lastError, time.Now(), id)
+	return err
+}
+
+// CountPending counts the deliveries waiting to be sent, including those waiting to be retried
+func (r *WebhookDeliveryRepository) CountPending(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_deliveries WHERE status = ?", models.DeliveryStatusPending).Scan(&count)
+	return count, err
+}
+
+// RequeueStale puts back deliveries left in flight by a worker that stopped before lockedBefore
+func (r *WebhookDeliveryRepository) RequeueStale(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	now := time.Now()
+	query := `UPDATE webhook_deliveries SET status = ?, next_attempt_at = ?, locked_at = NULL, updated_at = ?
+	WHERE status = ? AND locked_at < ?`
+
+	result, err := r.db.ExecContext(ctx, query, models.DeliveryStatusPending, now, now, models.DeliveryStatusDelivering, lockedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanWebhookDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	var (
+		responseStatus sql.NullInt64
+		lastError      sql.NullString
+		deliveredAt    sql.NullTime
+	)
+
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.SubscriptionID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&responseStatus,
+		&lastError,
+		&delivery.NextAttemptAt,
+		&deliveredAt,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.ResponseStatus = int(responseStatus.Int64)
+	delivery.LastError = lastError.String
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+
+	return delivery, nil
+}
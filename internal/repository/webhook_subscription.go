@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jukemori/timeline-generator/internal/database"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+const webhookSubscriptionColumns = "id, organization_id, user_id, url, secret, event_types, active, created_at, updated_at"
+
+// WebhookSubscriptionRepository handles database operations for webhook subscriptions
+type WebhookSubscriptionRepository struct {
+	db *timeoutDB
+}
+
+// NewWebhookSubscriptionRepository creates a new WebhookSubscriptionRepository
+func NewWebhookSubscriptionRepository() *WebhookSubscriptionRepository {
+	return &WebhookSubscriptionRepository{
+		db: withQueryTimeout(database.DB),
+	}
+}
+
+// Create creates a new webhook subscription of a user of an organization, or of the organization
+// itself when userID is empty
+func (r *WebhookSubscriptionRepository) Create(ctx context.Context, organizationID, userID, url, secret string, eventTypes []string) (*models.WebhookSubscription, error) {
+	subscription := &models.WebhookSubscription{
+		ID:             uuid.New().String(),
+		OrganizationID: organizationID,
+		UserID:         userID,
+		URL:            url,
+		Secret:         secret,
+		EventTypes:     eventTypes,
+		Active:         true,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	encoded, err := json.Marshal(subscription.EventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO webhook_subscriptions
+	(id, organization_id, user_id, url, secret, event_types, active, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = auditedExec(
+		ctx,
+		r.db,
+		rowTarget("webhook_subscription", "webhook_subscriptions", subscription.ID),
+		query,
+		subscription.ID,
+		subscription.OrganizationID,
+		nullString(subscription.UserID),
+		subscription.URL,
+		subscription.Secret,
+		encoded,
+		subscription.Active,
+		subscription.CreatedAt,
+		subscription.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// subscriberInOrganization limits webhook_subscriptions to those of the organization given as its
+// argument, whether of one of its users or of the organization itself
+const subscriberInOrganization = "organization_id = ?"
+
+// GetByID gets a webhook subscription of an organization by ID
+func (r *WebhookSubscriptionRepository) GetByID(ctx context.Context, organizationID, id string) (*models.WebhookSubscription, error) {
+	query := "SELECT " + webhookSubscriptionColumns + " FROM webhook_subscriptions WHERE id = ? AND " + subscriberInOrganization
+	return scanWebhookSubscription(r.db.QueryRowContext(ctx, query, id, organizationID))
+}
+
+// GetForDelivery gets the subscription a delivery is sent to. It serves the dispatcher, which sends
+// the deliveries of every organization, so it is not limited to one.
+func (r *WebhookSubscriptionRepository) GetForDelivery(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	query := "SELECT " + webhookSubscriptionColumns + " FROM webhook_subscriptions WHERE id = ?"
+	return scanWebhookSubscription(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetByUserID gets all webhook subscriptions of a user of an organization
+func (r *WebhookSubscriptionRepository) GetByUserID(ctx context.Context, organizationID, userID string) ([]*models.WebhookSubscription, error) {
+	query := "SELECT " + webhookSubscriptionColumns + " FROM webhook_subscriptions WHERE user_id = ? AND " + subscriberInOrganization +
+		" ORDER BY created_at ASC"
+	return r.query(ctx, query, userID, organizationID)
+}
+
+// GetOrganizationWide gets the webhook subscriptions of an organization itself
+func (r *WebhookSubscriptionRepository) GetOrganizationWide(ctx context.Context, organizationID string) ([]*models.WebhookSubscription, error) {
+	query := "SELECT " + webhookSubscriptionColumns + " FROM webhook_subscriptions WHERE user_id IS NULL AND " + subscriberInOrganization +
+		" ORDER BY created_at ASC"
+	return r.query(ctx, query, organizationID)
+}
+
+// GetActiveForEvent gets the active subscriptions that accept the event type of the user and of the
+// user's organization. Events are published for the user they concern.
+func (r *WebhookSubscriptionRepository) GetActiveForEvent(ctx context.Context, userID, eventType string) ([]*models.WebhookSubscription, error) {
+	query := "SELECT " + webhookSubscriptionColumns + ` FROM webhook_subscriptions
+	WHERE (user_id = ? OR (user_id IS NULL AND organization_id = (SELECT u.organization_id FROM users u WHERE u.id = ?)))
+	AND active = TRUE`
+	subscriptions, err := r.query(ctx, query, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := []*models.WebhookSubscription{}
+	for _, subscription := range subscriptions {
+		if subscription.Accepts(eventType) {
+			matching = append(matching, subscription)
+		}
+	}
+
+	return matching, nil
+}
+
+// SetActive enables or disables a webhook subscription of an organization
+func (r *WebhookSubscriptionRepository) SetActive(ctx context.Context, organizationID, id string, active bool) error {
+	query := "UPDATE webhook_subscriptions SET active = ?, updated_at = ? WHERE id = ? AND " + subscriberInOrganization
+	_, err := auditedExec(ctx, r.db, rowTarget("webhook_subscription", "webhook_subscriptions", id), query, active, time.Now(), id, organizationID)
+	return err
+}
+
+// Delete deletes a webhook subscription of an organization and its delivery log
+func (r *WebhookSubscriptionRepository) Delete(ctx context.Context, organizationID, id string) error {
+	query := "DELETE FROM webhook_subscriptions WHERE id = ? AND " + subscriberInOrganization
+	_, err := auditedExec(ctx, r.db, rowTarget("webhook_subscription", "webhook_subscriptions", id), query, id, organizationID)
+	return err
+}
+
+func (r *WebhookSubscriptionRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []*models.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+func scanWebhookSubscription(row rowScanner) (*models.WebhookSubscription, error) {
+	subscription := &models.WebhookSubscription{}
+	var userID sql.NullString
+	var eventTypes []byte
+
+	err := row.Scan(
+		&subscription.ID,
+		&subscription.OrganizationID,
+		&userID,
+		&subscription.URL,
+		&subscription.Secret,
+		&eventTypes,
+		&subscription.Active,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.UserID = userID.String
+	if err := json.Unmarshal(eventTypes, &subscription.EventTypes); err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
@@ -0,0 +1,329 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/auth"
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+	"github.com/jukemori/timeline-generator/internal/service"
+)
+
+// listGoals lists the goals the caller owns followed by those shared with them
+func (s *Server) listGoals(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	goalRepo := repository.NewGoalRepository()
+	owned, err := goalRepo.GetByUserID(r.Context(), user.OrganizationID, user.ID)
+	if err != nil {
+		return err
+	}
+	shared, err := goalRepo.GetSharedWithUserID(r.Context(), user.OrganizationID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, append(owned, shared...))
+	return nil
+}
+
+func (s *Server) getGoal(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	id := r.PathValue("id")
+	if err := service.CheckGoalAccess(r.Context(), user, id, models.GoalRoleViewer); err != nil {
+		return err
+	}
+
+	goal, err := repository.NewGoalRepository().GetVisible(r.Context(), user.OrganizationID, id, user.ID)
+	if err != nil {
+		return notFound(err, "goal", id)
+	}
+
+	writeJSON(w, http.StatusOK, goal)
+	return nil
+}
+
+func (s *Server) deleteGoal(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	id := r.PathValue("id")
+	if err := service.CheckGoalAccess(r.Context(), user, id, models.GoalRoleOwner); err != nil {
+		return err
+	}
+
+	if err := s.TrashService.Delete(r.Context(), models.TrashGoal, id); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) listTimelines(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	id := r.PathValue("id")
+	if err := service.CheckGoalAccess(r.Context(), user, id, models.GoalRoleViewer); err != nil {
+		return err
+	}
+
+	timelines, err := repository.NewTimelineRepository().GetVisibleByGoalID(r.Context(), user.OrganizationID, id, user.ID)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, timelines)
+	return nil
+}
+
+// generateTimeline generates a timeline and waits for it. Clients that cannot wait for the language
+// model should create a generation job instead.
+func (s *Server) generateTimeline(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	input, err := readTimelineInput(r)
+	if err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+	if err := s.GenerationLimiter.Acquire(ctx, user.ID, auth.ClientIP(ctx)); err != nil {
+		return err
+	}
+
+	timeline, err := s.TimelineGenerator.GenerateTimeline(ctx, user.ID, input)
+	if err != nil {
+		s.release(ctx, user.ID)
+		return err
+	}
+
+	w.Header().Set("Location", Prefix+"/timelines/"+timeline.ID)
+	writeJSON(w, http.StatusCreated, timeline)
+	return nil
+}
+
+func (s *Server) getTimeline(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	id := r.PathValue("id")
+	if err := service.CheckTimelineAccess(r.Context(), user, id, models.GoalRoleViewer); err != nil {
+		return err
+	}
+
+	timeline, err := repository.NewTimelineRepository().GetVisible(r.Context(), user.OrganizationID, id, user.ID)
+	if err != nil {
+		return notFound(err, "timeline", id)
+	}
+
+	writeJSON(w, http.StatusOK, timeline)
+	return nil
+}
+
+func (s *Server) deleteTimeline(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	id := r.PathValue("id")
+	if err := service.CheckTimelineAccess(r.Context(), user, id, models.GoalRoleEditor); err != nil {
+		return err
+	}
+
+	if err := s.TrashService.Delete(r.Context(), models.TrashTimeline, id); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// rescheduleRequest is the body of a reschedule request
+type rescheduleRequest struct {
+	// From is the date to reschedule from, today in the caller's time zone when empty
+	From string `json:"from"`
+}
+
+func (s *Server) rescheduleTimeline(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	var req rescheduleRequest
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+
+	start := clock.Today(s.Clock, user.Location())
+	if req.From != "" {
+		var err error
+		start, err = time.Parse(clock.DateLayout, req.From)
+		if err != nil {
+			return newError(http.StatusBadRequest, codeBadRequest, fmt.Sprintf("invalid from date %q", req.From))
+		}
+	}
+
+	id := r.PathValue("id")
+	if err := service.CheckTimelineAccess(r.Context(), user, id, models.GoalRoleEditor); err != nil {
+		return err
+	}
+
+	timeline, err := s.TimelineScheduler.Reschedule(r.Context(), user, id, start)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, timeline)
+	return nil
+}
+
+func (s *Server) getTask(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	id := r.PathValue("id")
+	if err := service.CheckTaskAccess(r.Context(), user, id, models.GoalRoleViewer); err != nil {
+		return err
+	}
+
+	task, err := repository.NewTaskRepository().GetVisible(r.Context(), user.OrganizationID, id, user.ID)
+	if err != nil {
+		return notFound(err, "task", id)
+	}
+
+	writeJSON(w, http.StatusOK, task)
+	return nil
+}
+
+// updateTaskRequest is the body of a task update
+type updateTaskRequest struct {
+	Completed *bool `json:"completed"`
+}
+
+func (s *Server) updateTask(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	var req updateTaskRequest
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	if req.Completed == nil {
+		return newError(http.StatusBadRequest, codeBadRequest, "completed is required")
+	}
+
+	id := r.PathValue("id")
+	if err := service.CheckTaskAccess(r.Context(), user, id, models.GoalRoleEditor); err != nil {
+		return err
+	}
+
+	task, err := s.TaskService.SetCompleted(r.Context(), user, id, *req.Completed)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, task)
+	return nil
+}
+
+func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	id := r.PathValue("id")
+	if err := service.CheckTaskAccess(r.Context(), user, id, models.GoalRoleEditor); err != nil {
+		return err
+	}
+
+	if err := s.TrashService.Delete(r.Context(), models.TrashTask, id); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// createGenerationJob queues a timeline generation and answers at once with the job to poll
+func (s *Server) createGenerationJob(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	input, err := readTimelineInput(r)
+	if err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+	if err := s.GenerationLimiter.Acquire(ctx, user.ID, auth.ClientIP(ctx)); err != nil {
+		return err
+	}
+
+	job, err := s.GenerationQueue.Enqueue(ctx, user.ID, input)
+	if err != nil {
+		s.release(ctx, user.ID)
+		return err
+	}
+
+	w.Header().Set("Location", Prefix+"/generation-jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+	return nil
+}
+
+func (s *Server) listGenerationJobs(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.JobStatusQueued, models.JobStatusRunning, models.JobStatusSucceeded, models.JobStatusFailed, models.JobStatusCancelled:
+	default:
+		return newError(http.StatusBadRequest, codeBadRequest, fmt.Sprintf("invalid status %q", status))
+	}
+
+	jobs, err := repository.NewGenerationJobRepository().GetByUserID(r.Context(), user.ID, status)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, jobs)
+	return nil
+}
+
+func (s *Server) getGenerationJob(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	job, err := loadGenerationJob(r.Context(), user, r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, job)
+	return nil
+}
+
+func (s *Server) cancelGenerationJob(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	job, err := loadGenerationJob(r.Context(), user, r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	job, err = s.GenerationQueue.Cancel(r.Context(), job.ID)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, job)
+	return nil
+}
+
+// release gives back the generation counted for a request that did not produce a timeline or job
+func (s *Server) release(ctx context.Context, userID string) {
+	if err := s.GenerationLimiter.Release(ctx, userID); err != nil {
+		log.Printf("failed to release generation usage for user %s: %v", userID, err)
+	}
+}
+
+// readTimelineInput reads and validates the description of a timeline to generate
+func readTimelineInput(r *http.Request) (models.TimelineInput, error) {
+	var input models.TimelineInput
+	if err := readJSON(r, &input); err != nil {
+		return input, err
+	}
+
+	if err := input.Validate(); err != nil {
+		return input, newError(http.StatusBadRequest, codeBadRequest, err.Error())
+	}
+	return input, nil
+}
+
+// loadGenerationJob loads a job owned by the caller, or any job in their organization for admins.
+// Other users' jobs are not found.
+func loadGenerationJob(ctx context.Context, user *models.User, id string) (*models.GenerationJob, error) {
+	job, err := repository.NewGenerationJobRepository().GetInOrganization(ctx, user.OrganizationID, id)
+	if err == nil && job.UserID != user.ID && !user.IsAdmin() {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, notFound(err, "generation job", id)
+	}
+
+	return job, nil
+}
+
+// notFound turns sql.ErrNoRows into a not found error for the resource, and returns other errors as they are
+func notFound(err error, resource, id string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return newError(http.StatusNotFound, codeNotFound, fmt.Sprintf("%s %s not found", resource, id))
+	}
+	return err
+}
@@ -0,0 +1,216 @@
+package rest
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/jukemori/timeline-generator/internal/auth"
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/jobs"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/ratelimit"
+	"github.com/jukemori/timeline-generator/internal/repository"
+	"github.com/jukemori/timeline-generator/internal/service"
+)
+
+// Prefix is the path every route of the API is under
+const Prefix = "/api/v1"
+
+// maxBodySize bounds the size of request bodies
+const maxBodySize = 1 << 20
+
+// openAPI describes the API. It is kept next to the handlers and must be updated along with them.
+//
+//go:embed openapi.yaml
+var openAPI []byte
+
+// Error codes of the API. Rate limit and quota errors use the codes in the ratelimit package.
+const (
+	codeBadRequest      = "BAD_REQUEST"
+	codeUnauthenticated = "UNAUTHENTICATED"
+	codeForbidden       = "FORBIDDEN"
+	codeNotFound        = "NOT_FOUND"
+	codeTimeout         = "TIMEOUT"
+	codeCancelled       = "CANCELLED"
+	codeInternal        = "INTERNAL"
+)
+
+// Server serves goals, timelines, tasks and timeline generation as JSON for clients that do not speak
+// GraphQL. It uses the same services as the GraphQL resolvers.
+type Server struct {
+	TimelineGenerator *service.TimelineGenerator
+	GenerationLimiter *service.GenerationLimiter
+	GenerationQueue   *jobs.Queue
+	TaskService       *service.TaskService
+	TimelineScheduler *service.TimelineScheduler
+	TrashService      *service.TrashService
+	Clock             clock.Clock
+}
+
+// Handler routes requests under Prefix. It expects auth.Authenticator.Middleware to have identified the caller.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET "+Prefix+"/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPI)
+	})
+
+	for _, route := range s.routes() {
+		mux.Handle(route.method+" "+Prefix+route.path, s.handle(route.handler))
+	}
+
+	mux.HandleFunc(Prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, newError(http.StatusNotFound, codeNotFound, "no such endpoint"))
+	})
+
+	return mux
+}
+
+// route is an endpoint of the API. Every route must be described in openapi.yaml.
+type route struct {
+	method  string
+	path    string
+	handler handlerFunc
+}
+
+// routes lists the endpoints of the API by their path under Prefix
+func (s *Server) routes() []route {
+	return []route{
+		{http.MethodGet, "/goals", s.listGoals},
+		{http.MethodGet, "/goals/{id}", s.getGoal},
+		{http.MethodDelete, "/goals/{id}", s.deleteGoal},
+		{http.MethodGet, "/goals/{id}/timelines", s.listTimelines},
+
+		{http.MethodPost, "/timelines", s.generateTimeline},
+		{http.MethodGet, "/timelines/{id}", s.getTimeline},
+		{http.MethodDelete, "/timelines/{id}", s.deleteTimeline},
+		{http.MethodPost, "/timelines/{id}/reschedule", s.rescheduleTimeline},
+
+		{http.MethodGet, "/tasks/{id}", s.getTask},
+		{http.MethodPatch, "/tasks/{id}", s.updateTask},
+		{http.MethodDelete, "/tasks/{id}", s.deleteTask},
+
+		{http.MethodPost, "/generation-jobs", s.createGenerationJob},
+		{http.MethodGet, "/generation-jobs", s.listGenerationJobs},
+		{http.MethodGet, "/generation-jobs/{id}", s.getGenerationJob},
+		{http.MethodPost, "/generation-jobs/{id}/cancel", s.cancelGenerationJob},
+	}
+}
+
+// handlerFunc handles a request made by an authenticated user. Errors it returns are written as the
+// error body by handle.
+type handlerFunc func(w http.ResponseWriter, r *http.Request, user *models.User) error
+
+// handle loads the calling user and serves the request with fn, writing any error it returns
+func (s *Server) handle(fn handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := currentUser(r.Context())
+		if err == nil {
+			err = fn(w, r, user)
+		}
+		if err != nil {
+			writeError(w, r, err)
+		}
+	})
+}
+
+// currentUser loads the user making the request
+func currentUser(ctx context.Context) (*models.User, error) {
+	userID := auth.UserID(ctx)
+	if userID == "" {
+		return nil, newError(http.StatusUnauthorized, codeUnauthenticated, "authentication required")
+	}
+
+	user, err := repository.NewUserRepository().GetByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, newError(http.StatusUnauthorized, codeUnauthenticated, "unknown user")
+	}
+	return user, err
+}
+
+// apiError is an error to report to the client with its HTTP status and code
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func newError(status int, code, message string) *apiError {
+	return &apiError{Status: status, Code: code, Message: message}
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+// errorBody is the body of every error response
+type errorBody struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// serviceStatus is the HTTP status of each code of the errors services report
+var serviceStatus = map[string]int{
+	service.CodeInvalid:       http.StatusBadRequest,
+	service.CodeForbidden:     http.StatusForbidden,
+	service.CodeNotFound:      http.StatusNotFound,
+	service.CodeConflict:      http.StatusConflict,
+	service.CodeUnprocessable: http.StatusUnprocessableEntity,
+}
+
+// writeError writes err as an error response. Errors not meant for the client are logged and reported
+// as internal errors.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *apiError
+	var limitErr *ratelimit.Error
+	var serviceErr *service.Error
+	switch {
+	case errors.As(err, &apiErr):
+	case errors.As(err, &serviceErr):
+		apiErr = newError(serviceStatus[serviceErr.Code], serviceErr.Code, serviceErr.Message)
+	case errors.As(err, &limitErr):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+		apiErr = newError(http.StatusTooManyRequests, limitErr.Code, limitErr.Message)
+	case errors.Is(err, context.DeadlineExceeded):
+		apiErr = newError(http.StatusGatewayTimeout, codeTimeout, "the request timed out")
+	case errors.Is(err, context.Canceled):
+		apiErr = newError(http.StatusServiceUnavailable, codeCancelled, "the request was cancelled")
+	default:
+		log.Printf("%s %s failed: %v", r.Method, r.URL.Path, err)
+		apiErr = newError(http.StatusInternalServerError, codeInternal, "internal error")
+	}
+
+	var body errorBody
+	body.Error.Code = apiErr.Code
+	body.Error.Message = apiErr.Message
+	writeJSON(w, apiErr.Status, body)
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// readJSON decodes a JSON request body into v, rejecting unknown fields. An empty body leaves v as it is.
+func readJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return newError(http.StatusBadRequest, codeBadRequest, fmt.Sprintf("invalid request body: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jukemori/timeline-generator/internal/service"
+	"gopkg.in/yaml.v3"
+)
+
+// TestRoutesMatchOpenAPI checks every route is described in openapi.yaml and every operation it
+// describes is routed
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]yaml.Node `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(openAPI, &spec); err != nil {
+		t.Fatalf("failed to parse openapi.yaml: %v", err)
+	}
+
+	documented := make(map[string]bool)
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	routed := make(map[string]bool)
+	for _, route := range (&Server{}).routes() {
+		routed[route.method+" "+route.path] = true
+	}
+
+	for _, endpoint := range sortedKeys(routed) {
+		if !documented[endpoint] {
+			t.Errorf("%s is routed but not described in openapi.yaml", endpoint)
+		}
+	}
+	for _, endpoint := range sortedKeys(documented) {
+		if !routed[endpoint] {
+			t.Errorf("%s is described in openapi.yaml but not routed", endpoint)
+		}
+	}
+}
+
+// TestUnknownRoute checks requests outside the routes get a not found error body
+func TestUnknownRoute(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	(&Server{}).Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Prefix+"/unknown", nil))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+	if !strings.Contains(recorder.Body.String(), codeNotFound) {
+		t.Errorf("body = %s, want code %s", recorder.Body.String(), codeNotFound)
+	}
+}
+
+// TestWriteErrorServiceError checks errors services report are written with their code and status
+func TestWriteErrorServiceError(t *testing.T) {
+	tests := []struct {
+		code   string
+		status int
+	}{
+		{service.CodeInvalid, http.StatusBadRequest},
+		{service.CodeForbidden, http.StatusForbidden},
+		{service.CodeNotFound, http.StatusNotFound},
+		{service.CodeConflict, http.StatusConflict},
+		{service.CodeUnprocessable, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			err := fmt.Errorf("wrapped: %w", &service.Error{Code: tt.code, Message: "refused"})
+			writeError(recorder, httptest.NewRequest(http.MethodGet, "/", nil), err)
+
+			if recorder.Code != tt.status {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.status)
+			}
+			var body errorBody
+			if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if body.Error.Code != tt.code || body.Error.Message != "refused" {
+				t.Errorf("error = %+v, want code %s and message refused", body.Error, tt.code)
+			}
+		})
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
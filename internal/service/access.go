@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// CheckGoalAccess checks the user has at least the required role on a goal. Goals that are not
+// shared with them are not found, like those that do not exist.
+func CheckGoalAccess(ctx context.Context, user *models.User, goalID, required string) error {
+	role, err := repository.NewGoalMemberRepository().GetRole(ctx, goalID, user.ID)
+	return checkRole(role, err, required, "goal", goalID)
+}
+
+// CheckTimelineAccess checks the user has at least the required role on the goal a timeline belongs
+// to. Timelines on goals not shared with them are not found.
+func CheckTimelineAccess(ctx context.Context, user *models.User, timelineID, required string) error {
+	role, err := repository.NewTimelineRepository().GetRole(ctx, timelineID, user.ID)
+	return checkRole(role, err, required, "timeline", timelineID)
+}
+
+// CheckTaskAccess checks the user has at least the required role on the goal a task belongs to.
+// Tasks on goals not shared with them are not found.
+func CheckTaskAccess(ctx context.Context, user *models.User, taskID, required string) error {
+	role, err := repository.NewTaskRepository().GetRole(ctx, taskID, user.ID)
+	return checkRole(role, err, required, "task", taskID)
+}
+
+// CheckTrashAccess checks the user can restore an item in the trash: goals by their owner, and
+// timelines and tasks by editors of their goal
+func CheckTrashAccess(ctx context.Context, user *models.User, kind, id string) error {
+	role, err := repository.NewTrashRepository().GetRole(ctx, kind, id, user.ID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && role == "") {
+		return notFound("%s %s not found in the trash", kind, id)
+	}
+
+	required := models.GoalRoleEditor
+	if kind == models.TrashGoal {
+		required = models.GoalRoleOwner
+	}
+	return checkRole(role, err, required, kind, id)
+}
+
+// checkRole turns the result of looking up a user's role on a resource into a not found error when
+// they have none, and a forbidden error unless it grants the required access
+func checkRole(role string, err error, required, resource, id string) error {
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && role == "") {
+		return notFound("%s %s not found", resource, id)
+	}
+	if err != nil {
+		return err
+	}
+	if !models.GoalRoleAllows(role, required) {
+		return newError(CodeForbidden, "%s access to this %s required", required, resource)
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jukemori/timeline-generator/internal/activity"
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// maxCommentLength is the longest comment body allowed, in characters
+const maxCommentLength = 10000
+
+// mentionPattern matches mentions of users by email, such as "@sam@example.com"
+var mentionPattern = regexp.MustCompile(`@([A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// CommentService is the service for discussing goals and their tasks, and for following what happens
+// on timelines
+type CommentService struct {
+	commentRepo  *repository.CommentRepository
+	activityRepo *repository.ActivityRepository
+	memberRepo   *repository.GoalMemberRepository
+	timelineRepo *repository.TimelineRepository
+	taskRepo     *repository.TaskRepository
+	hub          *activity.Hub
+	events       *events.Bus
+}
+
+// NewCommentService creates a new CommentService that sends comments to the hub's subscribers
+func NewCommentService(hub *activity.Hub, bus *events.Bus) *CommentService {
+	return &CommentService{
+		commentRepo:  repository.NewCommentRepository(),
+		activityRepo: repository.NewActivityRepository(),
+		memberRepo:   repository.NewGoalMemberRepository(),
+		timelineRepo: repository.NewTimelineRepository(),
+		taskRepo:     repository.NewTaskRepository(),
+		hub:          hub,
+		events:       bus,
+	}
+}
+
+// GoalComments gets the threads of comments on a goal of the user's organization itself
+func (s *CommentService) GoalComments(ctx context.Context, user *models.User, goalID string) ([]*models.Comment, error) {
+	comments, err := s.commentRepo.GetByGoalID(ctx, user.OrganizationID, goalID)
+	if err != nil {
+		return nil, err
+	}
+	return threads(comments), nil
+}
+
+// TaskComments gets the threads of comments on a task on a goal of the user's organization
+func (s *CommentService) TaskComments(ctx context.Context, user *models.User, taskID string) ([]*models.Comment, error) {
+	comments, err := s.commentRepo.GetByTaskID(ctx, user.OrganizationID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return threads(comments), nil
+}
+
+// Create comments on a goal, or on one of its tasks when taskID is set, optionally in reply to another
+// comment on the same goal or task. Users with access to the goal who are mentioned by email, as in
+// "@sam@example.com", are sent comment.mention.
+func (s *CommentService) Create(ctx context.Context, author *models.User, goalID, taskID, parentID, body string) (*models.Comment, error) {
+	body, err := validateCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID != "" {
+		parent, err := s.commentRepo.GetByID(ctx, author.OrganizationID, parentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, notFound("comment %s not found", parentID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if parent.DeletedAt != nil {
+			return nil, notFound("comment %s not found", parentID)
+		}
+		if parent.GoalID != goalID || parent.TaskID != taskID {
+			return nil, invalid("replies must be on the same goal or task as the comment they reply to")
+		}
+	}
+
+	mentions, err := s.mentions(ctx, goalID, body)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &models.Comment{
+		GoalID:      goalID,
+		TaskID:      taskID,
+		ParentID:    parentID,
+		UserID:      author.ID,
+		AuthorEmail: author.Email,
+		Body:        body,
+		Mentions:    mentions,
+	}
+	if err := s.commentRepo.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	s.notifyMentions(ctx, comment, nil)
+	s.broadcast(ctx, author.OrganizationID, comment, models.ActivityCommented, comment.CreatedAt)
+
+	return comment, nil
+}
+
+// Edit replaces the body of a comment on a goal of the editor's organization. Only users newly
+// mentioned by the edit are sent comment.mention.
+func (s *CommentService) Edit(ctx context.Context, editor *models.User, comment *models.Comment, body string) (*models.Comment, error) {
+	if comment.DeletedAt != nil {
+		return nil, notFound("comment %s not found", comment.ID)
+	}
+
+	body, err := validateCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions, err := s.mentions(ctx, comment.GoalID, body)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := comment.Mentions
+	comment.Body = body
+	comment.Mentions = mentions
+	if err := s.commentRepo.Update(ctx, editor.OrganizationID, comment); err != nil {
+		return nil, err
+	}
+
+	s.notifyMentions(ctx, comment, previous)
+	s.broadcast(ctx, editor.OrganizationID, comment, models.ActivityCommentEdited, *comment.EditedAt)
+
+	return comment, nil
+}
+
+// Delete deletes a comment on a goal of the user's organization. Its replies stay in the thread under
+// what is left of it.
+func (s *CommentService) Delete(ctx context.Context, user *models.User, comment *models.Comment) error {
+	if comment.DeletedAt != nil {
+		return notFound("comment %s not found", comment.ID)
+	}
+
+	if err := s.commentRepo.Delete(ctx, user.OrganizationID, comment.ID); err != nil {
+		return err
+	}
+
+	s.broadcast(ctx, user.OrganizationID, comment, models.ActivityCommentDeleted, time.Now())
+	return nil
+}
+
+// Feed gets up to limit items of the activity of a timeline on a goal of the user's organization from
+// before a time, newest first: its creation, reschedules, completed tasks, and comments on its tasks and its goal
+func (s *CommentService) Feed(ctx context.Context, user *models.User, timelineID string, before time.Time, limit int) ([]*models.ActivityItem, error) {
+	timeline, err := s.timelineRepo.GetByID(ctx, user.OrganizationID, timelineID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.activityRepo.GetByTimelineID(ctx, timelineID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := s.commentRepo.GetByTimelineID(ctx, user.OrganizationID, timelineID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		items = append(items, commentActivity(comment, timelineID, models.ActivityCommented, comment.CreatedAt))
+	}
+
+	if timeline.CreatedAt.Before(before) {
+		ownerID, err := s.timelineRepo.GetOwnerID(ctx, user.OrganizationID, timelineID)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &models.ActivityItem{
+			ID:         timeline.ID,
+			TimelineID: timeline.ID,
+			UserID:     ownerID,
+			Kind:       models.ActivityCreated,
+			OccurredAt: timeline.CreatedAt,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].OccurredAt.After(items[j].OccurredAt) })
+	if len(items) > limit {
+		items = items[:limit]
+	}
+
+	return items, nil
+}
+
+// mentions finds the users with access to the goal whose email the body mentions
+func (s *CommentService) mentions(ctx context.Context, goalID, body string) ([]string, error) {
+	mentions := []string{}
+
+	matches := mentionPattern.FindAllStringSubmatch(body, -1)
+	if len(matches) == 0 {
+		return mentions, nil
+	}
+
+	members, err := s.memberRepo.GetByGoalID(ctx, goalID)
+	if err != nil {
+		return nil, err
+	}
+	byEmail := make(map[string]string, len(members))
+	for _, member := range members {
+		byEmail[strings.ToLower(member.Email)] = member.UserID
+	}
+
+	seen := map[string]bool{}
+	for _, match := range matches {
+		userID, ok := byEmail[strings.ToLower(match[1])]
+		if ok && !seen[userID] {
+			seen[userID] = true
+			mentions = append(mentions, userID)
+		}
+	}
+
+	return mentions, nil
+}
+
+// notifyMentions publishes comment.mention for every user the comment mentions, other than its author
+// and those already mentioned before
+func (s *CommentService) notifyMentions(ctx context.Context, comment *models.Comment, previous []string) {
+	notified := map[string]bool{comment.UserID: true}
+	for _, userID := range previous {
+		notified[userID] = true
+	}
+
+	for _, userID := range comment.Mentions {
+		if !notified[userID] {
+			s.events.Publish(ctx, events.CommentMention, userID, events.NewMentionData(comment))
+		}
+	}
+}
+
+// broadcast sends comment activity to the subscribers of the timelines the comment is on: the task's
+// timeline, or every timeline of the goal. The goal belongs to the organization given.
+func (s *CommentService) broadcast(ctx context.Context, organizationID string, comment *models.Comment, kind string, occurredAt time.Time) {
+	var timelineIDs []string
+	if comment.TaskID != "" {
+		task, err := s.taskRepo.GetByID(ctx, organizationID, comment.TaskID)
+		if err != nil {
+			log.Printf("failed to send comment %s to subscribers: %v", comment.ID, err)
+			return
+		}
+		timelineIDs = []string{task.TimelineID}
+	} else {
+		var err error
+		if timelineIDs, err = s.timelineRepo.GetIDsByGoalID(ctx, organizationID, comment.GoalID); err != nil {
+			log.Printf("failed to send comment %s to subscribers: %v", comment.ID, err)
+			return
+		}
+	}
+
+	for _, timelineID := range timelineIDs {
+		s.hub.Broadcast(commentActivity(comment, timelineID, kind, occurredAt))
+	}
+}
+
+// commentActivity is the activity item for a comment on a timeline
+func commentActivity(comment *models.Comment, timelineID, kind string, occurredAt time.Time) *models.ActivityItem {
+	return &models.ActivityItem{
+		ID:         comment.ID,
+		TimelineID: timelineID,
+		TaskID:     comment.TaskID,
+		UserID:     comment.UserID,
+		Kind:       kind,
+		Comment:    comment,
+		OccurredAt: occurredAt,
+	}
+}
+
+// threads nests replies under the comments they reply to. Comments keep their order at each level.
+func threads(comments []*models.Comment) []*models.Comment {
+	byID := make(map[string]*models.Comment, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+	}
+
+	roots := []*models.Comment{}
+	for _, comment := range comments {
+		if parent, ok := byID[comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, comment)
+		} else {
+			roots = append(roots, comment)
+		}
+	}
+	return roots
+}
+
+// validateCommentBody trims a comment body and checks it is neither empty nor too long
+func validateCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", invalid("comment must not be empty")
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return "", invalid("comment must be at most %d characters", maxCommentLength)
+	}
+	return body, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/audit"
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// DeadlineMonitor publishes task.deadline_missed once for each incomplete task past its end date
+// in the owner's time zone
+type DeadlineMonitor struct {
+	taskRepo *repository.TaskRepository
+	events   *events.Bus
+	clock    clock.Clock
+	interval time.Duration
+}
+
+// NewDeadlineMonitor creates a new DeadlineMonitor that checks at the given interval
+func NewDeadlineMonitor(bus *events.Bus, clk clock.Clock, interval time.Duration) *DeadlineMonitor {
+	return &DeadlineMonitor{
+		taskRepo: repository.NewTaskRepository(),
+		events:   bus,
+		clock:    clk,
+		interval: interval,
+	}
+}
+
+// Run checks for missed deadlines until ctx is cancelled
+func (m *DeadlineMonitor) Run(ctx context.Context) {
+	ctx = audit.WithOperation(ctx, "deadline monitor")
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check reports tasks whose end date has passed
+func (m *DeadlineMonitor) check(ctx context.Context) {
+	now := m.clock.Now()
+
+	// No time zone is more than a day ahead of UTC, so this covers every task that could have been missed
+	tasks, err := m.taskRepo.GetMissedDeadlines(ctx, clock.Date(now, time.UTC).AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("failed to check missed deadlines: %v", err)
+		return
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		if !task.EndDate.Before(clock.Date(now, clock.Location(task.TimeZone))) {
+			continue
+		}
+
+		marked, err := m.taskRepo.MarkDeadlineMissed(ctx, task.ID)
+		if err != nil {
+			log.Printf("failed to mark missed deadline of task %s: %v", task.ID, err)
+			continue
+		}
+		if marked {
+			m.events.Publish(ctx, events.TaskDeadlineMissed, task.UserID, events.NewTaskData(&task.TimelineTask))
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import "fmt"
+
+// Codes of the errors services report for requests they refuse
+const (
+	CodeInvalid       = "BAD_REQUEST"
+	CodeForbidden     = "FORBIDDEN"
+	CodeNotFound      = "NOT_FOUND"
+	CodeConflict      = "CONFLICT"
+	CodeUnprocessable = "UNPROCESSABLE"
+)
+
+// Error is an error caused by the request rather than the server. Its message is safe to show to
+// the caller, and the APIs report it with its code instead of as an internal error.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// newError creates an Error with a formatted message
+func newError(code, format string, args ...interface{}) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// invalid reports a request with an invalid argument
+func invalid(format string, args ...interface{}) error {
+	return newError(CodeInvalid, format, args...)
+}
+
+// notFound reports a resource that does not exist or that the caller cannot see
+func notFound(format string, args ...interface{}) error {
+	return newError(CodeNotFound, format, args...)
+}
+
+// NotFound reports a resource, named by its kind and ID, that does not exist or that the caller
+// cannot see, as the access checks do
+func NotFound(resource, id string) error {
+	return notFound("%s %s not found", resource, id)
+}
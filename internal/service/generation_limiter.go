@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/ratelimit"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// GenerationLimits configures how often timelines can be generated
+type GenerationLimits struct {
+	UserPerMinute float64
+	UserBurst     int
+	IPPerMinute   float64
+	IPBurst       int
+	MonthlyQuota  int
+}
+
+// GenerationLimiter meters timeline generation per user and per IP, and enforces the monthly
+// generation quotas stored in the database for users and their organizations
+type GenerationLimiter struct {
+	userLimiter  *ratelimit.Limiter
+	ipLimiter    *ratelimit.Limiter
+	usageRepo    *repository.GenerationUsageRepository
+	clock        clock.Clock
+	monthlyQuota int
+}
+
+// NewGenerationLimiter creates a new GenerationLimiter
+func NewGenerationLimiter(limits GenerationLimits, clk clock.Clock) *GenerationLimiter {
+	return &GenerationLimiter{
+		userLimiter:  ratelimit.NewLimiter(limits.UserPerMinute, limits.UserBurst),
+		ipLimiter:    ratelimit.NewLimiter(limits.IPPerMinute, limits.IPBurst),
+		usageRepo:    repository.NewGenerationUsageRepository(),
+		clock:        clk,
+		monthlyQuota: limits.MonthlyQuota,
+	}
+}
+
+// Acquire checks the rate limits and counts one generation against the user's monthly quota.
+// Callers must call Release if the generation does not complete.
+func (l *GenerationLimiter) Acquire(ctx context.Context, userID, clientIP string) error {
+	if clientIP != "" {
+		if ok, retryAfter := l.ipLimiter.Allow(clientIP); !ok {
+			return &ratelimit.Error{
+				Code:       ratelimit.CodeRateLimited,
+				Message:    "too many timeline generations from this address",
+				RetryAfter: retryAfter,
+			}
+		}
+	}
+
+	if ok, retryAfter := l.userLimiter.Allow(userID); !ok {
+		return &ratelimit.Error{
+			Code:       ratelimit.CodeRateLimited,
+			Message:    "too many timeline generations",
+			RetryAfter: retryAfter,
+		}
+	}
+
+	limit, err := l.monthlyLimit(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	now := l.clock.Now().UTC()
+	reserved, err := l.usageRepo.Reserve(ctx, userID, UsagePeriod(now), limit)
+	if err != nil {
+		return fmt.Errorf("failed to record generation usage: %w", err)
+	}
+	if !reserved {
+		return &ratelimit.Error{
+			Code:       ratelimit.CodeQuotaExceeded,
+			Message:    fmt.Sprintf("monthly generation quota of %d reached", limit),
+			RetryAfter: nextPeriodStart(now).Sub(now),
+		}
+	}
+
+	return nil
+}
+
+// Release gives back the generation counted by Acquire
+func (l *GenerationLimiter) Release(ctx context.Context, userID string) error {
+	return l.usageRepo.Release(ctx, userID, UsagePeriod(l.clock.Now().UTC()))
+}
+
+// Usage gets the usage for the period of a user of an organization
+func (l *GenerationLimiter) Usage(ctx context.Context, organizationID, userID, period string) (*models.GenerationUsage, error) {
+	return l.usageRepo.GetByUserID(ctx, organizationID, userID, period, l.monthlyQuota)
+}
+
+// AllUsage gets the usage of every user of an organization for the period
+func (l *GenerationLimiter) AllUsage(ctx context.Context, organizationID, period string) ([]*models.GenerationUsage, error) {
+	return l.usageRepo.GetByPeriod(ctx, organizationID, period, l.monthlyQuota)
+}
+
+// ResetUsage clears the usage for the period of a user of an organization
+func (l *GenerationLimiter) ResetUsage(ctx context.Context, organizationID, userID, period string) (*models.GenerationUsage, error) {
+	if err := l.usageRepo.Reset(ctx, organizationID, userID, period); err != nil {
+		return nil, err
+	}
+	return l.Usage(ctx, organizationID, userID, period)
+}
+
+// SetMonthlyQuota overrides the monthly quota for a user of an organization
+func (l *GenerationLimiter) SetMonthlyQuota(ctx context.Context, organizationID, userID string, quota int) (*models.GenerationUsage, error) {
+	if quota < 0 {
+		return nil, invalid("monthly quota must not be negative")
+	}
+	if err := l.usageRepo.SetMonthlyLimit(ctx, organizationID, userID, quota); err != nil {
+		return nil, err
+	}
+	return l.Usage(ctx, organizationID, userID, UsagePeriod(l.clock.Now().UTC()))
+}
+
+func (l *GenerationLimiter) monthlyLimit(ctx context.Context, userID string) (int, error) {
+	limit, err := l.usageRepo.GetMonthlyLimit(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return l.monthlyQuota, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to load generation quota: %w", err)
+	}
+	return limit, nil
+}
+
+// UsagePeriod returns the quota period ("YYYY-MM") containing t
+func UsagePeriod(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+func nextPeriodStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+}
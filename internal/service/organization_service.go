@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"net/mail"
+	"strings"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/openai"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// OrganizationService is the service for administering an organization: its settings and its users
+type OrganizationService struct {
+	orgRepo  *repository.OrganizationRepository
+	userRepo *repository.UserRepository
+}
+
+// NewOrganizationService creates a new OrganizationService
+func NewOrganizationService() *OrganizationService {
+	return &OrganizationService{
+		orgRepo:  repository.NewOrganizationRepository(),
+		userRepo: repository.NewUserRepository(),
+	}
+}
+
+// Get gets an organization
+func (s *OrganizationService) Get(ctx context.Context, organizationID string) (*models.Organization, error) {
+	return s.orgRepo.GetByID(ctx, organizationID)
+}
+
+// UpdateSettings validates and replaces an organization's settings.
+// The default model must be one whose cost is known, and the prompt template must render.
+func (s *OrganizationService) UpdateSettings(ctx context.Context, organizationID string, settings models.OrganizationSettings) (*models.Organization, error) {
+	settings.DefaultModel = strings.TrimSpace(settings.DefaultModel)
+	if settings.DefaultModel != "" {
+		if _, ok := openai.PriceFor(settings.DefaultModel); !ok {
+			return nil, invalid("unknown model %q", settings.DefaultModel)
+		}
+	}
+	if strings.TrimSpace(settings.PromptTemplate) == "" {
+		settings.PromptTemplate = ""
+	} else if err := validatePromptTemplate(settings.PromptTemplate); err != nil {
+		return nil, err
+	}
+	if settings.MonthlyQuota != nil && *settings.MonthlyQuota < 0 {
+		return nil, invalid("monthly quota must not be negative")
+	}
+
+	if err := s.orgRepo.UpdateSettings(ctx, organizationID, settings); err != nil {
+		return nil, err
+	}
+	return s.orgRepo.GetByID(ctx, organizationID)
+}
+
+// Users gets every user of an organization
+func (s *OrganizationService) Users(ctx context.Context, organizationID string) ([]*models.User, error) {
+	return s.userRepo.GetByOrganizationID(ctx, organizationID)
+}
+
+// CreateUser adds a user to an organization
+func (s *OrganizationService) CreateUser(ctx context.Context, organizationID, email, role string) (*models.User, error) {
+	if !models.IsUserRole(role) {
+		return nil, invalid("invalid role %q", role)
+	}
+
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return nil, invalid("invalid email address %q", email)
+	}
+
+	return s.userRepo.Create(ctx, organizationID, strings.ToLower(address.Address), role)
+}
+
+// SetUserRole changes the role of a user of the admin's organization.
+// Admins cannot change their own role, so an organization always keeps an admin.
+func (s *OrganizationService) SetUserRole(ctx context.Context, admin *models.User, userID, role string) (*models.User, error) {
+	if !models.IsUserRole(role) {
+		return nil, invalid("invalid role %q", role)
+	}
+	if userID == admin.ID {
+		return nil, newError(CodeForbidden, "admins cannot change their own role")
+	}
+
+	if err := s.userRepo.UpdateRole(ctx, admin.OrganizationID, userID, role); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetInOrganization(ctx, admin.OrganizationID, userID)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// PromptData is what an organization's prompt template is rendered with, as in
+// "{{.Default}}\nWrite every task in {{.Goal}} for our engineers."
+type PromptData struct {
+	CurrentLevel     string
+	Goal             string
+	Objectives       string
+	CurrentDate      string
+	TargetDate       string
+	WorkingDays      string
+	HoursPerDay      float64
+	HoursPerWeek     float64
+	UnavailableDates string
+	// Default is the built-in prompt, for templates that add to it rather than replace it
+	Default string
+}
+
+// samplePromptData is used to check prompt templates render before they are saved
+var samplePromptData = PromptData{
+	CurrentLevel:     "Beginner",
+	Goal:             "Pass the JLPT N3",
+	Objectives:       "Read graded readers without a dictionary",
+	CurrentDate:      "2025-01-06",
+	TargetDate:       "2025-07-06",
+	WorkingDays:      "Monday, Tuesday, Wednesday, Thursday, Friday",
+	HoursPerDay:      2,
+	HoursPerWeek:     10,
+	UnavailableDates: "none",
+	Default:          "the built-in prompt",
+}
+
+// renderPrompt renders a prompt template
+func renderPrompt(text string, data PromptData) (string, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt template: %w", err)
+	}
+
+	var prompt strings.Builder
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		return "", fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return prompt.String(), nil
+}
+
+// validatePromptTemplate checks a prompt template parses and renders
+func validatePromptTemplate(text string) error {
+	prompt, err := renderPrompt(text, samplePromptData)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return invalid("invalid prompt template: it renders an empty prompt")
+	}
+	return nil
+}
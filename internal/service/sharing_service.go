@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/mail"
+	"strings"
+
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// SharingService is the service for sharing goals, and their timelines, with other users
+type SharingService struct {
+	goalRepo       *repository.GoalRepository
+	memberRepo     *repository.GoalMemberRepository
+	invitationRepo *repository.GoalInvitationRepository
+	userRepo       *repository.UserRepository
+	events         *events.Bus
+}
+
+// NewSharingService creates a new SharingService
+func NewSharingService(bus *events.Bus) *SharingService {
+	return &SharingService{
+		goalRepo:       repository.NewGoalRepository(),
+		memberRepo:     repository.NewGoalMemberRepository(),
+		invitationRepo: repository.NewGoalInvitationRepository(),
+		userRepo:       repository.NewUserRepository(),
+		events:         bus,
+	}
+}
+
+// Role gets the role a user has on a goal, or an empty role if the goal is not shared with them
+func (s *SharingService) Role(ctx context.Context, goalID, userID string) (string, error) {
+	return s.memberRepo.GetRole(ctx, goalID, userID)
+}
+
+// Members gets everyone with access to a goal, its owner first
+func (s *SharingService) Members(ctx context.Context, goalID string) ([]*models.GoalMember, error) {
+	return s.memberRepo.GetByGoalID(ctx, goalID)
+}
+
+// Invite invites an email address to a goal under a role and publishes goal.invitation, which emails
+// the invitation. Whoever signs in with the address can then accept it.
+func (s *SharingService) Invite(ctx context.Context, inviter *models.User, goalID, email, role string) (*models.GoalInvitation, error) {
+	if !models.IsShareableGoalRole(role) {
+		return nil, invalid("invalid role %q: goals can be shared with viewers, commenters and editors", role)
+	}
+
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return nil, invalid("invalid email address %q", email)
+	}
+	email = strings.ToLower(address.Address)
+
+	invitee, err := s.userRepo.GetByEmailInOrganization(ctx, inviter.OrganizationID, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if invitee != nil {
+		current, err := s.memberRepo.GetRole(ctx, goalID, invitee.ID)
+		if err != nil {
+			return nil, err
+		}
+		if current == models.GoalRoleOwner {
+			return nil, newError(CodeConflict, "%s owns this goal", email)
+		}
+		if current != "" {
+			return nil, newError(CodeConflict, "goal is already shared with %s; change their role instead", email)
+		}
+	}
+
+	invitation, err := s.invitationRepo.Save(ctx, goalID, email, role, inviter.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ctx, events.GoalInvitation, inviter.ID, events.NewInvitationData(invitation))
+
+	return invitation, nil
+}
+
+// Invitations gets the pending invitations sent to a user's email address within their organization
+func (s *SharingService) Invitations(ctx context.Context, user *models.User) ([]*models.GoalInvitation, error) {
+	return s.invitationRepo.GetByEmail(ctx, user.OrganizationID, strings.ToLower(user.Email))
+}
+
+// GoalInvitations gets the pending invitations to a goal
+func (s *SharingService) GoalInvitations(ctx context.Context, goalID string) ([]*models.GoalInvitation, error) {
+	return s.invitationRepo.GetByGoalID(ctx, goalID)
+}
+
+// Accept shares the goal of an invitation sent to the user's email address with them
+func (s *SharingService) Accept(ctx context.Context, user *models.User, invitation *models.GoalInvitation) (*models.GoalMember, error) {
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, newError(CodeForbidden, "invitation was sent to a different email address")
+	}
+
+	if err := s.invitationRepo.Accept(ctx, invitation, user.ID); err != nil {
+		return nil, err
+	}
+
+	return s.member(ctx, invitation.GoalID, user.ID)
+}
+
+// DeleteInvitation declines or revokes an invitation
+func (s *SharingService) DeleteInvitation(ctx context.Context, id string) error {
+	return s.invitationRepo.Delete(ctx, id)
+}
+
+// SetRole changes the role of a user a goal is shared with
+func (s *SharingService) SetRole(ctx context.Context, goalID, userID, role string) (*models.GoalMember, error) {
+	if !models.IsShareableGoalRole(role) {
+		return nil, invalid("invalid role %q: goals can be shared with viewers, commenters and editors", role)
+	}
+
+	current, err := s.memberRepo.GetRole(ctx, goalID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if current == models.GoalRoleOwner {
+		return nil, newError(CodeConflict, "the owner's role cannot be changed")
+	}
+	if current == "" {
+		return nil, notFound("goal is not shared with user %s", userID)
+	}
+
+	if err := s.memberRepo.Save(ctx, goalID, userID, role); err != nil {
+		return nil, err
+	}
+
+	return s.member(ctx, goalID, userID)
+}
+
+// RemoveMember stops sharing a goal with a user
+func (s *SharingService) RemoveMember(ctx context.Context, goalID, userID string) error {
+	return s.memberRepo.Delete(ctx, goalID, userID)
+}
+
+// SharedGoals gets the goals other users of their organization have shared with a user
+func (s *SharingService) SharedGoals(ctx context.Context, user *models.User) ([]*models.Goal, error) {
+	return s.goalRepo.GetSharedWithUserID(ctx, user.OrganizationID, user.ID)
+}
+
+// member gets one user's membership of a goal
+func (s *SharingService) member(ctx context.Context, goalID, userID string) (*models.GoalMember, error) {
+	members, err := s.memberRepo.GetByGoalID(ctx, goalID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range members {
+		if member.UserID == userID {
+			return member, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/recurrence"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// TaskService is the service for changing timeline tasks
+type TaskService struct {
+	taskRepo       *repository.TaskRepository
+	occurrenceRepo *repository.OccurrenceRepository
+	events         *events.Bus
+}
+
+// NewTaskService creates a new TaskService
+func NewTaskService(bus *events.Bus) *TaskService {
+	return &TaskService{
+		taskRepo:       repository.NewTaskRepository(),
+		occurrenceRepo: repository.NewOccurrenceRepository(),
+		events:         bus,
+	}
+}
+
+// SetCompleted marks a task on a goal of the user's organization as completed or not and publishes
+// task.completed when it becomes completed
+func (s *TaskService) SetCompleted(ctx context.Context, user *models.User, taskID string, completed bool) (*models.TimelineTask, error) {
+	task, err := s.taskRepo.GetByID(ctx, user.OrganizationID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.Completed == completed {
+		return task, nil
+	}
+
+	if err := s.taskRepo.UpdateCompletionStatus(ctx, user.OrganizationID, taskID, completed); err != nil {
+		return nil, err
+	}
+
+	task, err = s.taskRepo.GetByID(ctx, user.OrganizationID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if completed {
+		s.events.Publish(ctx, events.TaskCompleted, user.ID, events.NewTaskData(task))
+	}
+
+	return task, nil
+}
+
+// SetOccurrenceCompleted marks one occurrence of a recurring task on a goal of the user's organization
+// as completed or not. The task itself is completed once every occurrence is, and reopened when one is undone.
+func (s *TaskService) SetOccurrenceCompleted(ctx context.Context, user *models.User, taskID string, date time.Time, completed bool) (*models.TimelineTask, error) {
+	task, err := s.taskRepo.GetByID(ctx, user.OrganizationID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !task.IsRecurring() {
+		return nil, invalid("task %s does not recur", taskID)
+	}
+	occurs, err := recurrence.Occurs(task, date)
+	if err != nil {
+		return nil, err
+	}
+	if !occurs {
+		return nil, invalid("task %s does not occur on %s", taskID, date.Format("2006-01-02"))
+	}
+
+	if err := s.occurrenceRepo.SetCompleted(ctx, user.OrganizationID, taskID, date, completed); err != nil {
+		return nil, err
+	}
+
+	allCompleted := false
+	if completed {
+		occurrences, err := s.Occurrences(ctx, user, task, task.StartDate, task.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		allCompleted = true
+		for _, occurrence := range occurrences {
+			allCompleted = allCompleted && occurrence.Completed
+		}
+	}
+
+	return s.SetCompleted(ctx, user, taskID, allCompleted)
+}
+
+// SetRecurrence makes a task on a goal of the user's organization repeat by an RRULE, or a one-off
+// task again when the rule is empty. Completed occurrences the new rule does not have are deleted, and
+// the task is completed when every occurrence of the new rule already is. A task that no longer recurs
+// keeps whether it was completed.
+func (s *TaskService) SetRecurrence(ctx context.Context, user *models.User, taskID, rule string) (*models.TimelineTask, error) {
+	if rule != "" {
+		var err error
+		if rule, err = recurrence.Normalize(rule); err != nil {
+			return nil, invalid("%v", err)
+		}
+	}
+
+	task, err := s.taskRepo.GetByID(ctx, user.OrganizationID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	done, err := s.occurrenceRepo.GetCompleted(ctx, user.OrganizationID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	occurs := map[time.Time]bool{}
+	completed := task.Completed
+	if rule != "" {
+		updated := *task
+		updated.Recurrence = rule
+		dates, err := recurrence.All(&updated)
+		if err != nil {
+			return nil, err
+		}
+
+		completed = len(dates) > 0
+		for _, date := range dates {
+			occurs[date] = true
+			_, ok := done[date]
+			completed = completed && ok
+		}
+	}
+
+	var removed []time.Time
+	for date := range done {
+		if !occurs[date] {
+			removed = append(removed, date)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Before(removed[j]) })
+
+	if err := s.taskRepo.UpdateRecurrence(ctx, user.OrganizationID, taskID, rule, removed, completed); err != nil {
+		return nil, err
+	}
+
+	wasCompleted := task.Completed
+	task, err = s.taskRepo.GetByID(ctx, user.OrganizationID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if completed && !wasCompleted {
+		s.events.Publish(ctx, events.TaskCompleted, user.ID, events.NewTaskData(task))
+	}
+
+	return task, nil
+}
+
+// Occurrences lists the occurrences of a recurring task on a goal of the user's organization between
+// from and to, counting both ends
+func (s *TaskService) Occurrences(ctx context.Context, user *models.User, task *models.TimelineTask, from, to time.Time) ([]*models.TaskOccurrence, error) {
+	dates, err := recurrence.Dates(task, from, to)
+	if err != nil {
+		return nil, err
+	}
+	done, err := s.occurrenceRepo.GetCompleted(ctx, user.OrganizationID, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := make([]*models.TaskOccurrence, len(dates))
+	for i, date := range dates {
+		occurrences[i] = &models.TaskOccurrence{TaskID: task.ID, Date: date}
+		if completedAt, ok := done[date]; ok {
+			occurrences[i].Completed = true
+			occurrences[i].CompletedAt = &completedAt
+		}
+	}
+
+	return occurrences, nil
+}
+
+// Streak works out the streaks of a recurring task on a goal of the user's organization as of the civil date today
+func (s *TaskService) Streak(ctx context.Context, user *models.User, task *models.TimelineTask, today time.Time) (*models.Streak, error) {
+	dates, err := recurrence.All(task)
+	if err != nil {
+		return nil, err
+	}
+	completed, err := s.occurrenceRepo.GetCompleted(ctx, user.OrganizationID, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(map[time.Time]bool, len(completed))
+	for date := range completed {
+		done[date] = true
+	}
+
+	streak := recurrence.Streak(dates, done, today)
+	return &streak, nil
+}
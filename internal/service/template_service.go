@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/duration"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+	"github.com/jukemori/timeline-generator/internal/templates"
+)
+
+// TemplateService is the service for saving goal templates and creating timelines from them
+type TemplateService struct {
+	templateRepo *repository.GoalTemplateRepository
+	goalRepo     *repository.GoalRepository
+	timelineRepo *repository.TimelineRepository
+	scheduler    *TimelineScheduler
+}
+
+// NewTemplateService creates a new TemplateService
+func NewTemplateService(scheduler *TimelineScheduler) *TemplateService {
+	return &TemplateService{
+		templateRepo: repository.NewGoalTemplateRepository(),
+		goalRepo:     repository.NewGoalRepository(),
+		timelineRepo: repository.NewTimelineRepository(),
+		scheduler:    scheduler,
+	}
+}
+
+// List gets the curated templates followed by those the user has saved
+func (s *TemplateService) List(ctx context.Context, user *models.User) ([]*models.GoalTemplate, error) {
+	saved, err := s.templateRepo.GetByUserID(ctx, user.OrganizationID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return append(templates.Curated(), saved...), nil
+}
+
+// SaveFromTimeline saves a timeline on a goal of the user's organization as one of the user's
+// templates, titled after the timeline unless a title is given
+func (s *TemplateService) SaveFromTimeline(ctx context.Context, user *models.User, timelineID, title string) (*models.GoalTemplate, error) {
+	timeline, err := s.timelineRepo.GetByID(ctx, user.OrganizationID, timelineID)
+	if err != nil {
+		return nil, err
+	}
+	goal, err := s.goalRepo.GetByID(ctx, user.OrganizationID, timeline.GoalID)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := templates.FromTimeline(goal, timeline)
+	if err != nil {
+		return nil, err
+	}
+	template.UserID = user.ID
+	if title != "" {
+		template.Title = title
+	}
+
+	if err := s.templateRepo.Create(ctx, template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// Instantiate creates a goal and timeline for the user from a template starting on startDate.
+// With a target date the template is scaled to end on it; otherwise it keeps its own length.
+// Tasks are placed on the user's calendar like generated ones, after the work they already have, and
+// the plan ends with its last task, which may be before or after the target date.
+func (s *TemplateService) Instantiate(ctx context.Context, user *models.User, template *models.GoalTemplate, startDate time.Time, targetDate *time.Time) (*models.Timeline, error) {
+	spanDays := template.SpanDays
+	if targetDate != nil {
+		if !targetDate.After(startDate) {
+			return nil, invalid("target date must be after the start date")
+		}
+		spanDays = duration.SpanDays(startDate, *targetDate)
+	}
+
+	cal, err := s.scheduler.Calendar(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	capacity, err := s.scheduler.Capacity(ctx, user.OrganizationID, user.ID, cal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workload: %w", err)
+	}
+
+	tasks, err := placeTasks(cal, capacity, templateData(template, startDate, spanDays), startDate)
+	if err != nil {
+		return nil, err
+	}
+
+	endDate := startDate
+	for _, task := range tasks {
+		if task.EndDate.After(endDate) {
+			endDate = task.EndDate
+		}
+	}
+
+	goal := &models.Goal{
+		UserID:       user.ID,
+		Title:        template.Goal,
+		Description:  template.Description,
+		CurrentLevel: template.CurrentLevel,
+		TargetLevel:  template.TargetLevel,
+		StartDate:    startDate,
+		TargetDate:   endDate,
+	}
+	return createTimeline(ctx, s.timelineRepo, goal, template.Title, template.Description, tasks)
+}
+
+// Delete deletes a template saved by a user of the user's organization
+func (s *TemplateService) Delete(ctx context.Context, user *models.User, id string) error {
+	return s.templateRepo.Delete(ctx, user.OrganizationID, id)
+}
+
+// templateData proposes the template's tasks, scaled to spanDays, on dates from startDate,
+// in the form the generator places its tasks from
+func templateData(template *models.GoalTemplate, startDate time.Time, spanDays int) GeneratedTimelineData {
+	data := GeneratedTimelineData{
+		Title:       template.Title,
+		Description: template.Description,
+	}
+
+	for _, task := range templates.Scale(template, spanDays) {
+		taskStart := startDate.AddDate(0, 0, task.StartOffset)
+		data.Tasks = append(data.Tasks, GeneratedTask{
+			Title:       task.Title,
+			Description: task.Description,
+			StartDate:   taskStart.Format(clock.DateLayout),
+			EndDate:     taskStart.AddDate(0, 0, task.SpanDays-1).Format(clock.DateLayout),
+			Duration:    task.Duration.Label,
+			EffortHours: task.EffortHours,
+			Recurrence:  task.Recurrence,
+			Priority:    task.Priority,
+		})
+	}
+
+	return data
+}
@@ -4,68 +4,127 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/jukemori/timeline-generator/internal/calendar"
+	"github.com/jukemori/timeline-generator/internal/duration"
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/metrics"
 	"github.com/jukemori/timeline-generator/internal/models"
 	"github.com/jukemori/timeline-generator/internal/openai"
+	"github.com/jukemori/timeline-generator/internal/recurrence"
 	"github.com/jukemori/timeline-generator/internal/repository"
+	"github.com/jukemori/timeline-generator/internal/tracing"
 )
 
+// defaultOccurrenceHours is the effort assumed for each occurrence of a generated recurring task
+// whose duration cannot be parsed
+const defaultOccurrenceHours = 1
+
 // TimelineGenerator is the service for generating timelines
 type TimelineGenerator struct {
 	openAIClient *openai.Client
-	goalRepo     *repository.GoalRepository
+	orgRepo      *repository.OrganizationRepository
 	timelineRepo *repository.TimelineRepository
-	taskRepo     *repository.TaskRepository
+	usageRepo    *repository.LLMUsageRepository
+	scheduler    *TimelineScheduler
+	events       *events.Bus
 }
 
 // NewTimelineGenerator creates a new TimelineGenerator
-func NewTimelineGenerator(openAIClient *openai.Client) *TimelineGenerator {
+func NewTimelineGenerator(openAIClient *openai.Client, scheduler *TimelineScheduler, bus *events.Bus) *TimelineGenerator {
 	return &TimelineGenerator{
 		openAIClient: openAIClient,
-		goalRepo:     repository.NewGoalRepository(),
+		orgRepo:      repository.NewOrganizationRepository(),
 		timelineRepo: repository.NewTimelineRepository(),
-		taskRepo:     repository.NewTaskRepository(),
+		usageRepo:    repository.NewLLMUsageRepository(),
+		scheduler:    scheduler,
+		events:       bus,
 	}
 }
 
 // GeneratedTimelineData contains timeline data generated by OpenAI
 type GeneratedTimelineData struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Tasks       []struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		StartDate   string `json:"start_date"`
-		EndDate     string `json:"end_date"`
-		Duration    string `json:"duration"`
-		Priority    int    `json:"priority"`
-	} `json:"tasks"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Tasks       []GeneratedTask `json:"tasks"`
+}
+
+// GeneratedTask is a task as proposed by OpenAI, before it is placed on the user's calendar
+type GeneratedTask struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	StartDate   string  `json:"start_date"`
+	EndDate     string  `json:"end_date"`
+	Duration    string  `json:"duration"`
+	EffortHours float64 `json:"effort_hours"`
+	Recurrence  string  `json:"recurrence"`
+	Priority    int     `json:"priority"`
 }
 
 // GenerateTimeline generates a timeline using OpenAI
-func (g *TimelineGenerator) GenerateTimeline(userID string, input models.TimelineInput) (*models.Timeline, error) {
-	ctx := context.Background()
+func (g *TimelineGenerator) GenerateTimeline(ctx context.Context, userID string, input models.TimelineInput) (*models.Timeline, error) {
+	ctx, span := tracing.Start(ctx, "TimelineGenerator.GenerateTimeline")
+	timeline, err := g.generateTimeline(ctx, userID, input)
+	tracing.End(span, err)
+	return timeline, err
+}
+
+func (g *TimelineGenerator) generateTimeline(ctx context.Context, userID string, input models.TimelineInput) (*models.Timeline, error) {
+	// Parse dates
+	startDate, err := time.Parse("2006-01-02", input.CurrentDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid current date: %w", err)
+	}
+
+	availability, err := g.scheduler.Availability(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load availability: %w", err)
+	}
+	cal, err := calendar.New(availability)
+	if err != nil {
+		return nil, fmt.Errorf("invalid availability: %w", err)
+	}
+
+	// The user's organization may choose the model and prompt
+	organization, err := g.orgRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load organization: %w", err)
+	}
 
 	// Create the prompt for OpenAI
-	prompt := g.createPrompt(input)
+	prompt, err := g.createPrompt(input, availability, cal, organization.Settings.PromptTemplate)
+	if err != nil {
+		return nil, err
+	}
 	
 	// Generate timeline data using OpenAI
-	response, err := g.openAIClient.GenerateCompletion(ctx, prompt)
+	completion, err := g.openAIClient.GenerateCompletion(ctx, organization.Settings.DefaultModel, prompt)
+	usage := g.recordUsage(ctx, userID, models.OperationGenerateTimeline, completion, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate timeline: %w", err)
 	}
 
 	// Parse the response into timeline data
 	var timelineData GeneratedTimelineData
-	if err := json.Unmarshal([]byte(response), &timelineData); err != nil {
-		return nil, fmt.Errorf("failed to parse timeline data: %w", err)
+	if err := json.Unmarshal([]byte(completion.Content), &timelineData); err != nil {
+		return nil, newError(CodeUnprocessable, "failed to parse timeline data: %v", err)
 	}
 
-	// Parse dates
-	startDate, err := time.Parse("2006-01-02", input.CurrentDate)
+	if len(timelineData.Tasks) == 0 {
+		return nil, newError(CodeUnprocessable, "failed to parse timeline data: no tasks")
+	}
+
+	// Place the tasks on the days the user has capacity left after their other timelines
+	capacity, err := g.scheduler.Capacity(ctx, organization.ID, userID, cal)
 	if err != nil {
-		return nil, fmt.Errorf("invalid current date: %w", err)
+		return nil, fmt.Errorf("failed to load workload: %w", err)
+	}
+	tasks, err := placeTasks(cal, capacity, timelineData, startDate)
+	if err != nil {
+		return nil, err
 	}
 
 	var endDate time.Time
@@ -76,71 +135,205 @@ func (g *TimelineGenerator) GenerateTimeline(userID string, input models.Timelin
 		}
 	} else {
 		// Get end date from the last task
-		lastTask := timelineData.Tasks[len(timelineData.Tasks)-1]
-		endDate, err = time.Parse("2006-01-02", lastTask.EndDate)
-		if err != nil {
-			return nil, fmt.Errorf("invalid task end date: %w", err)
+		for _, task := range tasks {
+			if task.EndDate.After(endDate) {
+				endDate = task.EndDate
+			}
 		}
 	}
 
-	// Create a goal
-	goal, err := g.goalRepo.Create(
-		userID,
-		input.Goal,
-		"",
-		input.CurrentLevel,
-		input.Goal,
-		startDate,
-		endDate,
-	)
+	goal := &models.Goal{
+		UserID:       userID,
+		Title:        input.Goal,
+		CurrentLevel: input.CurrentLevel,
+		TargetLevel:  input.Goal,
+		StartDate:    startDate,
+		TargetDate:   endDate,
+	}
+	result, err := createTimeline(ctx, g.timelineRepo, goal, timelineData.Title, timelineData.Description, tasks)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create goal: %w", err)
+		return nil, err
 	}
 
-	// Create a timeline
-	timeline, err := g.timelineRepo.Create(
-		goal.ID,
-		timelineData.Title,
-		timelineData.Description,
-		startDate,
-		endDate,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create timeline: %w", err)
+	// Link the LLM call to what it produced
+	if usage != nil {
+		if err := g.usageRepo.AttachTimeline(ctx, usage.ID, result.GoalID, result.ID); err != nil {
+			log.Printf("failed to link LLM usage %s to timeline %s: %v", usage.ID, result.ID, err)
+		}
+	}
+
+	g.events.Publish(ctx, events.TimelineGenerated, userID, events.NewTimelineData(result))
+
+	return result, nil
+}
+
+// createTimeline stores a goal, a timeline for it and the timeline's placed tasks in one transaction,
+// and returns the timeline with its tasks
+func createTimeline(ctx context.Context, timelineRepo *repository.TimelineRepository, goal *models.Goal, title, description string, tasks []models.TimelineTask) (*models.Timeline, error) {
+	timeline := &models.Timeline{
+		Title:       title,
+		Description: description,
+		StartDate:   goal.StartDate,
+		EndDate:     goal.TargetDate,
 	}
+	if err := timelineRepo.CreateWithGoal(ctx, goal, timeline, tasks); err != nil {
+		return nil, err
+	}
+
+	// Get the complete timeline with tasks
+	return timelineRepo.GetByID(ctx, goal.OrganizationID, timeline.ID)
+}
+
+// recordUsage stores the token usage, latency and estimated cost of an LLM call.
+// Failing to record usage is logged rather than failing the generation.
+func (g *TimelineGenerator) recordUsage(ctx context.Context, userID, operation string, completion *openai.Completion, callErr error) *models.LLMUsage {
+	if completion == nil {
+		return nil
+	}
+
+	metrics.ObserveLLMCall(operation, completion.Model, completion.Latency, completion.PromptTokens, completion.CompletionTokens, callErr)
 
-	// Create tasks
-	for _, taskData := range timelineData.Tasks {
-		taskStartDate, err := time.Parse("2006-01-02", taskData.StartDate)
+	usage := &models.LLMUsage{
+		UserID:           userID,
+		Operation:        operation,
+		Model:            completion.Model,
+		PromptTokens:     completion.PromptTokens,
+		CompletionTokens: completion.CompletionTokens,
+		LatencyMs:        completion.Latency.Milliseconds(),
+		EstimatedCost:    completion.EstimatedCost(),
+		Success:          callErr == nil,
+	}
+
+	if err := g.usageRepo.Create(ctx, usage); err != nil {
+		log.Printf("failed to record LLM usage for user %s: %v", userID, err)
+		return nil
+	}
+
+	return usage
+}
+
+// maxEffortHours is the most effort a task can be stored with, the largest value of its DECIMAL(6,2)
+// column. Larger estimates are capped to it.
+const maxEffortHours = 9999.99
+
+// placeTasks schedules the generated tasks on the user's available days.
+// Each task starts no earlier than the date the model proposed or the timeline's start, and is fitted
+// into the daily and weekly capacity left over, taking as many days as its effort needs. A duration that
+// cannot be parsed falls back to the working days within the proposed dates, and a missing effort
+// estimate falls back to the duration.
+// Recurring tasks keep the dates the model proposed, and their duration and effort are per occurrence
+// until the effort is totalled here. Effort is capped at maxEffortHours.
+func placeTasks(cal *calendar.Calendar, capacity *calendar.Capacity, data GeneratedTimelineData, startDate time.Time) ([]models.TimelineTask, error) {
+	tasks := make([]models.TimelineTask, len(data.Tasks))
+	for i, taskData := range data.Tasks {
+		proposedStart, err := time.Parse("2006-01-02", taskData.StartDate)
 		if err != nil {
 			return nil, fmt.Errorf("invalid task start date: %w", err)
 		}
-
-		taskEndDate, err := time.Parse("2006-01-02", taskData.EndDate)
+		proposedEnd, err := time.Parse("2006-01-02", taskData.EndDate)
 		if err != nil {
 			return nil, fmt.Errorf("invalid task end date: %w", err)
 		}
+		if proposedStart.Before(startDate) {
+			proposedStart = startDate
+		}
+
+		rule := ""
+		if taskData.Recurrence != "" {
+			if rule, err = recurrence.Normalize(taskData.Recurrence); err != nil {
+				log.Printf("ignoring recurrence of generated task %q: %v", taskData.Title, err)
+				rule = ""
+			}
+		}
 
-		_, err = g.taskRepo.Create(
-			timeline.ID,
-			taskData.Title,
-			taskData.Description,
-			taskData.Duration,
-			taskStartDate,
-			taskEndDate,
-			taskData.Priority,
-		)
+		estimate, err := duration.Parse(taskData.Duration)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create task: %w", err)
+			days := max(1, cal.WorkingDaysBetween(proposedStart, proposedEnd))
+			estimate = models.Duration{Value: float64(days), Unit: models.DurationDays}
+			if rule != "" {
+				estimate = models.Duration{Value: defaultOccurrenceHours, Unit: models.DurationHours}
+			}
+			estimate.Label = duration.Format(estimate)
 		}
+
+		tasks[i] = models.TimelineTask{
+			Title:       taskData.Title,
+			Description: taskData.Description,
+			Duration:    estimate,
+			EffortHours: taskData.EffortHours,
+			Recurrence:  rule,
+			Priority:    taskData.Priority,
+		}
+
+		if rule != "" {
+			tasks[i].StartDate = proposedStart
+			tasks[i].EndDate = proposedEnd
+			if proposedEnd.Before(proposedStart) {
+				tasks[i].EndDate = proposedStart
+			}
+
+			dates, err := recurrence.All(&tasks[i])
+			if err != nil {
+				return nil, err
+			}
+			if tasks[i].EffortHours <= 0 {
+				tasks[i].EffortHours = effortHours(cal, &tasks[i])
+			} else {
+				tasks[i].EffortHours *= float64(len(dates))
+			}
+			tasks[i].EffortHours = min(tasks[i].EffortHours, maxEffortHours)
+			capacity.Book(taskHours(cal, &tasks[i]))
+			continue
+		}
+
+		if tasks[i].EffortHours <= 0 {
+			tasks[i].EffortHours = effortHours(cal, &tasks[i])
+		}
+		tasks[i].EffortHours = min(tasks[i].EffortHours, maxEffortHours)
+		tasks[i].StartDate, tasks[i].EndDate = capacity.Fit(proposedStart, tasks[i].EffortHours)
 	}
+	return tasks, nil
+}
 
-	// Get the complete timeline with tasks
-	return g.timelineRepo.GetByID(timeline.ID)
+// createPrompt creates a prompt for OpenAI, from the organization's prompt template when it has one
+func (g *TimelineGenerator) createPrompt(input models.TimelineInput, availability *models.Availability, cal *calendar.Calendar, promptTemplate string) (string, error) {
+	workingDays := make([]string, len(availability.WorkingDays))
+	for i, weekday := range availability.WorkingDays {
+		workingDays[i] = weekday.String()
+	}
+
+	closed := "none"
+	if from, err := time.Parse("2006-01-02", input.CurrentDate); err == nil {
+		to := from.AddDate(1, 0, 0)
+		if target, err := time.Parse("2006-01-02", input.TargetDate); err == nil {
+			to = target
+		}
+		if dates := cal.ClosedDates(from, to, 30); len(dates) > 0 {
+			closed = strings.Join(dates, ", ")
+		}
+	}
+
+	data := PromptData{
+		CurrentLevel:     input.CurrentLevel,
+		Goal:             input.Goal,
+		Objectives:       input.Objectives,
+		CurrentDate:      input.CurrentDate,
+		TargetDate:       input.TargetDate,
+		WorkingDays:      strings.Join(workingDays, ", "),
+		HoursPerDay:      availability.HoursPerDay,
+		HoursPerWeek:     cal.WeeklyCapacity(),
+		UnavailableDates: closed,
+	}
+	data.Default = defaultPrompt(data)
+
+	if promptTemplate == "" {
+		return data.Default, nil
+	}
+	return renderPrompt(promptTemplate, data)
 }
 
-// createPrompt creates a prompt for OpenAI
-func (g *TimelineGenerator) createPrompt(input models.TimelineInput) string {
+// defaultPrompt is the prompt used for organizations without a prompt template of their own
+func defaultPrompt(data PromptData) string {
 	return fmt.Sprintf(`
 You are a professional career and learning coach AI. Create a detailed learning timeline with specific tasks to help someone achieve their goal.
 
@@ -149,6 +342,10 @@ GOAL: %s
 OBJECTIVES: %s
 CURRENT DATE: %s
 TARGET DATE: %s
+WORKING DAYS: %s
+HOURS AVAILABLE PER WORKING DAY: %g
+HOURS AVAILABLE PER WEEK: %g
+UNAVAILABLE DATES: %s
 
 Your response should be formatted as a JSON object with the following structure:
 {
@@ -160,13 +357,16 @@ Your response should be formatted as a JSON object with the following structure:
       "description": "Detailed description of what to do",
       "start_date": "YYYY-MM-DD",
       "end_date": "YYYY-MM-DD",
-      "duration": "X days/weeks",
+      "duration": "N working days or N hours of effort",
+      "effort_hours": total hours of focused work the task needs (number),
+      "recurrence": "optional RRULE for tasks that repeat, such as FREQ=DAILY or FREQ=WEEKLY;BYDAY=SA",
       "priority": 1-5 (higher number means higher priority)
     },
     ...more tasks
   ]
 }
 
-Make sure dates are in YYYY-MM-DD format and are realistic based on task complexity. Break down complex goals into manageable steps. Include specific resources and measurable outcomes.
-`, input.CurrentLevel, input.Goal, input.Objectives, input.CurrentDate, input.TargetDate)
-}
\ No newline at end of file
+Make sure dates are in YYYY-MM-DD format and are realistic based on task complexity. Only schedule work on working days, never on unavailable dates, and count durations in working days. Keep the effort of the tasks scheduled in any week within the weekly capacity. Break down complex goals into manageable steps. For habits and repeated practice such as "practice 30 minutes daily" or "weekly mock test", use a single recurring task instead of one long task: its start and end dates bound the recurrence, and its duration and effort_hours are for each occurrence. Omit recurrence for one-off tasks. Include specific resources and measurable outcomes.
+`, data.CurrentLevel, data.Goal, data.Objectives, data.CurrentDate, data.TargetDate,
+		data.WorkingDays, data.HoursPerDay, data.HoursPerWeek, data.UnavailableDates)
+}
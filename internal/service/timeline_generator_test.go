@@ -0,0 +1,52 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/calendar"
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+func TestPlaceTasksCapsEffort(t *testing.T) {
+	cal, err := calendar.New(models.DefaultAvailability("user"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	startDate := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		task GeneratedTask
+		want float64
+	}{
+		{
+			name: "estimate within bounds",
+			task: GeneratedTask{StartDate: "2025-03-03", EndDate: "2025-03-07", Duration: "1 week", EffortHours: 6},
+			want: 6,
+		},
+		{
+			name: "estimate over the column's range",
+			task: GeneratedTask{StartDate: "2025-03-03", EndDate: "2025-03-07", Duration: "1 week", EffortHours: 1e7},
+			want: maxEffortHours,
+		},
+		{
+			name: "recurring estimate totalled over the column's range",
+			task: GeneratedTask{StartDate: "2025-03-03", EndDate: "2026-03-02", Duration: "2 hours", EffortHours: 100, Recurrence: "FREQ=DAILY"},
+			want: maxEffortHours,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.task.Title = tt.name
+			tasks, err := placeTasks(cal, cal.NewCapacity(), GeneratedTimelineData{Tasks: []GeneratedTask{tt.task}}, startDate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tasks[0].EffortHours; got != tt.want {
+				t.Errorf("effort = %v hours, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,474 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/calendar"
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/recurrence"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// TimelineScheduler places timeline tasks on the days their owner is available
+type TimelineScheduler struct {
+	clock            clock.Clock
+	userRepo         *repository.UserRepository
+	availabilityRepo *repository.AvailabilityRepository
+	goalRepo         *repository.GoalRepository
+	timelineRepo     *repository.TimelineRepository
+	taskRepo         *repository.TaskRepository
+	events           *events.Bus
+}
+
+// NewTimelineScheduler creates a new TimelineScheduler
+func NewTimelineScheduler(clk clock.Clock, bus *events.Bus) *TimelineScheduler {
+	return &TimelineScheduler{
+		clock:            clk,
+		userRepo:         repository.NewUserRepository(),
+		availabilityRepo: repository.NewAvailabilityRepository(),
+		goalRepo:         repository.NewGoalRepository(),
+		timelineRepo:     repository.NewTimelineRepository(),
+		taskRepo:         repository.NewTaskRepository(),
+		events:           bus,
+	}
+}
+
+// Availability gets a user's availability, falling back to the defaults
+func (s *TimelineScheduler) Availability(ctx context.Context, userID string) (*models.Availability, error) {
+	availability, err := s.availabilityRepo.GetByUserID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.DefaultAvailability(userID), nil
+	}
+	return availability, err
+}
+
+// SaveAvailability validates and saves a user's availability.
+// Working days and blackout dates are sorted and deduplicated.
+func (s *TimelineScheduler) SaveAvailability(ctx context.Context, availability *models.Availability) error {
+	if err := calendar.Validate(availability); err != nil {
+		return err
+	}
+
+	workingDays := map[time.Weekday]bool{}
+	for _, weekday := range availability.WorkingDays {
+		workingDays[weekday] = true
+	}
+	availability.WorkingDays = availability.WorkingDays[:0]
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if workingDays[weekday] {
+			availability.WorkingDays = append(availability.WorkingDays, weekday)
+		}
+	}
+
+	blackoutDates := map[time.Time]bool{}
+	dates := []time.Time{}
+	for _, date := range availability.BlackoutDates {
+		date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		if !blackoutDates[date] {
+			blackoutDates[date] = true
+			dates = append(dates, date)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	availability.BlackoutDates = dates
+
+	return s.availabilityRepo.Save(ctx, availability)
+}
+
+// Today returns the civil date it currently is in the user's time zone
+func (s *TimelineScheduler) Today(ctx context.Context, userID string) (time.Time, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return clock.Today(s.clock, user.Location()), nil
+}
+
+// Calendar gets the working calendar for a user
+func (s *TimelineScheduler) Calendar(ctx context.Context, userID string) (*calendar.Calendar, error) {
+	availability, err := s.Availability(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return calendar.New(availability)
+}
+
+// Reschedule moves a timeline's incomplete tasks onto the owner's available days, starting no earlier than from.
+// Tasks keep their order and are placed one after another, each starting where the one before it ends
+// and fitted into the capacity the owner's other timelines leave, taking as many days as its effort needs.
+// Recurring tasks keep their place relative to the first task and the length of their span.
+// Completed tasks are left where they are. Editors the goal is shared with reschedule on the owner's calendar too.
+// The tasks and the timeline's dates are saved in one transaction.
+// It publishes timeline.rescheduled on behalf of the user who rescheduled.
+func (s *TimelineScheduler) Reschedule(ctx context.Context, user *models.User, timelineID string, from time.Time) (*models.Timeline, error) {
+	ownerID, err := s.timelineRepo.GetOwnerID(ctx, user.OrganizationID, timelineID)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := s.Calendar(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline, err := s.timelineRepo.GetByID(ctx, user.OrganizationID, timelineID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The capacity left by the owner's other timelines
+	owned, err := s.taskRepo.GetIncompleteByUserID(ctx, user.OrganizationID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	capacity := cal.NewCapacity()
+	for i := range owned {
+		if owned[i].TimelineID != timelineID {
+			capacity.Book(taskHours(cal, &owned[i].TimelineTask))
+		}
+	}
+
+	pending := []*models.TimelineTask{}
+	for i := range timeline.Tasks {
+		if !timeline.Tasks[i].Completed {
+			pending = append(pending, &timeline.Tasks[i])
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		if !pending[i].StartDate.Equal(pending[j].StartDate) {
+			return pending[i].StartDate.Before(pending[j].StartDate)
+		}
+		return pending[i].Priority > pending[j].Priority
+	})
+
+	schedules := make([]repository.TaskSchedule, 0, len(pending))
+	if len(pending) > 0 {
+		anchor := pending[0].StartDate
+		// Recurring tasks stay on the days they occur on, so they are booked before the rest are fitted
+		for _, task := range pending {
+			if !task.IsRecurring() {
+				continue
+			}
+			span := task.EndDate.Sub(task.StartDate)
+			task.StartDate = cal.NextAvailable(from.Add(task.StartDate.Sub(anchor)))
+			task.EndDate = task.StartDate.Add(span)
+			capacity.Book(taskHours(cal, task))
+			schedules = append(schedules, repository.TaskSchedule{TaskID: task.ID, StartDate: task.StartDate, EndDate: task.EndDate})
+		}
+
+		earliest := from
+		for _, task := range pending {
+			if task.IsRecurring() {
+				continue
+			}
+			task.StartDate, task.EndDate = capacity.Fit(earliest, effortHours(cal, task))
+			earliest = task.EndDate
+			schedules = append(schedules, repository.TaskSchedule{TaskID: task.ID, StartDate: task.StartDate, EndDate: task.EndDate})
+		}
+	}
+
+	// The timeline widens to cover its tasks where they now are
+	startDate, endDate := timeline.StartDate, timeline.EndDate
+	for _, task := range timeline.Tasks {
+		if task.StartDate.Before(startDate) {
+			startDate = task.StartDate
+		}
+		if task.EndDate.After(endDate) {
+			endDate = task.EndDate
+		}
+	}
+
+	if err := s.timelineRepo.Reschedule(ctx, user.OrganizationID, timelineID, startDate, endDate, schedules); err != nil {
+		return nil, err
+	}
+
+	timeline, err = s.timelineRepo.GetByID(ctx, user.OrganizationID, timelineID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ctx, events.TimelineRescheduled, user.ID, events.NewTimelineData(timeline))
+
+	return timeline, nil
+}
+
+// coverTasks widens the date range of a timeline on a goal of an organization to cover all of its
+// tasks and returns the timeline
+func (s *TimelineScheduler) coverTasks(ctx context.Context, organizationID, timelineID string) (*models.Timeline, error) {
+	timeline, err := s.timelineRepo.GetByID(ctx, organizationID, timelineID)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate, endDate := timeline.StartDate, timeline.EndDate
+	for _, task := range timeline.Tasks {
+		if task.StartDate.Before(startDate) {
+			startDate = task.StartDate
+		}
+		if task.EndDate.After(endDate) {
+			endDate = task.EndDate
+		}
+	}
+	if !startDate.Equal(timeline.StartDate) || !endDate.Equal(timeline.EndDate) {
+		if err := s.timelineRepo.UpdateDates(ctx, organizationID, timeline.ID, startDate, endDate); err != nil {
+			return nil, err
+		}
+		timeline.StartDate, timeline.EndDate = startDate, endDate
+	}
+
+	return timeline, nil
+}
+
+// Level reschedules all of a user's incomplete tasks, across every timeline, so that no day or week
+// is booked beyond the user's capacity. Tasks are taken in start date order, then by priority,
+// and each starts no earlier than from or its current start date.
+// It publishes timeline.rescheduled for every timeline with tasks that moved.
+func (s *TimelineScheduler) Level(ctx context.Context, user *models.User, from time.Time) error {
+	cal, err := s.Calendar(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := s.taskRepo.GetIncompleteByUserID(ctx, user.OrganizationID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	timelineIDs := map[string]bool{}
+	for _, planned := range level(cal, tasks, from) {
+		if planned.StartDate.Equal(planned.task.StartDate) && planned.EndDate.Equal(planned.task.EndDate) {
+			continue
+		}
+		if err := s.taskRepo.UpdateSchedule(ctx, user.OrganizationID, planned.task.ID, planned.StartDate, planned.EndDate); err != nil {
+			return err
+		}
+		timelineIDs[planned.task.TimelineID] = true
+	}
+
+	for timelineID := range timelineIDs {
+		timeline, err := s.coverTasks(ctx, user.OrganizationID, timelineID)
+		if err != nil {
+			return err
+		}
+		s.events.Publish(ctx, events.TimelineRescheduled, user.ID, events.NewTimelineData(timeline))
+	}
+
+	return nil
+}
+
+// Workload is the hours a user has planned per week against their capacity
+type Workload struct {
+	WeeklyCapacity float64
+	Weeks          []*WeeklyLoad
+	// Conflicts are goals that cannot be finished by their target date once the load is leveled
+	Conflicts []*ScheduleConflict
+}
+
+// WeeklyLoad is the hours planned in a week starting on a Monday
+type WeeklyLoad struct {
+	WeekStart    time.Time
+	PlannedHours float64
+	Timelines    []*TimelineLoad
+}
+
+// TimelineLoad is the hours a timeline contributes to a week
+type TimelineLoad struct {
+	TimelineID string
+	Title      string
+	Hours      float64
+}
+
+// ScheduleConflict is a goal whose tasks, leveled against the user's capacity, end after its target date
+type ScheduleConflict struct {
+	Goal             *models.Goal
+	ProjectedEndDate time.Time
+}
+
+// maxWorkloadWeeks bounds how many weeks a workload report can cover
+const maxWorkloadWeeks = 104
+
+// Workload reports the hours planned per week between from and to across all of a user's
+// active timelines, as the tasks are currently scheduled, along with any goals that cannot
+// meet their target date when the load is leveled from today. The range can cover at most
+// maxWorkloadWeeks.
+func (s *TimelineScheduler) Workload(ctx context.Context, user *models.User, from, to time.Time) (*Workload, error) {
+	if to.Before(from) {
+		return nil, invalid("to must not be before from")
+	}
+	if to.After(from.AddDate(0, 0, 7*maxWorkloadWeeks)) {
+		return nil, invalid("the workload can cover at most %d weeks", maxWorkloadWeeks)
+	}
+
+	cal, err := s.Calendar(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.taskRepo.GetIncompleteByUserID(ctx, user.OrganizationID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	goals, err := s.goalRepo.GetByUserID(ctx, user.OrganizationID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	goalsByID := map[string]*models.Goal{}
+	timelineTitles := map[string]string{}
+	for _, goal := range goals {
+		goalsByID[goal.ID] = goal
+		timelines, err := s.timelineRepo.GetByGoalID(ctx, user.OrganizationID, goal.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, timeline := range timelines {
+			timelineTitles[timeline.ID] = timeline.Title
+		}
+	}
+
+	// Hours per week and timeline as currently scheduled
+	from, to = calendar.WeekStart(from), calendar.WeekStart(to)
+	weeks := map[time.Time]map[string]float64{}
+	for _, task := range tasks {
+		for date, hours := range taskHours(cal, &task.TimelineTask) {
+			week := calendar.WeekStart(date)
+			if week.Before(from) || week.After(to) {
+				continue
+			}
+			if weeks[week] == nil {
+				weeks[week] = map[string]float64{}
+			}
+			weeks[week][task.TimelineID] += hours
+		}
+	}
+
+	workload := &Workload{WeeklyCapacity: cal.WeeklyCapacity(), Weeks: []*WeeklyLoad{}, Conflicts: []*ScheduleConflict{}}
+	for week := from; !week.After(to); week = week.AddDate(0, 0, 7) {
+		load := &WeeklyLoad{WeekStart: week, Timelines: []*TimelineLoad{}}
+		for timelineID, hours := range weeks[week] {
+			load.PlannedHours += hours
+			load.Timelines = append(load.Timelines, &TimelineLoad{
+				TimelineID: timelineID,
+				Title:      timelineTitles[timelineID],
+				Hours:      hours,
+			})
+		}
+		sort.Slice(load.Timelines, func(i, j int) bool { return load.Timelines[i].Hours > load.Timelines[j].Hours })
+		workload.Weeks = append(workload.Weeks, load)
+	}
+
+	// Goals that cannot be met even with the load leveled
+	today := clock.Today(s.clock, user.Location())
+	projectedEnd := map[string]time.Time{}
+	for _, planned := range level(cal, tasks, today) {
+		if planned.EndDate.After(projectedEnd[planned.task.GoalID]) {
+			projectedEnd[planned.task.GoalID] = planned.EndDate
+		}
+	}
+	for goalID, endDate := range projectedEnd {
+		goal, ok := goalsByID[goalID]
+		if ok && endDate.After(goal.TargetDate) {
+			workload.Conflicts = append(workload.Conflicts, &ScheduleConflict{Goal: goal, ProjectedEndDate: endDate})
+		}
+	}
+	sort.Slice(workload.Conflicts, func(i, j int) bool {
+		return workload.Conflicts[i].Goal.TargetDate.Before(workload.Conflicts[j].Goal.TargetDate)
+	})
+
+	return workload, nil
+}
+
+// plannedTask is a task along with the dates leveling placed it on
+type plannedTask struct {
+	task      *repository.OwnedTask
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+// level fits tasks into the calendar's capacity in start date order, then by priority.
+// Recurring tasks stay on the dates they occur on and are booked before the rest.
+func level(cal *calendar.Calendar, tasks []repository.OwnedTask, from time.Time) []plannedTask {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if !tasks[i].StartDate.Equal(tasks[j].StartDate) {
+			return tasks[i].StartDate.Before(tasks[j].StartDate)
+		}
+		return tasks[i].Priority > tasks[j].Priority
+	})
+
+	capacity := cal.NewCapacity()
+	planned := make([]plannedTask, len(tasks))
+	for i := range tasks {
+		if tasks[i].IsRecurring() {
+			capacity.Book(taskHours(cal, &tasks[i].TimelineTask))
+			planned[i] = plannedTask{task: &tasks[i], StartDate: tasks[i].StartDate, EndDate: tasks[i].EndDate}
+		}
+	}
+	for i := range tasks {
+		if tasks[i].IsRecurring() {
+			continue
+		}
+		earliest := from
+		if tasks[i].StartDate.After(earliest) {
+			earliest = tasks[i].StartDate
+		}
+		startDate, endDate := capacity.Fit(earliest, effortHours(cal, &tasks[i].TimelineTask))
+		planned[i] = plannedTask{task: &tasks[i], StartDate: startDate, EndDate: endDate}
+	}
+	return planned
+}
+
+// effortHours returns the hours a task needs. Tasks created before effort was estimated
+// fall back to their duration, with days worth the user's hours per day, for each time they occur.
+func effortHours(cal *calendar.Calendar, task *models.TimelineTask) float64 {
+	if task.EffortHours > 0 {
+		return task.EffortHours
+	}
+
+	hours := task.Duration.Value * cal.HoursPerDay()
+	if task.Duration.Unit == models.DurationHours {
+		hours = task.Duration.Value
+	}
+	if task.IsRecurring() {
+		dates, err := recurrence.All(task)
+		if err == nil {
+			hours *= float64(len(dates))
+		}
+	}
+	return hours
+}
+
+// taskHours returns the hours a task is planned to take on each day. The effort of a recurring task
+// is divided between the dates it occurs on; any other task's is spread over its available days.
+func taskHours(cal *calendar.Calendar, task *models.TimelineTask) map[time.Time]float64 {
+	if task.IsRecurring() {
+		dates, err := recurrence.All(task)
+		if err == nil && len(dates) > 0 {
+			perOccurrence := effortHours(cal, task) / float64(len(dates))
+			hours := make(map[time.Time]float64, len(dates))
+			for _, date := range dates {
+				hours[date] = perOccurrence
+			}
+			return hours
+		}
+	}
+	return cal.Spread(task.StartDate, task.EndDate, effortHours(cal, task))
+}
+
+// Capacity returns the capacity of a user of an organization with all of their incomplete tasks booked as scheduled
+func (s *TimelineScheduler) Capacity(ctx context.Context, organizationID, userID string, cal *calendar.Calendar) (*calendar.Capacity, error) {
+	tasks, err := s.taskRepo.GetIncompleteByUserID(ctx, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity := cal.NewCapacity()
+	for i := range tasks {
+		capacity.Book(taskHours(cal, &tasks[i].TimelineTask))
+	}
+	return capacity, nil
+}
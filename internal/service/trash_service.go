@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/audit"
+	"github.com/jukemori/timeline-generator/internal/clock"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// TrashService is the service for deleting goals, timelines and tasks. Deleted items stay in the
+// trash, where they can be restored, until they have been there for the retention period.
+type TrashService struct {
+	trashRepo *repository.TrashRepository
+	clock     clock.Clock
+	retention time.Duration
+	interval  time.Duration
+}
+
+// NewTrashService creates a new TrashService that purges items past the retention period at the
+// given interval
+func NewTrashService(clk clock.Clock, retention, interval time.Duration) *TrashService {
+	return &TrashService{
+		trashRepo: repository.NewTrashRepository(),
+		clock:     clk,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Items gets the items in the trash a user can restore, most recently deleted first
+func (s *TrashService) Items(ctx context.Context, userID string) ([]*models.TrashItem, error) {
+	return s.trashRepo.GetByUserID(ctx, userID)
+}
+
+// PurgeAt is when an item in the trash will be permanently deleted
+func (s *TrashService) PurgeAt(item *models.TrashItem) time.Time {
+	return item.DeletedAt.Add(s.retention)
+}
+
+// Delete moves a goal, timeline or task to the trash along with everything that belongs to it
+func (s *TrashService) Delete(ctx context.Context, kind, id string) error {
+	deleted, err := s.trashRepo.Delete(ctx, kind, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return notFound("%s %s not found", kind, id)
+	}
+	return nil
+}
+
+// Restore takes an item out of the trash along with the timelines and tasks deleted with it
+func (s *TrashService) Restore(ctx context.Context, kind, id string) error {
+	restored, err := s.trashRepo.Restore(ctx, kind, id)
+	if errors.Is(err, repository.ErrParentInTrash) {
+		return newError(CodeConflict, "%s %s cannot be restored: %v", kind, id, err)
+	}
+	if err != nil {
+		return err
+	}
+	if !restored {
+		return notFound("%s %s not found in the trash", kind, id)
+	}
+	return nil
+}
+
+// Run purges items past the retention period until ctx is cancelled
+func (s *TrashService) Run(ctx context.Context) {
+	ctx = audit.WithOperation(ctx, "trash purge")
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge permanently deletes the items that have been in the trash for the retention period
+func (s *TrashService) purge(ctx context.Context) {
+	items, err := s.trashRepo.GetDeletedBefore(ctx, s.clock.Now().Add(-s.retention))
+	if err != nil {
+		log.Printf("failed to find items to purge from the trash: %v", err)
+		return
+	}
+
+	for _, item := range items {
+		if _, err := s.trashRepo.Purge(ctx, item.Kind, item.ID); err != nil {
+			log.Printf("failed to purge %s %s from the trash: %v", item.Kind, item.ID, err)
+		}
+	}
+}
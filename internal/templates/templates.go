@@ -0,0 +1,183 @@
+package templates
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+
+	"github.com/jukemori/timeline-generator/internal/duration"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/recurrence"
+)
+
+//go:embed curated/*.json
+var curatedFS embed.FS
+
+// curated are the bundled templates by ID
+var curated = mustLoadCurated()
+
+// Curated returns the bundled templates ordered by title
+func Curated() []*models.GoalTemplate {
+	list := make([]*models.GoalTemplate, 0, len(curated))
+	for _, template := range curated {
+		list = append(list, template)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Title < list[j].Title })
+	return list
+}
+
+// Lookup gets a bundled template by ID
+func Lookup(id string) (*models.GoalTemplate, bool) {
+	template, ok := curated[id]
+	return template, ok
+}
+
+// FromTimeline builds a template from a timeline and its goal, placing each task by its offset from
+// the timeline's start. Recurring tasks keep their rule without its bounds, and their effort is
+// divided back into the effort per occurrence.
+func FromTimeline(goal *models.Goal, timeline *models.Timeline) (*models.GoalTemplate, error) {
+	template := &models.GoalTemplate{
+		Title:        timeline.Title,
+		Description:  timeline.Description,
+		Goal:         goal.Title,
+		CurrentLevel: goal.CurrentLevel,
+		TargetLevel:  goal.TargetLevel,
+		SpanDays:     duration.SpanDays(timeline.StartDate, timeline.EndDate),
+		Tasks:        make([]models.TemplateTask, len(timeline.Tasks)),
+	}
+
+	for i := range timeline.Tasks {
+		task := &timeline.Tasks[i]
+		templateTask := models.TemplateTask{
+			Title:       task.Title,
+			Description: task.Description,
+			// A task starting before the timeline starts with it, since SpanDays counts at least one day
+			StartOffset: duration.SpanDays(timeline.StartDate, task.StartDate) - 1,
+			SpanDays:    duration.SpanDays(task.StartDate, task.EndDate),
+			Duration:    task.Duration,
+			EffortHours: task.EffortHours,
+			Priority:    task.Priority,
+		}
+
+		if task.IsRecurring() {
+			dates, err := recurrence.All(task)
+			if err != nil {
+				return nil, fmt.Errorf("task %s: %w", task.ID, err)
+			}
+			if templateTask.Recurrence, err = recurrence.Unbounded(task.Recurrence); err != nil {
+				return nil, fmt.Errorf("task %s: %w", task.ID, err)
+			}
+			if len(dates) > 0 {
+				templateTask.EffortHours /= float64(len(dates))
+			}
+		}
+
+		template.SpanDays = max(template.SpanDays, templateTask.StartOffset+templateTask.SpanDays)
+		template.Tasks[i] = templateTask
+	}
+
+	return template, nil
+}
+
+// Scale stretches or compresses a template's tasks to cover a plan of spanDays days.
+// Offsets and spans scale in proportion. The work itself does not change with the time given to it, so
+// tasks keep their duration and effort: one-off tasks are spread over more or fewer days, and recurring
+// tasks occur more or less often.
+func Scale(template *models.GoalTemplate, spanDays int) []models.TemplateTask {
+	factor := 1.0
+	if template.SpanDays > 0 && spanDays > 0 {
+		factor = float64(spanDays) / float64(template.SpanDays)
+	}
+
+	tasks := make([]models.TemplateTask, len(template.Tasks))
+	for i, task := range template.Tasks {
+		task.StartOffset = int(math.Round(float64(task.StartOffset) * factor))
+		task.SpanDays = max(1, int(math.Round(float64(task.SpanDays)*factor)))
+		if spanDays > 0 {
+			task.StartOffset = min(task.StartOffset, spanDays-1)
+			task.SpanDays = min(task.SpanDays, spanDays-task.StartOffset)
+		}
+		tasks[i] = task
+	}
+
+	return tasks
+}
+
+// curatedFile is the format of a bundled template file, with durations as labels
+type curatedFile struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	Goal         string `json:"goal"`
+	CurrentLevel string `json:"current_level"`
+	TargetLevel  string `json:"target_level"`
+	SpanDays     int    `json:"span_days"`
+	Tasks        []struct {
+		Title       string  `json:"title"`
+		Description string  `json:"description"`
+		StartOffset int     `json:"start_offset"`
+		SpanDays    int     `json:"span_days"`
+		Duration    string  `json:"duration"`
+		EffortHours float64 `json:"effort_hours"`
+		Recurrence  string  `json:"recurrence"`
+		Priority    int     `json:"priority"`
+	} `json:"tasks"`
+}
+
+func mustLoadCurated() map[string]*models.GoalTemplate {
+	files, err := curatedFS.ReadDir("curated")
+	if err != nil {
+		panic(err)
+	}
+
+	templates := map[string]*models.GoalTemplate{}
+	for _, file := range files {
+		data, err := curatedFS.ReadFile(path.Join("curated", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		var parsed curatedFile
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			panic(fmt.Sprintf("invalid template file %s: %v", file.Name(), err))
+		}
+
+		template := &models.GoalTemplate{
+			ID:           parsed.ID,
+			Title:        parsed.Title,
+			Description:  parsed.Description,
+			Goal:         parsed.Goal,
+			CurrentLevel: parsed.CurrentLevel,
+			TargetLevel:  parsed.TargetLevel,
+			SpanDays:     parsed.SpanDays,
+		}
+		for _, task := range parsed.Tasks {
+			estimate, err := duration.Parse(task.Duration)
+			if err != nil {
+				panic(fmt.Sprintf("invalid task duration in %s: %v", file.Name(), err))
+			}
+			if task.Recurrence != "" {
+				if task.Recurrence, err = recurrence.Normalize(task.Recurrence); err != nil {
+					panic(fmt.Sprintf("invalid task recurrence in %s: %v", file.Name(), err))
+				}
+			}
+
+			template.Tasks = append(template.Tasks, models.TemplateTask{
+				Title:       task.Title,
+				Description: task.Description,
+				StartOffset: task.StartOffset,
+				SpanDays:    task.SpanDays,
+				Duration:    estimate,
+				EffortHours: task.EffortHours,
+				Recurrence:  task.Recurrence,
+				Priority:    task.Priority,
+			})
+		}
+		templates[template.ID] = template
+	}
+
+	return templates
+}
@@ -0,0 +1,58 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/jukemori/timeline-generator/internal/models"
+)
+
+func TestScaleKeepsEffort(t *testing.T) {
+	template := &models.GoalTemplate{
+		SpanDays: 10,
+		Tasks: []models.TemplateTask{
+			{
+				Title:       "Read",
+				StartOffset: 4,
+				SpanDays:    2,
+				Duration:    models.Duration{Value: 6, Unit: models.DurationHours, Label: "6 hours"},
+				EffortHours: 6,
+			},
+			{
+				Title:       "Practice",
+				StartOffset: 0,
+				SpanDays:    10,
+				Duration:    models.Duration{Value: 1, Unit: models.DurationHours, Label: "1 hour"},
+				EffortHours: 1,
+				Recurrence:  "FREQ=WEEKLY",
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		spanDays    int
+		wantOffsets []int
+		wantSpans   []int
+	}{
+		{"stretched", 20, []int{8, 0}, []int{4, 20}},
+		{"compressed", 5, []int{2, 0}, []int{1, 5}},
+		{"unchanged", 10, []int{4, 0}, []int{2, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := Scale(template, tt.spanDays)
+			for i, task := range tasks {
+				original := template.Tasks[i]
+				if task.StartOffset != tt.wantOffsets[i] || task.SpanDays != tt.wantSpans[i] {
+					t.Errorf("task %q: offset %d span %d, want offset %d span %d",
+						task.Title, task.StartOffset, task.SpanDays, tt.wantOffsets[i], tt.wantSpans[i])
+				}
+				if task.EffortHours != original.EffortHours || task.Duration != original.Duration {
+					t.Errorf("task %q: effort %v duration %v, want %v and %v",
+						task.Title, task.EffortHours, task.Duration, original.EffortHours, original.Duration)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/jukemori/timeline-generator/internal/operation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer is a gqlgen extension wrapping each operation and each resolver call in a span.
+// Install it with the server's Use.
+type Tracer struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = Tracer{}
+
+// ExtensionName implements graphql.HandlerExtension
+func (Tracer) ExtensionName() string {
+	return "Tracing"
+}
+
+// Validate implements graphql.HandlerExtension
+func (Tracer) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse wraps the execution of an operation in a span named after its root field, keeping
+// the name the client gave it as an attribute. Each event a subscription sends gets its own span.
+func (Tracer) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	opCtx := graphql.GetOperationContext(ctx)
+	if opCtx.Operation == nil {
+		return next(ctx)
+	}
+
+	name := operation.Name(opCtx)
+	operationType := string(opCtx.Operation.Operation)
+	ctx, span := Start(ctx, operationType+" "+name, trace.WithAttributes(
+		attribute.String("graphql.operation.name", opCtx.OperationName),
+		attribute.String("graphql.operation.type", operationType),
+		attribute.String("graphql.operation.root_field", name),
+	))
+	defer span.End()
+
+	response := next(ctx)
+	if response == nil {
+		return response
+	}
+	if len(response.Errors) > 0 {
+		span.SetStatus(codes.Error, response.Errors.Error())
+	}
+	return response
+}
+
+// InterceptField wraps each resolver call in a span. Fields read straight from their parent object
+// get no span of their own.
+func (Tracer) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	field := graphql.GetFieldContext(ctx)
+	if field == nil || !field.IsResolver {
+		return next(ctx)
+	}
+
+	ctx, span := Start(ctx, field.Object+"."+field.Field.Name, trace.WithAttributes(
+		attribute.String("graphql.field.path", field.Path().String()),
+	))
+	result, err := next(ctx)
+	End(span, err)
+	return result, err
+}
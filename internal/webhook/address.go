@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned for webhook URLs that reach the server's own networks
+var ErrForbiddenAddress = errors.New("webhook url must not point to a loopback, private or link-local address")
+
+// sharedAddressSpace is the carrier-grade NAT range, which is private to the network it is used in
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// forbidden reports whether an address is one webhooks must not be sent to: anything other than a
+// public unicast address, such as the server itself, its private network or a cloud metadata service
+func forbidden(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// refuseForbidden is a net.Dialer Control function refusing connections to forbidden addresses.
+// It runs after the host name has been resolved, for every connection including those that follow
+// redirects, so a name cannot be pointed at a forbidden address once its URL has been checked.
+func refuseForbidden(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || forbidden(ip) {
+		return fmt.Errorf("refusing to connect to %s: %w", address, ErrForbiddenAddress)
+	}
+	return nil
+}
+
+// newClient creates the client deliveries are sent with. Unless private networks are allowed, it
+// refuses to connect to forbidden addresses. It never uses a proxy, which would connect on its behalf.
+func newClient(config Config) *http.Client {
+	dialer := &net.Dialer{Timeout: config.Timeout, KeepAlive: 30 * time.Second}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = refuseForbidden
+	}
+
+	return &http.Client{
+		Timeout: config.Timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
+
+// ValidateURL checks that a webhook URL is an absolute http or https URL and, unless private networks
+// are allowed, that its host resolves only to addresses deliveries may be sent to. Deliveries check
+// the address again when they connect.
+func (d *Dispatcher) ValidateURL(ctx context.Context, rawURL string) (*url.URL, error) {
+	endpoint, err := url.Parse(rawURL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Hostname() == "" {
+		return nil, errors.New("webhook url must be an absolute http or https URL")
+	}
+	if endpoint.User != nil {
+		return nil, errors.New("webhook url must not contain credentials")
+	}
+	if d.config.AllowPrivateNetworks {
+		return endpoint, nil
+	}
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, endpoint.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("webhook url host %s cannot be resolved", endpoint.Hostname())
+	}
+	for _, address := range addresses {
+		if forbidden(address.IP) {
+			return nil, ErrForbiddenAddress
+		}
+	}
+	return endpoint, nil
+}
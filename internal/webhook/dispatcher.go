@@ -0,0 +1,254 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jukemori/timeline-generator/internal/events"
+	"github.com/jukemori/timeline-generator/internal/models"
+	"github.com/jukemori/timeline-generator/internal/repository"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Config configures webhook delivery
+type Config struct {
+	Workers      int
+	MaxAttempts  int
+	Timeout      time.Duration
+	PollInterval time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// AllowPrivateNetworks lets webhooks reach loopback, private and link-local addresses, for development
+	AllowPrivateNetworks bool
+}
+
+// DefaultConfig returns the delivery configuration used when none is given
+func DefaultConfig() Config {
+	return Config{
+		Workers:      2,
+		MaxAttempts:  8,
+		Timeout:      10 * time.Second,
+		PollInterval: 2 * time.Second,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   6 * time.Hour,
+	}
+}
+
+// Dispatcher turns events into webhook deliveries and sends them
+type Dispatcher struct {
+	config           Config
+	client           *http.Client
+	subscriptionRepo *repository.WebhookSubscriptionRepository
+	deliveryRepo     *repository.WebhookDeliveryRepository
+	wg               sync.WaitGroup
+}
+
+// NewDispatcher creates a new Dispatcher
+func NewDispatcher(config Config) *Dispatcher {
+	return &Dispatcher{
+		config:           config,
+		client:           newClient(config),
+		subscriptionRepo: repository.NewWebhookSubscriptionRepository(),
+		deliveryRepo:     repository.NewWebhookDeliveryRepository(),
+	}
+}
+
+// Handle queues a delivery of the event to each matching subscription. It is an events.Handler.
+func (d *Dispatcher) Handle(ctx context.Context, event events.Event) {
+	subscriptions, err := d.subscriptionRepo.GetActiveForEvent(ctx, event.UserID, event.Type)
+	if err != nil {
+		log.Printf("failed to load webhook subscriptions for event %s: %v", event.ID, err)
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("failed to encode event %s: %v", event.ID, err)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		if _, err := d.deliveryRepo.Create(ctx, subscription.ID, event.ID, event.Type, string(payload)); err != nil {
+			log.Printf("failed to queue webhook delivery of event %s to %s: %v", event.ID, subscription.ID, err)
+		}
+	}
+}
+
+// Redeliver queues a fresh delivery of a previously sent payload
+func (d *Dispatcher) Redeliver(ctx context.Context, deliveryID string) (*models.WebhookDelivery, error) {
+	delivery, err := d.deliveryRepo.GetByID(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	return d.deliveryRepo.Create(ctx, delivery.SubscriptionID, delivery.EventID, delivery.EventType, delivery.Payload)
+}
+
+// Depth counts the deliveries waiting to be sent
+func (d *Dispatcher) Depth() (int, error) {
+	return d.deliveryRepo.CountPending(context.Background())
+}
+
+// Start launches the delivery workers. They stop when ctx is cancelled; use Wait to block until they have.
+func (d *Dispatcher) Start(ctx context.Context) {
+	if _, err := d.deliveryRepo.RequeueStale(ctx, time.Now().Add(-2*d.config.Timeout)); err != nil {
+		log.Printf("failed to requeue stale webhook deliveries: %v", err)
+	}
+
+	for i := 0; i < d.config.Workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.work(ctx)
+		}()
+	}
+}
+
+// Wait blocks until all workers have stopped
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// work claims and sends deliveries until ctx is cancelled
+func (d *Dispatcher) work(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			delivery, err := d.deliveryRepo.ClaimNext(ctx)
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				log.Printf("failed to claim webhook delivery: %v", err)
+				break
+			}
+			// A claimed delivery is finished even when shutting down; the client timeout bounds it
+			d.deliver(context.WithoutCancel(ctx), delivery)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver sends a claimed delivery and records the outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	subscription, err := d.subscriptionRepo.GetForDelivery(ctx, delivery.SubscriptionID)
+	if err != nil {
+		d.record(ctx, delivery, 0, fmt.Errorf("failed to load subscription: %w", err))
+		return
+	}
+	if !subscription.Active {
+		d.record(ctx, delivery, 0, errors.New("subscription is inactive"))
+		return
+	}
+
+	status, err := d.send(ctx, subscription, delivery)
+	d.record(ctx, delivery, status, err)
+}
+
+// send POSTs the signed payload to the subscription URL. Only the response status is kept; the body
+// is discarded so that receivers cannot be used to read responses back.
+func (d *Dispatcher) send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "timeline-generator-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(subscription.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// record stores the outcome of an attempt, scheduling a retry with exponential backoff on failure
+func (d *Dispatcher) record(ctx context.Context, delivery *models.WebhookDelivery, status int, sendErr error) {
+	var err error
+	switch {
+	case sendErr == nil:
+		err = d.deliveryRepo.MarkSucceeded(ctx, delivery.ID, status)
+	case delivery.Attempts < d.config.MaxAttempts:
+		nextAttemptAt := time.Now().Add(d.backoff(delivery.Attempts))
+		err = d.deliveryRepo.MarkRetry(ctx, delivery.ID, status, sendErr.Error(), nextAttemptAt)
+	default:
+		err = d.deliveryRepo.MarkFailed(ctx, delivery.ID, status, sendErr.Error())
+	}
+
+	if err != nil {
+		log.Printf("failed to record webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// backoff returns the delay before retrying a delivery that has failed attempts times
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.config.MaxBackoff {
+			return d.config.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.body" keyed by the subscription secret.
+// Receivers should recompute it and compare against the X-Webhook-Signature header.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a random signing secret for a subscription
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
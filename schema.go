@@ -0,0 +1,9 @@
+// Package timeline holds the files shared by the server's commands
+package timeline
+
+import _ "embed"
+
+// Schema is the database schema, as applied by schema.sql
+//
+//go:embed schema.sql
+var Schema string
@@ -1,15 +1,33 @@
 CREATE DATABASE IF NOT EXISTS timeline_generator;
 USE timeline_generator;
 
-CREATE TABLE users (
+CREATE TABLE organizations (
   id VARCHAR(36) PRIMARY KEY,
-  email VARCHAR(255) UNIQUE NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  default_model VARCHAR(100),
+  prompt_template TEXT,
+  monthly_quota INT,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
 
+CREATE TABLE users (
+  id VARCHAR(36) PRIMARY KEY,
+  organization_id VARCHAR(36) NOT NULL,
+  email VARCHAR(255) NOT NULL,
+  role VARCHAR(20) NOT NULL DEFAULT 'user',
+  time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  -- Email addresses are unique within an organization only, so one organization cannot learn
+  -- which addresses another has signed up
+  UNIQUE KEY uq_users_organization_email (organization_id, email),
+  FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
 CREATE TABLE goals (
   id VARCHAR(36) PRIMARY KEY,
+  organization_id VARCHAR(36) NOT NULL,
   user_id VARCHAR(36) NOT NULL,
   title VARCHAR(255) NOT NULL,
   description TEXT,
@@ -19,6 +37,9 @@ CREATE TABLE goals (
   target_date DATE NOT NULL,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  deleted_at DATETIME(6),
+  INDEX idx_goals_deleted (deleted_at),
+  FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
   FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
@@ -31,6 +52,8 @@ CREATE TABLE timelines (
   end_date DATE NOT NULL,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  deleted_at DATETIME(6),
+  INDEX idx_timelines_deleted (deleted_at),
   FOREIGN KEY (goal_id) REFERENCES goals(id) ON DELETE CASCADE
 );
 
@@ -42,9 +65,277 @@ CREATE TABLE timeline_tasks (
   start_date DATE NOT NULL,
   end_date DATE NOT NULL,
   duration VARCHAR(50) NOT NULL,
+  duration_value DECIMAL(8,2),
+  duration_unit VARCHAR(10),
+  effort_hours DECIMAL(6,2),
+  recurrence VARCHAR(255),
   priority INT NOT NULL,
   completed BOOLEAN DEFAULT FALSE,
+  completed_at DATETIME,
+  deadline_missed_at DATETIME,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  deleted_at DATETIME(6),
+  INDEX idx_timeline_tasks_deleted (deleted_at),
   FOREIGN KEY (timeline_id) REFERENCES timelines(id) ON DELETE CASCADE
-);
\ No newline at end of file
+);
+
+CREATE TABLE generation_quotas (
+  user_id VARCHAR(36) PRIMARY KEY,
+  monthly_limit INT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE generation_usage (
+  user_id VARCHAR(36) NOT NULL,
+  period CHAR(7) NOT NULL,
+  generations INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, period),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE llm_usage (
+  id VARCHAR(36) PRIMARY KEY,
+  user_id VARCHAR(36),
+  goal_id VARCHAR(36),
+  timeline_id VARCHAR(36),
+  operation VARCHAR(50) NOT NULL,
+  model VARCHAR(100) NOT NULL,
+  prompt_tokens INT NOT NULL DEFAULT 0,
+  completion_tokens INT NOT NULL DEFAULT 0,
+  latency_ms INT NOT NULL DEFAULT 0,
+  estimated_cost DECIMAL(12, 6) NOT NULL DEFAULT 0,
+  success BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_llm_usage_user_created (user_id, created_at),
+  INDEX idx_llm_usage_model_created (model, created_at),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+  FOREIGN KEY (goal_id) REFERENCES goals(id) ON DELETE SET NULL,
+  FOREIGN KEY (timeline_id) REFERENCES timelines(id) ON DELETE SET NULL
+);
+
+CREATE TABLE generation_jobs (
+  id VARCHAR(36) PRIMARY KEY,
+  user_id VARCHAR(36) NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'queued',
+  input JSON NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  max_attempts INT NOT NULL DEFAULT 3,
+  last_error TEXT,
+  timeline_id VARCHAR(36),
+  run_at DATETIME NOT NULL,
+  locked_at DATETIME,
+  started_at DATETIME,
+  finished_at DATETIME,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX idx_generation_jobs_status_run_at (status, run_at),
+  INDEX idx_generation_jobs_user (user_id, created_at),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (timeline_id) REFERENCES timelines(id) ON DELETE SET NULL
+);
+
+-- Subscriptions without a user are the organization's and receive the events of all its users
+CREATE TABLE webhook_subscriptions (
+  id VARCHAR(36) PRIMARY KEY,
+  organization_id VARCHAR(36) NOT NULL,
+  user_id VARCHAR(36),
+  url VARCHAR(2048) NOT NULL,
+  secret VARCHAR(128) NOT NULL,
+  event_types JSON NOT NULL,
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX idx_webhook_subscriptions_user (user_id, active),
+  INDEX idx_webhook_subscriptions_organization (organization_id, active),
+  FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE webhook_deliveries (
+  id VARCHAR(36) PRIMARY KEY,
+  subscription_id VARCHAR(36) NOT NULL,
+  event_id VARCHAR(36) NOT NULL,
+  event_type VARCHAR(50) NOT NULL,
+  payload JSON NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  response_status INT,
+  last_error TEXT,
+  next_attempt_at DATETIME NOT NULL,
+  locked_at DATETIME,
+  delivered_at DATETIME,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX idx_webhook_deliveries_status_next (status, next_attempt_at),
+  INDEX idx_webhook_deliveries_subscription (subscription_id, created_at),
+  FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE reminder_settings (
+  user_id VARCHAR(36) PRIMARY KEY,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  start_lead_days JSON NOT NULL,
+  due_lead_days JSON NOT NULL,
+  overdue BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE task_reminders (
+  task_id VARCHAR(36) NOT NULL,
+  kind VARCHAR(20) NOT NULL,
+  lead_days INT NOT NULL,
+  due_on DATE NOT NULL,
+  user_id VARCHAR(36) NOT NULL,
+  -- Messages quote the task title, which alone can take up all 255 characters of a VARCHAR(255)
+  message TEXT NOT NULL,
+  sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (task_id, kind, lead_days, due_on),
+  INDEX idx_task_reminders_user (user_id, sent_at),
+  FOREIGN KEY (task_id) REFERENCES timeline_tasks(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE notification_preferences (
+  user_id VARCHAR(36) PRIMARY KEY,
+  email_reminders BOOLEAN NOT NULL DEFAULT TRUE,
+  weekly_digest BOOLEAN NOT NULL DEFAULT TRUE,
+  digest_day TINYINT NOT NULL DEFAULT 1,
+  unsubscribe_token VARCHAR(64) NOT NULL UNIQUE,
+  last_digest_at DATETIME,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE availability (
+  user_id VARCHAR(36) PRIMARY KEY,
+  working_days JSON NOT NULL,
+  hours_per_day DECIMAL(4,2) NOT NULL,
+  weekly_capacity DECIMAL(5,2) NOT NULL DEFAULT 0,
+  holiday_set VARCHAR(10) NOT NULL DEFAULT '',
+  blackout_dates JSON NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE task_occurrences (
+  task_id VARCHAR(36) NOT NULL,
+  occurs_on DATE NOT NULL,
+  completed_at DATETIME NOT NULL,
+  PRIMARY KEY (task_id, occurs_on),
+  FOREIGN KEY (task_id) REFERENCES timeline_tasks(id) ON DELETE CASCADE
+);
+
+CREATE TABLE goal_templates (
+  id VARCHAR(36) PRIMARY KEY,
+  user_id VARCHAR(36) NOT NULL,
+  title VARCHAR(255) NOT NULL,
+  description TEXT,
+  goal VARCHAR(255) NOT NULL,
+  current_level TEXT NOT NULL,
+  target_level TEXT NOT NULL,
+  span_days INT NOT NULL,
+  tasks JSON NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX idx_goal_templates_user (user_id, created_at),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE goal_members (
+  goal_id VARCHAR(36) NOT NULL,
+  user_id VARCHAR(36) NOT NULL,
+  role VARCHAR(20) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (goal_id, user_id),
+  INDEX idx_goal_members_user (user_id),
+  FOREIGN KEY (goal_id) REFERENCES goals(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE goal_invitations (
+  id VARCHAR(36) PRIMARY KEY,
+  goal_id VARCHAR(36) NOT NULL,
+  email VARCHAR(255) NOT NULL,
+  role VARCHAR(20) NOT NULL,
+  invited_by VARCHAR(36) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY uq_goal_invitations_goal_email (goal_id, email),
+  INDEX idx_goal_invitations_email (email),
+  FOREIGN KEY (goal_id) REFERENCES goals(id) ON DELETE CASCADE,
+  FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE comments (
+  id VARCHAR(36) PRIMARY KEY,
+  goal_id VARCHAR(36) NOT NULL,
+  task_id VARCHAR(36),
+  parent_id VARCHAR(36),
+  user_id VARCHAR(36) NOT NULL,
+  body TEXT NOT NULL,
+  mentions JSON NOT NULL,
+  edited_at TIMESTAMP NULL,
+  -- Deleted comments are kept without their body so that their replies stay in the thread
+  deleted_at TIMESTAMP NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX idx_comments_goal (goal_id, created_at),
+  INDEX idx_comments_task (task_id, created_at),
+  FOREIGN KEY (goal_id) REFERENCES goals(id) ON DELETE CASCADE,
+  FOREIGN KEY (task_id) REFERENCES timeline_tasks(id) ON DELETE CASCADE,
+  FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE timeline_activity (
+  id VARCHAR(36) PRIMARY KEY,
+  timeline_id VARCHAR(36) NOT NULL,
+  task_id VARCHAR(36),
+  user_id VARCHAR(36),
+  kind VARCHAR(20) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_timeline_activity_timeline (timeline_id, created_at),
+  FOREIGN KEY (timeline_id) REFERENCES timelines(id) ON DELETE CASCADE,
+  FOREIGN KEY (task_id) REFERENCES timeline_tasks(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE audit_log (
+  id VARCHAR(36) PRIMARY KEY,
+  organization_id VARCHAR(36),
+  actor_id VARCHAR(36),
+  operation VARCHAR(255) NOT NULL,
+  action VARCHAR(10) NOT NULL,
+  entity_type VARCHAR(50) NOT NULL,
+  entity_id VARCHAR(100) NOT NULL,
+  before_values JSON,
+  after_values JSON,
+  request_id VARCHAR(64),
+  ip_address VARCHAR(45),
+  user_agent VARCHAR(255),
+  created_at TIMESTAMP(6) DEFAULT CURRENT_TIMESTAMP(6),
+  INDEX idx_audit_log_organization (organization_id, created_at),
+  INDEX idx_audit_log_entity (entity_type, entity_id, created_at),
+  INDEX idx_audit_log_actor (actor_id, created_at)
+);
+
+-- The audit log is append-only: entries can be added but never changed or removed.
+-- Purging a goal or timeline from the trash audits the timelines and tasks deleted with it. The other
+-- rows ON DELETE CASCADE removes with them, such as comments, memberships, invitations, occurrences,
+-- reminders and activity, are covered by the audit entry of the goal, timeline or task they belonged to.
+-- Records the system keeps about its own work, such as job claims, webhook deliveries, sent reminders
+-- and usage counters, are not audited: they change on every request or tick and hold no user data.
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';